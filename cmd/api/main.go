@@ -4,34 +4,102 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
+	nethttp "net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 
-	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
 	_ "github.com/satryo-pramahardi/go-vehicle-tracker/docs"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/app/service"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/config"
 	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/db"
 	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/delivery/http"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/queue"
 	vehiclepg "github.com/satryo-pramahardi/go-vehicle-tracker/internal/repository/postgres"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/tracing"
 )
 
 func main() {
-	// Load environment variables
-	godotenv.Load()
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	cfg, err := config.Load()
+	if err != nil {
+		logging.Fatal(slog.Default(), "Invalid configuration", logging.Err(err))
+	}
+	logger, err := logging.Setup(http.ServiceName, cfg.Logging.Level, cfg.Logging.Format)
+	if err != nil {
+		logging.Fatal(slog.Default(), "Invalid logging configuration", logging.Err(err))
+	}
+	logger.Info("Loaded configuration", slog.Any("config", cfg))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	stopTracing, err := tracing.Setup(ctx, cfg.Tracing, http.ServiceName)
+	if err != nil {
+		logging.Fatal(logger, "Failed to set up tracing", logging.Err(err))
 	}
 
 	// Initialize db and repository
-	db := db.ConnectGorm()
-	repo := vehiclepg.NewVehicleLocationRepository(db)
+	gormDB := db.ConnectGorm(cfg.Postgres)
+	repo := vehiclepg.NewVehicleLocationRepository(gormDB)
+	geofenceRepo := vehiclepg.NewGeofenceRepository(gormDB)
+	groupRepo := vehiclepg.NewVehicleGroupRepository(gormDB)
+	assignmentRepo := vehiclepg.NewGeofenceAssignmentRepository(gormDB)
+
+	// Geofence change announcements and dead letter administration need Redis
+	var notifier http.GeofenceChangeNotifier
+	var deadLetterHandler *http.DeadLetterHandler
+	var rdb *redis.Client
+	if cfg.Redis.Addr != "" {
+		rdb = redis.NewClient(cfg.Redis.Options())
+		notifier = service.NewRedisGeofenceNotifier(rdb)
+		deadLetterHandler = http.NewDeadLetterHandler(queue.NewRedisQueue(rdb, ""))
+	} else {
+		logger.Warn("REDIS_ADDR not set, geofence changes will not be announced and dead letter admin is disabled")
+	}
 
 	// Initialize handler and router
 	handler := http.NewVehicleHandler(repo)
-	router := http.SetupRouter(handler)
+	geofenceHandler := http.NewGeofenceHandler(geofenceRepo, notifier)
+	assignmentHandler := http.NewAssignmentHandler(groupRepo, assignmentRepo, geofenceRepo, notifier)
+	router := http.SetupRouter(handler, geofenceHandler, assignmentHandler, deadLetterHandler)
+
+	port := strconv.Itoa(cfg.API.Port)
+	server := &nethttp.Server{Addr: ":" + port, Handler: router}
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("Starting API server", slog.String("port", port))
+		serveErr <- server.ListenAndServe()
+	}()
 
-	log.Printf("[API_SERVER] Starting API server on port %s", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("[API_SERVER] Failed to start server: %v", err)
+	select {
+	case err := <-serveErr:
+		logging.Fatal(logger, "Failed to start server", logging.Err(err))
+	case <-ctx.Done():
+	}
+
+	// Stop accepting connections and let in-flight requests finish
+	logger.Info("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, nethttp.ErrServerClosed) {
+		logger.Error("Timed out waiting for in-flight requests", logging.Err(err))
+	}
+
+	if rdb != nil {
+		if err := rdb.Close(); err != nil {
+			logger.Error("Failed to close Redis", logging.Err(err))
+		}
+	}
+	if err := db.Close(gormDB); err != nil {
+		logger.Error("Failed to close database", logging.Err(err))
+	}
+	if err := stopTracing(shutdownCtx); err != nil {
+		logger.Error("Failed to flush traces", logging.Err(err))
 	}
 }
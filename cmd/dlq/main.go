@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/config"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/queue"
+)
+
+// serviceName identifies the tool in logs
+const serviceName = "vehicle-tracker-dlq"
+
+const usage = `Usage: dlq <command> [flags] [ids...]
+
+Commands:
+  list              List dead letter entries, oldest first
+  show <id>         Print one entry with its full payload
+  replay [ids...]   Publish entries back onto their original queue
+  purge [ids...]    Delete entries
+
+list, replay and purge accept -queue, -error, -since and -until to select
+entries. replay and purge act on the given IDs, the filtered entries, or
+every entry with -all.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		logging.Fatal(slog.Default(), "Invalid configuration", logging.Err(err))
+	}
+	logger, err := logging.Setup(serviceName, cfg.Logging.Level, cfg.Logging.Format)
+	if err != nil {
+		logging.Fatal(slog.Default(), "Invalid logging configuration", logging.Err(err))
+	}
+	rdb := redis.NewClient(cfg.Redis.Options())
+	defer rdb.Close()
+	store := queue.NewRedisQueue(rdb, "")
+
+	ctx := context.Background()
+	cmd, args := os.Args[1], os.Args[2:]
+	switch cmd {
+	case "list":
+		err = list(ctx, store, args)
+	case "show":
+		err = show(ctx, store, args)
+	case "replay":
+		err = apply(ctx, store, cmd, args, queue.ReplayDeadLetters)
+	case "purge":
+		err = apply(ctx, store, cmd, args, queue.PurgeDeadLetters)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		logging.Fatal(logger, "Command failed", slog.String("command", cmd), logging.Err(err))
+	}
+}
+
+// filterFlags registers the flags shared by list, replay and purge and returns
+// a function that builds the filter once they are parsed
+func filterFlags(fs *flag.FlagSet) func() (queue.DeadLetterFilter, error) {
+	queueName := fs.String("queue", "", "Only entries from this queue")
+	errorMsg := fs.String("error", "", "Only entries whose error contains this text")
+	since := fs.String("since", "", "Only entries that failed at or after this RFC3339 time")
+	until := fs.String("until", "", "Only entries that failed at or before this RFC3339 time")
+
+	return func() (queue.DeadLetterFilter, error) {
+		filter := queue.DeadLetterFilter{Queue: *queueName, Error: *errorMsg}
+		var err error
+		if filter.Since, err = parseTime("since", *since); err != nil {
+			return filter, err
+		}
+		if filter.Until, err = parseTime("until", *until); err != nil {
+			return filter, err
+		}
+		return filter, nil
+	}
+}
+
+func parseTime(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("-%s must be an RFC3339 timestamp: %w", name, err)
+	}
+	return t, nil
+}
+
+func list(ctx context.Context, store queue.DeadLetterStore, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	buildFilter := filterFlags(fs)
+	limit := fs.Int("limit", 100, "Maximum number of entries to print (0=all)")
+	fs.Parse(args)
+
+	filter, err := buildFilter()
+	if err != nil {
+		return err
+	}
+	filter.Limit = *limit
+
+	entries, err := store.ListDeadLetters(ctx, filter)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tQUEUE\tFAILED AT\tRETRIES\tERROR")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", entry.ID, queueName(entry),
+			time.Unix(entry.FailedAt, 0).UTC().Format(time.RFC3339), entry.RetryCount, entry.ErrorMsg)
+	}
+	return w.Flush()
+}
+
+func queueName(entry model.DeadLetterEntry) string {
+	if entry.Queue == "" {
+		return "-"
+	}
+	return entry.Queue
+}
+
+func show(ctx context.Context, store queue.DeadLetterStore, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected exactly one entry ID")
+	}
+	entry, err := store.GetDeadLetter(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+func apply(ctx context.Context, store queue.DeadLetterStore, cmd string, args []string,
+	run func(context.Context, queue.DeadLetterStore, []string) queue.DeadLetterResult) error {
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	buildFilter := filterFlags(fs)
+	all := fs.Bool("all", false, "Select every entry")
+	fs.Parse(args)
+
+	filter, err := buildFilter()
+	if err != nil {
+		return err
+	}
+	ids := fs.Args()
+	if len(ids) == 0 && !*all && filter == (queue.DeadLetterFilter{}) {
+		return fmt.Errorf("give entry IDs, a filter, or -all")
+	}
+
+	selected, err := queue.SelectDeadLetters(ctx, store, ids, filter)
+	if err != nil {
+		return err
+	}
+	result := run(ctx, store, selected)
+	for _, id := range result.Succeeded {
+		fmt.Printf("%s\tok\n", id)
+	}
+	for id, reason := range result.Failed {
+		fmt.Printf("%s\tfailed: %s\n", id, reason)
+	}
+	slog.Info("Command finished", slog.String("command", cmd),
+		slog.Int("succeeded", len(result.Succeeded)), slog.Int("failed", len(result.Failed)))
+	if len(result.Failed) > 0 {
+		os.Exit(1)
+	}
+	return nil
+}
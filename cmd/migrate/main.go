@@ -1,52 +1,88 @@
 package main
 
 import (
-	"log"
+	"log/slog"
 
-	"github.com/joho/godotenv"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/config"
 	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/db"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
 	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
 	"gorm.io/gorm"
 )
 
+// serviceName identifies the migration in logs
+const serviceName = "vehicle-tracker-migrate"
+
 func main() {
-	godotenv.Load()
-	db := db.ConnectGorm()
+	cfg, err := config.Load()
+	if err != nil {
+		logging.Fatal(slog.Default(), "Invalid configuration", logging.Err(err))
+	}
+	logger, err := logging.Setup(serviceName, cfg.Logging.Level, cfg.Logging.Format)
+	if err != nil {
+		logging.Fatal(slog.Default(), "Invalid logging configuration", logging.Err(err))
+	}
+	db := db.ConnectGorm(cfg.Postgres)
 
-	log.Println("[MIGRATE] Starting database migration...")
+	logger.Info("Starting database migration")
 
 	if err := db.AutoMigrate(&model.VehicleLocation{}); err != nil {
-		log.Fatalf("[MIGRATE] Failed to migrate VehicleLocation: %v", err)
+		logging.Fatal(logger, "Failed to migrate VehicleLocation", logging.Err(err))
 	}
-	log.Println("[MIGRATE] ✅ VehicleLocation table migrated")
+	logger.Info("VehicleLocation table migrated")
 
 	if err := db.AutoMigrate(&model.EventLog{}); err != nil {
-		log.Fatalf("[MIGRATE] Failed to migrate EventLog: %v", err)
+		logging.Fatal(logger, "Failed to migrate EventLog", logging.Err(err))
 	}
-	log.Println("[MIGRATE] ✅ EventLog table migrated")
+	logger.Info("EventLog table migrated")
 
 	if err := db.AutoMigrate(&model.Geofence{}); err != nil {
-		log.Fatalf("[MIGRATE] Failed to migrate Geofence: %v", err)
+		logging.Fatal(logger, "Failed to migrate Geofence", logging.Err(err))
 	}
-	log.Println("[MIGRATE] ✅ Geofence table migrated")
+	logger.Info("Geofence table migrated")
 
+	// AutoMigrate never alters an existing check constraint, so drop the event
+	// type check and let it be recreated with the current event types
+	if db.Migrator().HasConstraint(&model.GeofenceEvent{}, "chk_geofence_events_event_type") {
+		if err := db.Migrator().DropConstraint(&model.GeofenceEvent{}, "chk_geofence_events_event_type"); err != nil {
+			logging.Fatal(logger, "Failed to drop GeofenceEvent event type check", logging.Err(err))
+		}
+	}
 	if err := db.AutoMigrate(&model.GeofenceEvent{}); err != nil {
-		log.Fatalf("[MIGRATE] Failed to migrate GeofenceEvent: %v", err)
+		logging.Fatal(logger, "Failed to migrate GeofenceEvent", logging.Err(err))
+	}
+	logger.Info("GeofenceEvent table migrated")
+
+	if err := db.AutoMigrate(&model.VehicleGroup{}, &model.VehicleGroupMember{}); err != nil {
+		logging.Fatal(logger, "Failed to migrate VehicleGroup", logging.Err(err))
+	}
+	logger.Info("VehicleGroup tables migrated")
+
+	// Deleting an assigned group used to cascade to its assignments, which
+	// left the geofence applying to every vehicle. AutoMigrate doesn't alter
+	// an existing foreign key, so drop it and let it be recreated as RESTRICT.
+	if db.Migrator().HasConstraint(&model.GeofenceAssignment{}, "Group") {
+		if err := db.Migrator().DropConstraint(&model.GeofenceAssignment{}, "Group"); err != nil {
+			logging.Fatal(logger, "Failed to drop GeofenceAssignment group foreign key", logging.Err(err))
+		}
+	}
+	if err := db.AutoMigrate(&model.GeofenceAssignment{}); err != nil {
+		logging.Fatal(logger, "Failed to migrate GeofenceAssignment", logging.Err(err))
 	}
-	log.Println("[MIGRATE] ✅ GeofenceEvent table migrated")
+	logger.Info("GeofenceAssignment table migrated")
 
 	// Seed some sample geofences
-	seedGeofences(db)
+	seedGeofences(db, logger)
 
-	log.Println("[MIGRATE] 🎉 Database migrated successfully")
+	logger.Info("Database migrated successfully")
 }
 
-func seedGeofences(db *gorm.DB) {
+func seedGeofences(db *gorm.DB, logger *slog.Logger) {
 	// Check if Bundaran HI geofence already exists
 	var count int64
 	db.Model(&model.Geofence{}).Where("name = ?", "Bundaran HI").Count(&count)
 	if count > 0 {
-		log.Println("[MIGRATE] 📍 Bundaran HI geofence already exists, skipping seed")
+		logger.Info("Geofence already exists, skipping seed", slog.String("geofence", "Bundaran HI"))
 		return
 	}
 
@@ -60,8 +96,8 @@ func seedGeofences(db *gorm.DB) {
 	}
 
 	if err := db.Create(&bundaranHI).Error; err != nil {
-		log.Printf("[MIGRATE] ⚠️ Failed to seed Bundaran HI geofence: %v", err)
+		logger.Error("Failed to seed geofence", slog.String("geofence", "Bundaran HI"), logging.Err(err))
 		return
 	}
-	log.Println("[MIGRATE] 📍 Bundaran HI geofence seeded successfully")
+	logger.Info("Seeded geofence", slog.String("geofence", "Bundaran HI"), slog.Int64("geofence_id", bundaranHI.ID))
 }
@@ -4,41 +4,51 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"math"
 	"math/rand"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
-	"github.com/joho/godotenv"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/config"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
 )
 
 type VehicleLocationPayload struct {
-	VehicleID string    `json:"vehicle_id"`
-	Latitude  float64   `json:"latitude"`
-	Longitude float64   `json:"longitude"`
-	Speed     float64   `json:"speed"`
-	Timestamp time.Time `json:"timestamp"`
+	VehicleID  string    `json:"vehicle_id"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	Speed      float64   `json:"speed"`
+	Heading    float64   `json:"heading"`
+	Satellites int       `json:"satellites"`
+	Timestamp  time.Time `json:"timestamp"`
 }
 
+// serviceName identifies the publisher in logs
+const serviceName = "vehicle-tracker-publisher"
+
 func meterToLatOffset(m float64) float64 {
 	return m / 111320 // 1 degree = 111320 meters
 }
 
 func main() {
 
-	godotenv.Load()
-
-	brokerEnv := os.Getenv("MQTT_BROKER")
-	if brokerEnv == "" {
-		brokerEnv = "tcp://mqtt:1883"
+	cfg, err := config.Load()
+	if err != nil {
+		logging.Fatal(slog.Default(), "Invalid configuration", logging.Err(err))
+	}
+	logger, err := logging.Setup(serviceName, cfg.Logging.Level, cfg.Logging.Format)
+	if err != nil {
+		logging.Fatal(slog.Default(), "Invalid logging configuration", logging.Err(err))
 	}
 
 	var (
-		broker     = flag.String("broker", brokerEnv, "MQTT broker URL")
+		broker     = flag.String("broker", cfg.MQTT.BrokerURL(), "MQTT broker URL")
 		vehicleID  = flag.String("vehicle-id", "TJ001", "Vehicle ID")
 		interval   = flag.Int("interval", 2, "Seconds between messages")
 		count      = flag.Int("count", 0, "Number of messages to send (0=infinite)")
@@ -47,11 +57,11 @@ func main() {
 	)
 	flag.Parse()
 
-	log.Printf("[PUBLISHER] Starting publisher, connecting to %s", *broker)
+	logger.Info("Starting publisher", slog.String("broker", *broker))
 	client := mqtt.NewClient(mqtt.NewClientOptions().AddBroker(*broker))
 
 	if token := client.Connect(); token.Wait() && token.Error() != nil {
-		log.Fatalf("[PUBLISHER] Failed to connect to MQTT broker: %v", token.Error())
+		logging.Fatal(logger, "Failed to connect to MQTT broker", logging.Err(token.Error()))
 	}
 
 	id := *vehicleID
@@ -62,10 +72,8 @@ func main() {
 	baseLat := -6.193125  // Bundaran HI
 	baseLon := 106.820233 // Bundaran HI
 
-	topic := os.Getenv("MQTT_TOPIC")
-	if topic == "" {
-		topic = fmt.Sprintf("fleet/vehicle/%s/location", id)
-	}
+	// Publish to this vehicle's topic within the subscribed pattern
+	topic := strings.Replace(cfg.MQTT.Topic, "+", id, 1)
 
 	// Setup graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -73,7 +81,7 @@ func main() {
 	done := make(chan struct{})
 	go func() {
 		<-sigChan
-		log.Println("[PUBLISHER] Interrupt received, shutting down...")
+		logger.Info("Interrupt received, shutting down")
 		done <- struct{}{}
 	}()
 
@@ -90,21 +98,28 @@ func main() {
 		select {
 		case <-done:
 			client.Disconnect(250)
-			log.Printf("[PUBLISHER] Finished sending %d messages", sent)
+			logger.Info("Finished sending messages", slog.Int("count", sent))
 			return
 		case <-ticker.C:
 			lat := baseLat + offset
+			// The vehicle drives due north, then due south on the way back
+			heading := 0.0
+			if direction < 0 {
+				heading = 180
+			}
 			payload := VehicleLocationPayload{
-				VehicleID: id,
-				Latitude:  lat,
-				Longitude: baseLon,
-				Speed:     *speed,
-				Timestamp: time.Now(),
+				VehicleID:  id,
+				Latitude:   lat,
+				Longitude:  baseLon,
+				Speed:      *speed,
+				Heading:    heading,
+				Satellites: 8 + rand.Intn(5),
+				Timestamp:  time.Now(),
 			}
 
 			data, err := json.Marshal(payload)
 			if err != nil {
-				log.Printf("[PUBLISHER] Failed to marshal payload: %v", err)
+				logger.Error("Failed to marshal payload", logging.Err(err))
 				continue
 			}
 
@@ -112,13 +127,13 @@ func main() {
 			token.Wait()
 
 			if token.Error() != nil {
-				log.Printf("[PUBLISHER] Failed to publish message: %v", token.Error())
+				logger.Error("Failed to publish message", logging.VehicleID(id), logging.Queue(topic), logging.Err(token.Error()))
 			}
 
 			sent++
 			if *count > 0 && sent >= *count {
 				client.Disconnect(250)
-				log.Printf("[PUBLISHER] Finished sending %d messages", sent)
+				logger.Info("Finished sending messages", slog.Int("count", sent))
 				return
 			}
 
@@ -126,7 +141,8 @@ func main() {
 			if math.Abs(offset) >= limit {
 				direction *= -1
 			}
-			log.Printf("[PUBLISHER] Sent location: vehcile=%s lat=%.6f lon=%.6f speed=%.2f", id, lat, baseLon, *speed)
+			logger.Info("Sent location", logging.VehicleID(id), logging.Queue(topic),
+				slog.Float64("latitude", lat), slog.Float64("longitude", baseLon), slog.Float64("speed", *speed))
 		}
 	}
 }
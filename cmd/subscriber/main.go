@@ -1,41 +1,88 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 
-	"github.com/joho/godotenv"
 	"github.com/redis/go-redis/v9"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/app/service"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/config"
 	mqtt_handler "github.com/satryo-pramahardi/go-vehicle-tracker/internal/delivery/mqtt"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/metrics"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/shutdown"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/tracing"
 )
 
+// serviceName identifies the subscriber in logs and traces
+const serviceName = "vehicle-tracker-subscriber"
+
 func main() {
-	godotenv.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		logging.Fatal(slog.Default(), "Invalid configuration", logging.Err(err))
+	}
+	logger, err := logging.Setup(serviceName, cfg.Logging.Level, cfg.Logging.Format)
+	if err != nil {
+		logging.Fatal(slog.Default(), "Invalid logging configuration", logging.Err(err))
+	}
+	logger.Info("Loaded configuration", slog.Any("config", cfg))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	stopTracing, err := tracing.Setup(ctx, cfg.Tracing, serviceName)
+	if err != nil {
+		logging.Fatal(logger, "Failed to set up tracing", logging.Err(err))
+	}
+
+	validator, err := service.NewLocationValidator(cfg.Ingest.VehicleIDPattern, cfg.Ingest.MaxClockSkew, cfg.Ingest.MaxAge)
+	if err != nil {
+		logging.Fatal(logger, "Invalid ingest configuration", logging.Err(err))
+	}
 
-	config := mqtt_handler.LoadMqttConfig()
-	client := mqtt_handler.NewMQTTClient(config)
+	client := mqtt_handler.NewMQTTClient(cfg.MQTT)
 
 	if err := client.Connect(); err != nil {
-		log.Fatalf("[SUBSCRIBER] Failed to connect to MQTT broker: %v", err)
+		logging.Fatal(logger, "Failed to connect to MQTT broker", logging.Err(err))
 	}
 
-	redisAddr := os.Getenv("REDIS_ADDR")
-	rdb := redis.NewClient(&redis.Options{
-		Addr: redisAddr,
-	})
+	rdb := redis.NewClient(cfg.Redis.Options())
+
+	// Pushes outlive the stop signal so that received updates still reach
+	// Redis; they are only abandoned once the shutdown timeout passes
+	pushCtx, abort := context.WithCancel(context.Background())
+	defer abort()
+	var inflight sync.WaitGroup
 
 	// Subscribe to MQTT topic
-	if err := client.Subscribe(config.Topic, mqtt_handler.MessageHandler(rdb)); err != nil {
-		log.Fatalf("[SUBSCRIBER] Failed to subscribe to topic: %v", err)
+	if err := client.Subscribe(cfg.MQTT.Topic, mqtt_handler.MessageHandler(pushCtx, rdb, validator, &inflight)); err != nil {
+		logging.Fatal(logger, "Failed to subscribe to topic", logging.Err(err))
 	}
 
-	// Wait for interrupt signal
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	stopMetrics := metrics.Serve(cfg.Metrics.Addr)
 
-	<-sigChan
+	// Wait for interrupt signal
+	<-ctx.Done()
+	logger.Info("Shutting down")
 
+	// Stop receiving before draining, so no new pushes start
 	client.Disconnect()
+	if !shutdown.Wait(&inflight, cfg.ShutdownTimeout) {
+		logger.Warn("Timed out waiting for in-flight pushes, abandoning them")
+		abort()
+	}
+	if err := stopMetrics(context.Background()); err != nil {
+		logger.Error("Failed to stop metrics server", logging.Err(err))
+	}
+	if err := rdb.Close(); err != nil {
+		logger.Error("Failed to close Redis", logging.Err(err))
+	}
+	if err := stopTracing(context.Background()); err != nil {
+		logger.Error("Failed to flush traces", logging.Err(err))
+	}
 }
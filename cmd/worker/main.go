@@ -1,37 +1,146 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"os"
+	"os/signal"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/redis/go-redis/v9"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
 
 	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/app/service"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/config"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/db"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/metrics"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/queue"
+	geofencepg "github.com/satryo-pramahardi/go-vehicle-tracker/internal/repository/postgres"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/shutdown"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/tracing"
 )
 
+// serviceName identifies the worker in logs and traces
+const serviceName = "vehicle-tracker-worker"
+
 func main() {
-	// Load config from env
-	redisAddr := os.Getenv("REDIS_ADDR")
-	dbDsn := os.Getenv("DB_DSN")
+	cfg, err := config.Load()
+	if err != nil {
+		logging.Fatal(slog.Default(), "Invalid configuration", logging.Err(err))
+	}
+	logger, err := logging.Setup(serviceName, cfg.Logging.Level, cfg.Logging.Format)
+	if err != nil {
+		logging.Fatal(slog.Default(), "Invalid logging configuration", logging.Err(err))
+	}
+	logger.Info("Loaded configuration", slog.Any("config", cfg))
+
+	// Stop consuming on SIGINT or SIGTERM; in-flight batches still finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	stopTracing, err := tracing.Setup(ctx, cfg.Tracing, serviceName)
+	if err != nil {
+		logging.Fatal(logger, "Failed to set up tracing", logging.Err(err))
+	}
 
-	// Connect to Redis
-	rdb := redis.NewClient(&redis.Options{
-		Addr: redisAddr,
-	})
+	rdb := redis.NewClient(cfg.Redis.Options())
+	gormDB := db.ConnectGorm(cfg.Postgres)
 
-	// Connect to Postgres
-	db, err := gorm.Open(postgres.Open(dbDsn), &gorm.Config{})
+	// Setup RabbitMQ service
+	rabbitMQ, err := service.NewRabbitMQService(cfg.RabbitMQ.ConnString(), cfg.Alerts)
 	if err != nil {
-		log.Fatalf("[WORKER] Failed to connect to DB: %v", err)
+		logger.Warn("Failed to initialize RabbitMQ, alerts will not be published", logging.Err(err))
+		// Continue without RabbitMQ - geofence events still saved to DB
+		rabbitMQ = nil
+	} else {
+		logger.Info("RabbitMQ service initialized successfully")
 	}
 
-	// Start workers as goroutines
-	go service.SaveEventLogFromRedis(rdb, db)
-	go service.SaveVehicleLocationFromRedis(rdb, db)
-	go service.ArchiveDeadLetterWorker(rdb)
+	// Geofences and their assignments are cached in memory and reloaded when
+	// the API announces a change
+	geofenceCache := service.NewGeofenceCache(
+		geofencepg.NewGeofenceRepository(gormDB),
+		geofencepg.NewGeofenceAssignmentRepository(gormDB),
+		time.Minute,
+	)
 
-	// Block main from exiting
-	select {}
+	// Rebuild per-vehicle geofence state, optionally shared with other workers via Redis
+	var stateRedis *redis.Client
+	if cfg.Worker.GeofenceStateRedis {
+		stateRedis = rdb
+	}
+	geofenceEventRepo := geofencepg.NewGeofenceEventRepository(gormDB)
+	geofenceStates := service.NewGeofenceStateCache(stateRedis)
+	if err := geofenceStates.Load(ctx, geofenceEventRepo); err != nil {
+		logging.Fatal(logger, "Failed to load geofence state", logging.Err(err))
+	}
+
+	// Workers consume Redis streams through consumer groups, so additional
+	// worker processes share the load
+	workerID := cfg.Worker.ID
+	if workerID == "" {
+		workerID = service.DefaultWorkerID()
+	}
+	logger.Info("Consuming", slog.String("worker_id", workerID))
+	redisQueue := queue.NewRedisQueue(rdb, workerID)
+	if n, err := redisQueue.ImportLegacyDeadLetters(ctx); err != nil {
+		logger.Error("Failed to import legacy dead letters", logging.Err(err))
+	} else if n > 0 {
+		logger.Info("Imported legacy dead letters", slog.Int("count", n))
+	}
+
+	geofenceService := service.NewGeofenceService(geofenceCache, geofenceStates, geofenceEventRepo, redisQueue, rabbitMQ)
+	eventLogWorker := service.NewEventLogWorker(redisQueue, geofencepg.NewEventLogRepository(gormDB), cfg.Worker.EventLog.Retry.Policy())
+	concurrency := cfg.Worker.Location.Concurrency
+	if concurrency == 0 {
+		concurrency = runtime.NumCPU()
+	}
+	locationWorker := service.NewLocationWorker(redisQueue, geofencepg.NewVehicleLocationRepository(gormDB), geofenceService, cfg.Worker.Location.Retry.Policy(), cfg.Worker.Location.BatchPolicy(), concurrency)
+
+	// Start workers as goroutines. The scheduler publishes failed messages
+	// back onto their stream once their retry backoff has passed.
+	var wg sync.WaitGroup
+	run := func(fn func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn()
+		}()
+	}
+	run(func() { redisQueue.RunScheduler(ctx, queue.DefaultSchedulePoll) })
+	run(func() { eventLogWorker.Run(ctx) })
+	run(func() { locationWorker.Run(ctx) })
+	run(func() { service.MonitorStreams(ctx, redisQueue, time.Minute) })
+	run(func() { geofenceCache.WatchGeofenceChanges(ctx, rdb) })
+
+	// Serve /metrics, including the stream backlog read at scrape time
+	prometheus.MustRegister(service.NewStreamCollector(redisQueue))
+	stopMetrics := metrics.Serve(cfg.Metrics.Addr)
+
+	<-ctx.Done()
+	logger.Info("Shutting down, waiting for in-flight messages")
+	if !shutdown.Wait(&wg, cfg.ShutdownTimeout) {
+		// Unacknowledged messages stay pending and are handled again on restart
+		logger.Warn("Timed out waiting for in-flight messages")
+	}
+	if err := stopMetrics(context.Background()); err != nil {
+		logger.Error("Failed to stop metrics server", logging.Err(err))
+	}
+
+	if rabbitMQ != nil {
+		rabbitMQ.Close()
+	}
+	if err := rdb.Close(); err != nil {
+		logger.Error("Failed to close Redis", logging.Err(err))
+	}
+	if err := db.Close(gormDB); err != nil {
+		logger.Error("Failed to close database", logging.Err(err))
+	}
+	if err := stopTracing(context.Background()); err != nil {
+		logger.Error("Failed to flush traces", logging.Err(err))
+	}
 }
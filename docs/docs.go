@@ -15,13 +15,959 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/dead-letters": {
+            "get": {
+                "description": "List dead-lettered entries, oldest first, optionally filtered by queue, error and failure time",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List dead letters",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Original queue",
+                        "name": "queue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case-insensitive error message match",
+                        "name": "error",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Failed at or after (RFC3339)",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Failed at or before (RFC3339)",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of results (default and max 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.DeadLetterListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/dead-letters/purge": {
+            "post": {
+                "description": "Permanently delete the selected entries",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Purge dead letters",
+                "parameters": [
+                    {
+                        "description": "Entries to purge",
+                        "name": "selection",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.DeadLetterSelection"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_satryo-pramahardi_go-vehicle-tracker_internal_queue.DeadLetterResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/dead-letters/replay": {
+            "post": {
+                "description": "Publish the selected entries back onto their original queues and remove them. Entries without a queue are reported as failed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Replay dead letters",
+                "parameters": [
+                    {
+                        "description": "Entries to replay",
+                        "name": "selection",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.DeadLetterSelection"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_satryo-pramahardi_go-vehicle-tracker_internal_queue.DeadLetterResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/dead-letters/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get dead letter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Dead letter ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_satryo-pramahardi_go-vehicle-tracker_internal_model.DeadLetterEntry"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Delete dead letter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Dead letter ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/geofences": {
+            "get": {
+                "description": "List geofences, optionally filtered by active flag, shape and name",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "geofences"
+                ],
+                "summary": "List geofences",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Filter by active flag",
+                        "name": "active",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "circle",
+                            "polygon",
+                            "multipolygon"
+                        ],
+                        "type": "string",
+                        "description": "Filter by shape",
+                        "name": "shape",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case-insensitive name match",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of results",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of results to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.GeofenceListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a circular, polygon or multipolygon geofence",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "geofences"
+                ],
+                "summary": "Create geofence",
+                "parameters": [
+                    {
+                        "description": "Geofence",
+                        "name": "geofence",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.GeofenceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_satryo-pramahardi_go-vehicle-tracker_internal_model.Geofence"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/geofences/{id}": {
+            "get": {
+                "description": "Get a geofence by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "geofences"
+                ],
+                "summary": "Get geofence",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Geofence ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_satryo-pramahardi_go-vehicle-tracker_internal_model.Geofence"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace a geofence definition; active is left unchanged when omitted",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "geofences"
+                ],
+                "summary": "Update geofence",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Geofence ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Geofence",
+                        "name": "geofence",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.GeofenceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_satryo-pramahardi_go-vehicle-tracker_internal_model.Geofence"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Permanently delete a geofence",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "geofences"
+                ],
+                "summary": "Delete geofence",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Geofence ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/geofences/{id}/activate": {
+            "post": {
+                "description": "Resume evaluating a deactivated geofence",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "geofences"
+                ],
+                "summary": "Activate geofence",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Geofence ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/geofences/{id}/assignments": {
+            "get": {
+                "description": "List the vehicles and groups a geofence is restricted to. A geofence without assignments applies to every vehicle.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "geofences"
+                ],
+                "summary": "List geofence assignments",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Geofence ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_satryo-pramahardi_go-vehicle-tracker_internal_model.GeofenceAssignment"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Restrict a geofence to a vehicle or a vehicle group. Exactly one of vehicle_id and group_id must be set.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "geofences"
+                ],
+                "summary": "Assign geofence",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Geofence ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Assignment",
+                        "name": "assignment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.GeofenceAssignmentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_satryo-pramahardi_go-vehicle-tracker_internal_model.GeofenceAssignment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/geofences/{id}/assignments/{assignment_id}": {
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "geofences"
+                ],
+                "summary": "Delete geofence assignment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Geofence ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Assignment ID",
+                        "name": "assignment_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/geofences/{id}/deactivate": {
+            "post": {
+                "description": "Stop evaluating a geofence without deleting it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "geofences"
+                ],
+                "summary": "Deactivate geofence",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Geofence ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/healthz": {
             "get": {
                 "description": "Check if the API is up",
                 "tags": [
-                    "health"
+                    "health"
+                ],
+                "summary": "Health check",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/vehicle-groups": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "vehicle-groups"
+                ],
+                "summary": "List vehicle groups",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_satryo-pramahardi_go-vehicle-tracker_internal_model.VehicleGroup"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a named group of vehicles that geofences can be assigned to",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "vehicle-groups"
+                ],
+                "summary": "Create vehicle group",
+                "parameters": [
+                    {
+                        "description": "Vehicle group",
+                        "name": "group",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.VehicleGroupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_satryo-pramahardi_go-vehicle-tracker_internal_model.VehicleGroup"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/vehicle-groups/{id}": {
+            "delete": {
+                "description": "Delete a group along with its memberships. A group that is still assigned to geofences can't be deleted.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "vehicle-groups"
+                ],
+                "summary": "Delete vehicle group",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Vehicle group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/vehicle-groups/{id}/vehicles": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "vehicle-groups"
+                ],
+                "summary": "List group vehicles",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Vehicle group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/vehicle-groups/{id}/vehicles/{vehicle_id}": {
+            "put": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "vehicle-groups"
+                ],
+                "summary": "Add vehicle to group",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Vehicle group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Vehicle ID",
+                        "name": "vehicle_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "vehicle-groups"
+                ],
+                "summary": "Remove vehicle from group",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Vehicle group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Vehicle ID",
+                        "name": "vehicle_id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Health check",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -29,6 +975,18 @@ const docTemplate = `{
                             "type": "object",
                             "additionalProperties": true
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
                     }
                 }
             }
@@ -61,6 +1019,18 @@ const docTemplate = `{
                         "name": "end",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Attribute filter given as attr[key]=value, e.g. attr[ignition]=false; repeat for more keys. true and false match booleans and numeric values match numbers, which must be finite. Wrap a value in double quotes to match it as a string.",
+                        "name": "attr",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated attribute keys to return, e.g. fuel_level,ignition; all when omitted",
+                        "name": "attributes",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -82,6 +1052,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_delivery_http.ErrorResponse"
+                        }
                     }
                 }
             }
@@ -100,6 +1076,12 @@ const docTemplate = `{
                         "name": "vehicle_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated attribute keys to return, e.g. fuel_level,ignition; all when omitted",
+                        "name": "attributes",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -120,6 +1102,233 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "github_com_satryo-pramahardi_go-vehicle-tracker_internal_model.DeadLetterEntry": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_satryo-pramahardi_go-vehicle-tracker_internal_model.DeliveryAttempt"
+                    }
+                },
+                "error_msg": {
+                    "type": "string"
+                },
+                "event_json": {
+                    "type": "string"
+                },
+                "failed_at": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "queue": {
+                    "type": "string"
+                },
+                "retry_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_satryo-pramahardi_go-vehicle-tracker_internal_model.DeliveryAttempt": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "failed_at": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_satryo-pramahardi_go-vehicle-tracker_internal_model.Geofence": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "center_lat": {
+                    "description": "circle only",
+                    "type": "number"
+                },
+                "center_lng": {
+                    "description": "circle only",
+                    "type": "number"
+                },
+                "coordinates": {
+                    "description": "GeoJSON-style [lng, lat] coordinates for polygon and multipolygon shapes",
+                    "type": "object"
+                },
+                "depart_by": {
+                    "description": "Daily \"HH:MM\" time by which vehicles should have left, in the\nschedule's timezone or UTC without one; an overdue event is raised for\nvehicles still inside. Empty disables it.",
+                    "type": "string",
+                    "example": "18:00"
+                },
+                "dwell_minutes": {
+                    "description": "Minutes inside before a dwell event is raised; zero disables dwell events",
+                    "type": "integer"
+                },
+                "hysteresis": {
+                    "description": "Meters outside the boundary a vehicle must reach before it counts as\nhaving left; zero uses DefaultGeofenceHysteresis",
+                    "type": "number"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "radius": {
+                    "description": "meters, circle only",
+                    "type": "number"
+                },
+                "schedule": {
+                    "description": "Optional windows outside of which the geofence is not enforced",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_satryo-pramahardi_go-vehicle-tracker_internal_model.GeofenceSchedule"
+                        }
+                    ]
+                },
+                "shape": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_satryo-pramahardi_go-vehicle-tracker_internal_model.GeofenceAssignment": {
+            "type": "object",
+            "properties": {
+                "geofence_id": {
+                    "type": "integer"
+                },
+                "group_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "vehicle_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_satryo-pramahardi_go-vehicle-tracker_internal_model.GeofenceSchedule": {
+            "type": "object",
+            "properties": {
+                "timezone": {
+                    "description": "IANA timezone name, UTC when empty",
+                    "type": "string",
+                    "example": "Asia/Jakarta"
+                },
+                "windows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_satryo-pramahardi_go-vehicle-tracker_internal_model.ScheduleWindow"
+                    }
+                }
+            }
+        },
+        "github_com_satryo-pramahardi_go-vehicle-tracker_internal_model.ScheduleWindow": {
+            "type": "object",
+            "properties": {
+                "days": {
+                    "description": "Three-letter lowercase day names (mon, tue, ...); empty means every day",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "mon",
+                        "tue",
+                        "wed",
+                        "thu",
+                        "fri"
+                    ]
+                },
+                "end": {
+                    "type": "string",
+                    "example": "08:30"
+                },
+                "start": {
+                    "type": "string",
+                    "example": "06:30"
+                }
+            }
+        },
+        "github_com_satryo-pramahardi_go-vehicle-tracker_internal_model.VehicleGroup": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_satryo-pramahardi_go-vehicle-tracker_internal_queue.DeadLetterResult": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "succeeded": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "internal_delivery_http.DeadLetterListResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_satryo-pramahardi_go-vehicle-tracker_internal_model.DeadLetterEntry"
+                    }
+                }
+            }
+        },
+        "internal_delivery_http.DeadLetterSelection": {
+            "type": "object",
+            "properties": {
+                "all": {
+                    "type": "boolean"
+                },
+                "error": {
+                    "type": "string",
+                    "example": "connection refused"
+                },
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "42"
+                    ]
+                },
+                "queue": {
+                    "type": "string",
+                    "example": "event_log:stream"
+                },
+                "since": {
+                    "type": "string"
+                },
+                "until": {
+                    "type": "string"
+                }
+            }
+        },
         "internal_delivery_http.ErrorResponse": {
             "type": "object",
             "properties": {
@@ -135,26 +1344,155 @@ const docTemplate = `{
                 }
             }
         },
+        "internal_delivery_http.GeofenceAssignmentRequest": {
+            "type": "object",
+            "properties": {
+                "group_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "vehicle_id": {
+                    "type": "string",
+                    "example": "BUS-001"
+                }
+            }
+        },
+        "internal_delivery_http.GeofenceListResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "geofences": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_satryo-pramahardi_go-vehicle-tracker_internal_model.Geofence"
+                    }
+                }
+            }
+        },
+        "internal_delivery_http.GeofenceRequest": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "description": "Omitted means active on create and unchanged on update",
+                    "type": "boolean"
+                },
+                "center_lat": {
+                    "type": "number",
+                    "example": -6.193125
+                },
+                "center_lng": {
+                    "type": "number",
+                    "example": 106.820233
+                },
+                "coordinates": {
+                    "description": "GeoJSON-style [lng, lat] coordinates, required for polygon shapes",
+                    "type": "object"
+                },
+                "depart_by": {
+                    "description": "Daily HH:MM time, in the schedule's timezone or UTC, after which vehicles still inside raise geofence_overdue",
+                    "type": "string",
+                    "example": "18:00"
+                },
+                "dwell_minutes": {
+                    "description": "Minutes inside before a geofence_dwell event; 0 disables it",
+                    "type": "integer",
+                    "example": 45
+                },
+                "hysteresis": {
+                    "description": "Meters beyond the boundary a vehicle must reach to exit, greater than 0\nand at most 1000; omitted uses the default of 5",
+                    "type": "number",
+                    "example": 5
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Bundaran HI"
+                },
+                "radius": {
+                    "type": "number",
+                    "example": 100
+                },
+                "schedule": {
+                    "description": "Optional windows outside of which the geofence is not enforced",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_satryo-pramahardi_go-vehicle-tracker_internal_model.GeofenceSchedule"
+                        }
+                    ]
+                },
+                "shape": {
+                    "type": "string",
+                    "enum": [
+                        "circle",
+                        "polygon",
+                        "multipolygon"
+                    ],
+                    "example": "circle"
+                }
+            }
+        },
         "internal_delivery_http.LocationResponse": {
             "type": "object",
             "properties": {
+                "accuracy": {
+                    "type": "number",
+                    "example": 3.5
+                },
+                "altitude": {
+                    "type": "number",
+                    "example": 12.5
+                },
+                "attributes": {
+                    "description": "Sensor and IO values, e.g. {\"ignition\": true, \"fuel_level\": 62.5}",
+                    "type": "object"
+                },
+                "hdop": {
+                    "type": "number",
+                    "example": 0.9
+                },
                 "heading": {
-                    "type": "number"
+                    "type": "number",
+                    "example": 90
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
                 },
                 "latitude": {
-                    "type": "number"
+                    "type": "number",
+                    "example": -6.193125
                 },
                 "longitude": {
-                    "type": "number"
+                    "type": "number",
+                    "example": 106.820233
+                },
+                "received_at": {
+                    "type": "string"
+                },
+                "satellites": {
+                    "type": "integer",
+                    "example": 9
                 },
                 "speed": {
-                    "type": "number"
+                    "type": "number",
+                    "example": 42.5
                 },
                 "timestamp": {
                     "type": "string"
                 },
                 "vehicle_id": {
-                    "type": "string"
+                    "type": "string",
+                    "example": "B1234XYZ"
+                }
+            }
+        },
+        "internal_delivery_http.VehicleGroupRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "Depot A buses"
                 }
             }
         }
@@ -4,13 +4,62 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"os"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
 	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/queue"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func PushLocationUpdateToRedis(rdb *redis.Client, eventType, source string, payload []byte) error {
+var (
+	streamLog     = logging.Component("stream")
+	deadLetterLog = logging.Component("dead_letter")
+)
+
+// Topics feeding the workers. Each worker type consumes through its own
+// group, so running more worker processes spreads the load.
+const (
+	EventLogTopic        = "event_log:stream"
+	VehicleLocationTopic = "vehicle_location:stream"
+
+	EventLogGroup        = "event_log_workers"
+	VehicleLocationGroup = "location_workers"
+)
+
+// workerGroups pairs each worker topic with its consumer group
+var workerGroups = [][2]string{
+	{VehicleLocationTopic, VehicleLocationGroup},
+	{EventLogTopic, EventLogGroup},
+}
+
+// DefaultWorkerID names this process's consumers when no worker ID is
+// configured. A stable ID lets a restarted worker pick up its own pending
+// messages, which hostname-pid only gives within one container.
+func DefaultWorkerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "worker"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// PushLocationUpdateToRedis publishes a location update to the Redis streams
+// read by the workers
+func PushLocationUpdateToRedis(ctx context.Context, rdb *redis.Client, eventType, source string, payload []byte) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "PushLocationUpdateToRedis", trace.WithSpanKind(trace.SpanKindProducer))
+	defer func() { tracing.End(span, err) }()
+
+	return PushLocationUpdate(ctx, queue.NewRedisQueue(rdb, ""), eventType, source, payload)
+}
+
+// PushLocationUpdate publishes a location update to both the location and the
+// event log topics
+func PushLocationUpdate(ctx context.Context, q queue.Queue, eventType, source string, payload []byte) error {
 	envelope := model.EventEnvelope{
 		EventType: eventType,
 		Source:    source,
@@ -20,13 +69,13 @@ func PushLocationUpdateToRedis(rdb *redis.Client, eventType, source string, payl
 
 	errCh := make(chan error, 2)
 
-	// Push to both queues concurrently
+	// Push to both topics concurrently
 	go func() {
-		errCh <- sendEventToRedis(rdb, "event_log:queue", envelope)
+		errCh <- sendEvent(ctx, q, EventLogTopic, envelope)
 	}()
 
 	go func() {
-		errCh <- sendEventToRedis(rdb, "vehicle_location:queue", envelope)
+		errCh <- sendEvent(ctx, q, VehicleLocationTopic, envelope)
 	}()
 
 	// Wait for both operations to complete
@@ -53,39 +102,51 @@ func UnmarshalEnvelopePayload[T any](data []byte) (T, error) {
 	return result, nil
 }
 
-// helper function to push event to redis
-func sendEventToRedis(rdb *redis.Client, queueName string, envelope model.EventEnvelope) error {
+// helper function to publish an event envelope to a topic, carrying the trace
+// context of ctx
+func sendEvent(ctx context.Context, q queue.Queue, topic string, envelope model.EventEnvelope) error {
+	envelope.TraceContext = tracing.Inject(ctx)
 	data, err := json.Marshal(envelope)
 	if err != nil {
 		return err
 	}
 
-	return rdb.LPush(context.Background(), queueName, data).Err()
+	return q.Publish(ctx, topic, data)
 }
 
-// helper function to retrieve event from redis queue
-func getEventFromRedis(rdb *redis.Client, queueName string) (model.EventEnvelope, string, error) {
-	res, err := rdb.BRPop(context.Background(), 0, queueName).Result()
-	if err != nil {
-		return model.EventEnvelope{}, "", err
-	}
-
-	if len(res) < 2 {
-		return model.EventEnvelope{}, "", fmt.Errorf("invalid result format")
+// helper function to dead-letter a payload, logging any failure. topic is
+// empty for payloads that can't be replayed onto a topic.
+func pushDeadLetter(ctx context.Context, q queue.Queue, topic, eventJSON string, err error) {
+	if pushErr := q.DeadLetter(ctx, topic, queue.Message{Body: []byte(eventJSON)}, err); pushErr != nil {
+		deadLetterLog.ErrorContext(ctx, "Failed to push to dead letter queue", logging.Queue(topic), logging.Err(pushErr))
 	}
-
-	rawJSON := res[1]
-
-	var envelope model.EventEnvelope
-	err = json.Unmarshal([]byte(rawJSON), &envelope)
-	if err != nil {
-		return model.EventEnvelope{}, "", err
-	}
-
-	return envelope, rawJSON, nil
 }
 
-// GetEventFromRedis is an exported version for testing
-func GetEventFromRedis(rdb *redis.Client, queueName string) (model.EventEnvelope, string, error) {
-	return getEventFromRedis(rdb, queueName)
+// MonitorStreams logs the backlog of the worker consumer groups and the
+// number of retries waiting to come due, every interval until ctx is cancelled
+func MonitorStreams(ctx context.Context, q *queue.RedisQueue, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, g := range workerGroups {
+			status, err := q.Status(ctx, g[0], g[1])
+			if err != nil {
+				streamLog.ErrorContext(ctx, "Failed to read stream status", logging.Queue(g[0]), logging.Err(err))
+				continue
+			}
+			streamLog.InfoContext(ctx, "Stream status", logging.Queue(status.Stream), slog.String("group", status.Group),
+				slog.Int64("length", status.Length), slog.Int64("pending", status.Pending), slog.Int64("lag", status.Lag))
+		}
+		if scheduled, err := q.Scheduled(ctx); err != nil {
+			streamLog.ErrorContext(ctx, "Failed to count scheduled retries", logging.Err(err))
+		} else {
+			streamLog.InfoContext(ctx, "Scheduled retries", slog.Int64("count", scheduled))
+		}
+	}
 }
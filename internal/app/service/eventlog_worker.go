@@ -3,66 +3,68 @@ package service
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"time"
 
-	"github.com/redis/go-redis/v9"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
 	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
-	"gorm.io/gorm"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/queue"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/repository"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func SaveEventLogFromRedis(rdb *redis.Client, db *gorm.DB) {
-	for {
-		envelope, rawEventJSON, err := getEventFromRedis(rdb, "event_log:queue")
-		if err != nil {
-			log.Printf("[EVENTLOG_WORKER] Error popping event log from Redis: %v", err)
-			continue
-		}
+var eventLogWorkerLog = logging.Component("event_log_worker")
 
-		eventLog := model.EventLog{
-			EventType: envelope.EventType,
-			Timestamp: envelope.Timestamp,
-			Payload:   envelope.Payload,
-			Source:    envelope.Source,
-		}
+// EventLogWorker persists event log entries. Entries that fail to save are
+// retried according to its retry policy; entries that can't be decoded or run
+// out of attempts are dead-lettered.
+type EventLogWorker struct {
+	queue queue.Queue
+	repo  repository.EventLogRepository
+	retry queue.RetryPolicy
+}
 
-		if err := db.Create(&eventLog).Error; err != nil {
-			log.Printf("[EVENTLOG_WORKER] Failed to save event log: %v", err)
-			// push to dead letter queue
-			pushDeadLetter(rdb, rawEventJSON, err)
-		}
-	}
+func NewEventLogWorker(q queue.Queue, repo repository.EventLogRepository, retry queue.RetryPolicy) *EventLogWorker {
+	return &EventLogWorker{queue: q, repo: repo, retry: retry}
 }
 
-func pushDeadLetter(rdb *redis.Client, eventJSON string, err error) {
-	entry := model.DeadLetterEntry{
-		EventJSON: eventJSON,
-		ErrorMsg:  err.Error(),
-		FailedAt:  time.Now().Unix(),
-	}
-	entryJSON, _ := json.Marshal(entry)
-	_, pushErr := rdb.RPush(context.Background(), "event_log:dead_letter", entryJSON).Result()
-	if pushErr != nil {
-		log.Printf("[EVENTLOG_WORKER] Error pushing to dead letter queue: %v", pushErr)
-	}
+// Run consumes the event log topic until ctx is cancelled
+func (w *EventLogWorker) Run(ctx context.Context) error {
+	return w.queue.Consume(ctx, EventLogTopic, EventLogGroup, queue.Retry(w.queue, EventLogTopic, w.retry, w.observe))
 }
 
-// Worker that archives dead letter entries to a permanent failed list
-func ArchiveDeadLetterWorker(rdb *redis.Client) {
-	ctx := context.Background()
-	for {
-		result, err := rdb.BRPop(ctx, 0, "event_log:dead_letter").Result()
-		if err != nil {
-			log.Printf("[EVENTLOG_WORKER] Error popping event log from Redis: %v", err)
-			continue
-		}
+// observe handles msg, recording the processing metrics
+func (w *EventLogWorker) observe(ctx context.Context, msg queue.Message) error {
+	start := time.Now()
+	err := w.handle(ctx, msg)
+	observeWorker(eventLogWorkerName, start, err)
+	return err
+}
 
-		entryJSON := result[1]
-		log.Printf("[EVENTLOG_WORKER] Archiving dead letter entry: %s", entryJSON)
+func (w *EventLogWorker) handle(ctx context.Context, msg queue.Message) (err error) {
+	var envelope model.EventEnvelope
+	if err := json.Unmarshal(msg.Body, &envelope); err != nil {
+		eventLogWorkerLog.ErrorContext(ctx, "Failed to unmarshal event log", logging.Queue(EventLogTopic), slog.String("message_id", msg.ID), logging.Err(err))
+		return queue.Permanent(err)
+	}
+
+	ctx, span := tracing.Tracer().Start(tracing.Extract(ctx, envelope.TraceContext), "SaveEventLog",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attribute.String("event.type", envelope.EventType)))
+	defer func() { tracing.End(span, err) }()
+
+	eventLog := model.EventLog{
+		EventType: envelope.EventType,
+		Timestamp: envelope.Timestamp,
+		Payload:   envelope.Payload,
+		Source:    envelope.Source,
+	}
 
-		_, err = rdb.RPush(ctx, "event_log:dead_letter_queue", entryJSON).Result()
-		if err != nil {
-			log.Printf("[EVENTLOG_WORKER] Error pushing to permanent failed list: %v", err)
-		}
+	if err := w.repo.InsertEvent(ctx, &eventLog); err != nil {
+		eventLogWorkerLog.ErrorContext(ctx, "Failed to save event log", logging.EventType(envelope.EventType), logging.Err(err))
+		return err
 	}
+	return nil
 }
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/queue"
+)
+
+// fakeEventLogRepo stores inserted event logs in memory. It fails with err
+// every time if failures is zero, otherwise only the first failures times.
+type fakeEventLogRepo struct {
+	mu       sync.Mutex
+	events   []model.EventLog
+	err      error
+	failures int
+}
+
+func (r *fakeEventLogRepo) InsertEvent(ctx context.Context, evt *model.EventLog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		if r.failures == 0 {
+			return r.err
+		}
+		if r.failures--; r.failures == 0 {
+			r.err = nil
+		}
+		return errors.New("transient")
+	}
+	r.events = append(r.events, *evt)
+	return nil
+}
+
+func (r *fakeEventLogRepo) saved() []model.EventLog {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]model.EventLog(nil), r.events...)
+}
+
+func TestEventLogWorker_SavesEvents(t *testing.T) {
+	q := queue.NewMemoryQueue()
+	repo := &fakeEventLogRepo{}
+	require.NoError(t, PushLocationUpdate(context.Background(), q, "location_update", "mqtt-subscriber", []byte(`{"vehicle_id":"B1234XYZ"}`)))
+
+	worker := NewEventLogWorker(q, repo, testRetryPolicy)
+	runWorker(t, worker.Run, func() bool { return len(repo.saved()) == 1 })
+
+	saved := repo.saved()[0]
+	assert.Equal(t, "location_update", saved.EventType)
+	assert.Equal(t, "mqtt-subscriber", saved.Source)
+	assert.JSONEq(t, `{"vehicle_id":"B1234XYZ"}`, string(saved.Payload))
+	assert.False(t, saved.Timestamp.IsZero())
+	assert.Empty(t, q.DeadLetters())
+}
+
+func TestEventLogWorker_RetriesTransientFailures(t *testing.T) {
+	q := queue.NewMemoryQueue()
+	repo := &fakeEventLogRepo{err: errors.New("connection refused"), failures: 2}
+	require.NoError(t, q.Publish(context.Background(), EventLogTopic, []byte(`{"event_type":"location_update"}`)))
+
+	worker := NewEventLogWorker(q, repo, testRetryPolicy)
+	runWorker(t, worker.Run, func() bool { return len(repo.saved()) == 1 })
+
+	assert.Equal(t, "location_update", repo.saved()[0].EventType)
+	assert.Empty(t, q.DeadLetters())
+}
+
+func TestEventLogWorker_DeadLettersFailures(t *testing.T) {
+	q := queue.NewMemoryQueue()
+	repo := &fakeEventLogRepo{err: errors.New("connection refused")}
+	require.NoError(t, q.Publish(context.Background(), EventLogTopic, []byte("not json")))
+	require.NoError(t, q.Publish(context.Background(), EventLogTopic, []byte(`{"event_type":"save_error"}`)))
+
+	worker := NewEventLogWorker(q, repo, testRetryPolicy)
+	runWorker(t, worker.Run, func() bool { return len(q.DeadLetters()) == 2 })
+
+	// Undecodable entries are dead-lettered without retrying
+	entries := q.DeadLetters()
+	assert.Equal(t, "not json", entries[0].EventJSON)
+	assert.Equal(t, EventLogTopic, entries[0].Queue)
+	assert.Contains(t, entries[0].ErrorMsg, "invalid character")
+	assert.Len(t, entries[0].Attempts, 1)
+
+	assert.Equal(t, `{"event_type":"save_error"}`, entries[1].EventJSON)
+	assert.Equal(t, "connection refused", entries[1].ErrorMsg)
+	require.Len(t, entries[1].Attempts, testRetryPolicy.MaxAttempts)
+	assert.Equal(t, "connection refused", entries[1].Attempts[0].Error)
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/repository"
+)
+
+var cacheLog = logging.Component("geofence_cache")
+
+// GeofenceChangeChannel is the Redis pub/sub channel announcing geofence changes
+const GeofenceChangeChannel = "geofence:changed"
+
+// GeofenceCache keeps the active geofences and their vehicle assignments in
+// memory as an indexed GeofenceSet. It reloads from the repositories when
+// invalidated or once the refresh interval has elapsed.
+type GeofenceCache struct {
+	repo            repository.GeofenceRepository
+	assignmentRepo  repository.GeofenceAssignmentRepository
+	refreshInterval time.Duration
+
+	mu        sync.RWMutex
+	geofences *GeofenceSet
+	loadedAt  time.Time
+	stale     bool
+}
+
+// NewGeofenceCache creates the cache. assignmentRepo may be nil, in which
+// case every geofence applies to every vehicle.
+func NewGeofenceCache(repo repository.GeofenceRepository, assignmentRepo repository.GeofenceAssignmentRepository, refreshInterval time.Duration) *GeofenceCache {
+	return &GeofenceCache{
+		repo:            repo,
+		assignmentRepo:  assignmentRepo,
+		refreshInterval: refreshInterval,
+		stale:           true,
+	}
+}
+
+// Geofences returns the active geofences, reloading them if the cache is stale.
+// If a reload fails the previous set is returned along with the error.
+func (c *GeofenceCache) Geofences(ctx context.Context) (*GeofenceSet, error) {
+	c.mu.RLock()
+	if !c.needsReload() {
+		geofences := c.geofences
+		c.mu.RUnlock()
+		return geofences, nil
+	}
+	c.mu.RUnlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.needsReload() {
+		return c.geofences, nil
+	}
+
+	active := true
+	loaded, err := c.repo.ListGeofences(ctx, repository.GeofenceFilter{Active: &active})
+	if err != nil {
+		return c.geofences, err
+	}
+
+	var assignments map[int64][]string
+	if c.assignmentRepo != nil {
+		assignments, err = c.assignmentRepo.GeofenceVehicles(ctx)
+		if err != nil {
+			return c.geofences, err
+		}
+	}
+
+	geofences := make([]model.Geofence, 0, len(loaded))
+	for _, g := range loaded {
+		geofences = append(geofences, *g)
+	}
+	c.geofences = NewGeofenceSet(geofences, assignments)
+	c.loadedAt = time.Now()
+	c.stale = false
+	cacheLog.InfoContext(ctx, "Loaded active geofences", slog.Int("count", c.geofences.Len()))
+	return c.geofences, nil
+}
+
+// Invalidate forces the next Geofences call to reload from the repository
+func (c *GeofenceCache) Invalidate() {
+	c.mu.Lock()
+	c.stale = true
+	c.mu.Unlock()
+}
+
+func (c *GeofenceCache) needsReload() bool {
+	return c.stale || (c.refreshInterval > 0 && time.Since(c.loadedAt) >= c.refreshInterval)
+}
+
+// WatchGeofenceChanges invalidates the cache whenever a change is published on
+// GeofenceChangeChannel. It blocks until ctx is cancelled or the subscription
+// is closed.
+func (c *GeofenceCache) WatchGeofenceChanges(ctx context.Context, rdb *redis.Client) {
+	sub := rdb.Subscribe(ctx, GeofenceChangeChannel)
+	defer sub.Close()
+
+	changes := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-changes:
+			if !ok {
+				return
+			}
+			cacheLog.InfoContext(ctx, "Geofence changed, invalidating cache", slog.String("geofence_id", msg.Payload))
+			c.Invalidate()
+		}
+	}
+}
+
+// RedisGeofenceNotifier announces geofence changes on GeofenceChangeChannel
+type RedisGeofenceNotifier struct {
+	rdb *redis.Client
+}
+
+func NewRedisGeofenceNotifier(rdb *redis.Client) *RedisGeofenceNotifier {
+	return &RedisGeofenceNotifier{rdb: rdb}
+}
+
+func (n *RedisGeofenceNotifier) NotifyGeofenceChanged(ctx context.Context, id int64) {
+	if err := n.rdb.Publish(ctx, GeofenceChangeChannel, strconv.FormatInt(id, 10)).Err(); err != nil {
+		cacheLog.ErrorContext(ctx, "Failed to publish geofence change", slog.Int64("geofence_id", id), logging.Err(err))
+	}
+}
@@ -0,0 +1,383 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/geo"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/queue"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/repository"
+)
+
+// fakeGeofenceRepo serves geofences from memory and counts list queries
+type fakeGeofenceRepo struct {
+	repository.GeofenceRepository
+	geofences []*model.Geofence
+	err       error
+	queries   int
+}
+
+func (r *fakeGeofenceRepo) ListGeofences(ctx context.Context, filter repository.GeofenceFilter) ([]*model.Geofence, error) {
+	r.queries++
+	if r.err != nil {
+		return nil, r.err
+	}
+	var result []*model.Geofence
+	for _, g := range r.geofences {
+		if filter.Active == nil || g.Active == *filter.Active {
+			result = append(result, g)
+		}
+	}
+	return result, nil
+}
+
+// fakeAssignmentRepo serves resolved geofence assignments from memory
+type fakeAssignmentRepo struct {
+	repository.GeofenceAssignmentRepository
+	vehicles map[int64][]string
+	err      error
+}
+
+func (r *fakeAssignmentRepo) GeofenceVehicles(ctx context.Context) (map[int64][]string, error) {
+	return r.vehicles, r.err
+}
+
+// fakeGeofenceEventRepo stores geofence events in memory and counts queries
+type fakeGeofenceEventRepo struct {
+	mu      sync.Mutex
+	events  []*model.GeofenceEvent
+	queries int
+	err     error
+}
+
+func (r *fakeGeofenceEventRepo) InsertGeofenceEvent(ctx context.Context, evt *model.GeofenceEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.events = append(r.events, evt)
+	return nil
+}
+
+func (r *fakeGeofenceEventRepo) LatestGeofenceStates(ctx context.Context) ([]*model.GeofenceEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queries++
+	return r.events, nil
+}
+
+// lastEventType mirrors the per-geofence query the worker used to run for
+// every location update near a boundary
+func (r *fakeGeofenceEventRepo) lastEventType(vehicleID string, geofenceID int64) string {
+	r.queries++
+	for i := len(r.events) - 1; i >= 0; i-- {
+		if r.events[i].VehicleID == vehicleID && r.events[i].GeofenceID == geofenceID {
+			return r.events[i].EventType
+		}
+	}
+	return ""
+}
+
+func TestGeofenceCache_ReloadsOnlyWhenStale(t *testing.T) {
+	repo := &fakeGeofenceRepo{geofences: []*model.Geofence{
+		{ID: 1, Name: "Active", Active: true},
+		{ID: 2, Name: "Inactive", Active: false},
+	}}
+	cache := NewGeofenceCache(repo, nil, time.Hour)
+
+	geofences, err := cache.Geofences(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, geofences.Len())
+	assert.Equal(t, int64(1), geofences.geofences[0].ID)
+
+	_, _ = cache.Geofences(context.Background())
+	assert.Equal(t, 1, repo.queries)
+
+	cache.Invalidate()
+	_, _ = cache.Geofences(context.Background())
+	assert.Equal(t, 2, repo.queries)
+}
+
+func TestGeofenceCache_RefreshInterval(t *testing.T) {
+	repo := &fakeGeofenceRepo{}
+	cache := NewGeofenceCache(repo, nil, time.Millisecond)
+
+	_, _ = cache.Geofences(context.Background())
+	time.Sleep(2 * time.Millisecond)
+	_, _ = cache.Geofences(context.Background())
+	assert.Equal(t, 2, repo.queries)
+}
+
+func TestGeofenceCache_KeepsPreviousSetOnError(t *testing.T) {
+	repo := &fakeGeofenceRepo{geofences: []*model.Geofence{{ID: 1, Active: true}}}
+	cache := NewGeofenceCache(repo, nil, time.Hour)
+	_, _ = cache.Geofences(context.Background())
+
+	repo.err = errors.New("connection refused")
+	cache.Invalidate()
+	geofences, err := cache.Geofences(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 1, geofences.Len())
+}
+
+func TestGeofenceCache_LoadsAssignments(t *testing.T) {
+	repo := &fakeGeofenceRepo{geofences: []*model.Geofence{{ID: 1, Active: true}, {ID: 2, Active: true}}}
+	assignments := &fakeAssignmentRepo{vehicles: map[int64][]string{1: {"BUS-001"}}}
+	cache := NewGeofenceCache(repo, assignments, time.Hour)
+
+	geofences, err := cache.Geofences(context.Background())
+	require.NoError(t, err)
+	assert.True(t, geofences.appliesTo(1, "BUS-001"))
+	assert.False(t, geofences.appliesTo(1, "BUS-002"))
+	assert.True(t, geofences.appliesTo(2, "BUS-002"))
+
+	// A failed assignment load keeps the previous set rather than dropping
+	// the restrictions
+	assignments.err = errors.New("connection refused")
+	cache.Invalidate()
+	geofences, err = cache.Geofences(context.Background())
+	assert.Error(t, err)
+	assert.False(t, geofences.appliesTo(1, "BUS-002"))
+}
+
+func TestGeofenceStateCache_Load(t *testing.T) {
+	entered := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	eventRepo := &fakeGeofenceEventRepo{events: []*model.GeofenceEvent{
+		{VehicleID: "BUS-001", GeofenceID: 1, EventType: model.GeofenceEventDwell, Timestamp: entered.Add(time.Hour)},
+		{VehicleID: "BUS-001", GeofenceID: 1, EventType: model.GeofenceEventEntry, Timestamp: entered},
+		{VehicleID: "BUS-002", GeofenceID: 1, EventType: model.GeofenceEventExit, Timestamp: entered},
+		// Overdue from an earlier visit no longer applies
+		{VehicleID: "BUS-002", GeofenceID: 2, EventType: model.GeofenceEventOverdue, Timestamp: entered.Add(-time.Hour)},
+		{VehicleID: "BUS-002", GeofenceID: 2, EventType: model.GeofenceEventEntry, Timestamp: entered},
+	}}
+	states := NewGeofenceStateCache(nil)
+	require.NoError(t, states.Load(context.Background(), eventRepo))
+
+	bus1 := states.Vehicle(context.Background(), "BUS-001")
+	assert.True(t, bus1.Inside(1))
+	assert.Equal(t, GeofenceVisit{EventType: model.GeofenceEventEntry, Since: entered, DwellReported: true}, bus1.Geofences[1])
+	bus2 := states.Vehicle(context.Background(), "BUS-002")
+	assert.Equal(t, model.GeofenceEventExit, bus2.Geofences[1].EventType)
+	assert.Equal(t, GeofenceVisit{EventType: model.GeofenceEventEntry, Since: entered}, bus2.Geofences[2])
+	assert.Empty(t, states.Vehicle(context.Background(), "BUS-003").Geofences)
+
+	left := entered.Add(2 * time.Hour)
+	states.Set(context.Background(), "BUS-001", 1, model.GeofenceEventExit, left)
+	states.SetLastLocation(context.Background(), "BUS-001", geo.Point{Lat: -6.19, Lng: 106.82}, left)
+	state := states.Vehicle(context.Background(), "BUS-001")
+	assert.Equal(t, GeofenceVisit{EventType: model.GeofenceEventExit, Since: left}, state.Geofences[1])
+	assert.Equal(t, &geo.Point{Lat: -6.19, Lng: 106.82}, state.LastLocation)
+	assert.True(t, left.Equal(state.LastEvaluated))
+	assert.True(t, state.Stale(left.Add(-time.Second)))
+	assert.False(t, state.Stale(left))
+}
+
+func TestGeofenceStateCache_RedisKeysExpire(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+	eventRepo := &fakeGeofenceEventRepo{events: []*model.GeofenceEvent{
+		{VehicleID: "BUS-001", GeofenceID: 1, EventType: model.GeofenceEventEntry, Timestamp: time.Now()},
+	}}
+	states := NewGeofenceStateCache(rdb)
+	require.NoError(t, states.Load(ctx, eventRepo))
+	assert.Equal(t, geofenceStateTTL, mr.TTL(geofenceStateKeyPrefix+"BUS-001"))
+
+	states.SetLastLocation(ctx, "BUS-002", geo.Point{Lat: -6.19, Lng: 106.82}, time.Now())
+	mr.FastForward(geofenceStateTTL / 2)
+	states.Set(ctx, "BUS-002", 1, model.GeofenceEventEntry, time.Now())
+	assert.Equal(t, geofenceStateTTL, mr.TTL(geofenceStateKeyPrefix+"BUS-002"), "every write extends the expiry")
+
+	mr.FastForward(geofenceStateTTL)
+	assert.False(t, mr.Exists(geofenceStateKeyPrefix+"BUS-002"))
+	assert.Empty(t, states.Vehicle(ctx, "BUS-001").Geofences)
+}
+
+func TestGeofenceStateCache_LoadReplacesRedisState(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+	entered := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	key := geofenceStateKeyPrefix + "BUS-001"
+
+	// Left behind by an earlier run: geofence 2 has no recorded events any
+	// more, and the visit to geofence 3 predates the recorded one
+	mr.HSet(key, "2", model.GeofenceEventEntry,
+		"3", encodeVisit(GeofenceVisit{EventType: model.GeofenceEventEntry, Since: entered.Add(-time.Hour)}),
+		lastLocationField, "-6.193125,106.820233",
+		lastEvaluatedField, entered.Add(time.Hour).Format(time.RFC3339Nano))
+	// Written by a live worker after the events were read
+	mr.HSet(key, "4", encodeVisit(GeofenceVisit{EventType: model.GeofenceEventEntry, Since: entered.Add(2 * time.Hour)}),
+		"5", encodeVisit(GeofenceVisit{EventType: model.GeofenceEventEntry, Since: entered, DwellReported: true}))
+
+	eventRepo := &fakeGeofenceEventRepo{events: []*model.GeofenceEvent{
+		{VehicleID: "BUS-001", GeofenceID: 1, EventType: model.GeofenceEventEntry, Timestamp: entered},
+		{VehicleID: "BUS-001", GeofenceID: 3, EventType: model.GeofenceEventExit, Timestamp: entered},
+		{VehicleID: "BUS-001", GeofenceID: 5, EventType: model.GeofenceEventEntry, Timestamp: entered},
+	}}
+	require.NoError(t, NewGeofenceStateCache(rdb).Load(ctx, eventRepo))
+
+	state := NewGeofenceStateCache(rdb).Vehicle(ctx, "BUS-001")
+	assert.Equal(t, map[int64]GeofenceVisit{
+		1: {EventType: model.GeofenceEventEntry, Since: entered},
+		3: {EventType: model.GeofenceEventExit, Since: entered},
+		4: {EventType: model.GeofenceEventEntry, Since: entered.Add(2 * time.Hour)},
+		5: {EventType: model.GeofenceEventEntry, Since: entered, DwellReported: true},
+	}, state.Geofences)
+	assert.Equal(t, &geo.Point{Lat: -6.193125, Lng: 106.820233}, state.LastLocation)
+	assert.True(t, entered.Add(time.Hour).Equal(state.LastEvaluated))
+	assert.Equal(t, geofenceStateTTL, mr.TTL(key))
+}
+
+func TestParseVehicleGeofenceState(t *testing.T) {
+	since := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	visit := GeofenceVisit{EventType: model.GeofenceEventEntry, Since: since, OverdueReported: true}
+	state := parseVehicleGeofenceState(map[string]string{
+		"1":                encodeVisit(visit),
+		"2":                model.GeofenceEventExit,
+		lastLocationField:  "-6.193125,106.820233",
+		lastEvaluatedField: "2024-05-01T08:30:00.5Z",
+		"bogus":            "x",
+	})
+	assert.Equal(t, map[int64]GeofenceVisit{1: visit, 2: {EventType: model.GeofenceEventExit}}, state.Geofences)
+	assert.Equal(t, &geo.Point{Lat: -6.193125, Lng: 106.820233}, state.LastLocation)
+	assert.Equal(t, since.Add(30*time.Minute+500*time.Millisecond), state.LastEvaluated)
+}
+
+func TestGeofenceStateCache_Nil(t *testing.T) {
+	var states *GeofenceStateCache
+	states.Set(context.Background(), "BUS-001", 1, model.GeofenceEventEntry, time.Now())
+	states.SetLastLocation(context.Background(), "BUS-001", geo.Point{}, time.Now())
+	assert.False(t, states.Vehicle(context.Background(), "BUS-001").Inside(1))
+}
+
+func TestGeofenceService_RecordsStateWithoutQueries(t *testing.T) {
+	repo := &fakeGeofenceRepo{geofences: []*model.Geofence{{
+		ID: 1, Name: "Bundaran HI", CenterLat: -6.193125, CenterLng: 106.820233, Radius: 100, Active: true,
+	}}}
+	eventRepo := &fakeGeofenceEventRepo{}
+	states := NewGeofenceStateCache(nil)
+	svc := NewGeofenceService(NewGeofenceCache(repo, nil, time.Hour), states, eventRepo, nil, nil)
+
+	// 0.0008 degrees latitude ≈ 89m, just inside the boundary
+	loc := model.VehicleLocation{VehicleID: "BUS-001", Latitude: -6.192325, Longitude: 106.820233}
+	events, _, err := svc.detectEvents(context.Background(), loc)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	states.Set(context.Background(), loc.VehicleID, 1, events[0].EventType, time.Now())
+	events, _, _ = svc.detectEvents(context.Background(), loc)
+	assert.Len(t, events, 0)
+
+	assert.Equal(t, 1, repo.queries)
+	assert.Equal(t, 0, eventRepo.queries)
+}
+
+func TestGeofenceService_PublishesEventsToEventLog(t *testing.T) {
+	repo := &fakeGeofenceRepo{geofences: []*model.Geofence{{
+		ID: 1, Name: "Bundaran HI", CenterLat: -6.193125, CenterLng: 106.820233, Radius: 100, Active: true,
+	}}}
+	eventRepo := &fakeGeofenceEventRepo{}
+	q := queue.NewMemoryQueue()
+	svc := NewGeofenceService(NewGeofenceCache(repo, nil, time.Hour), NewGeofenceStateCache(nil), eventRepo, q, nil)
+
+	svc.CallCheckGeofences(context.Background(), model.VehicleLocation{VehicleID: "BUS-001", Latitude: -6.193125, Longitude: 106.820233})
+
+	require.Len(t, eventRepo.events, 1)
+	assert.Equal(t, model.GeofenceEventEntry, eventRepo.events[0].EventType)
+	events := publishedEvents(t, q, EventLogTopic)
+	require.Len(t, events, 1)
+	assert.Equal(t, model.GeofenceEventEntry, events[0].EventType)
+	assert.Equal(t, "geofence_service", events[0].Source)
+	assert.Empty(t, q.DeadLetters())
+}
+
+func TestGeofenceService_DeadLettersUnsavedEvents(t *testing.T) {
+	repo := &fakeGeofenceRepo{geofences: []*model.Geofence{{
+		ID: 1, Name: "Bundaran HI", CenterLat: -6.193125, CenterLng: 106.820233, Radius: 100, Active: true,
+	}}}
+	eventRepo := &fakeGeofenceEventRepo{err: errors.New("connection refused")}
+	states := NewGeofenceStateCache(nil)
+	q := queue.NewMemoryQueue()
+	svc := NewGeofenceService(NewGeofenceCache(repo, nil, time.Hour), states, eventRepo, q, nil)
+
+	svc.CallCheckGeofences(context.Background(), model.VehicleLocation{VehicleID: "BUS-001", Latitude: -6.193125, Longitude: 106.820233})
+
+	entries := q.DeadLetters()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "connection refused", entries[0].ErrorMsg)
+	assert.Contains(t, entries[0].EventJSON, `"GeofenceID":1`)
+	assert.Empty(t, q.Messages(EventLogTopic))
+	assert.False(t, states.Vehicle(context.Background(), "BUS-001").Inside(1), "unsaved events must not change state")
+}
+
+// benchmarkFleet builds a grid of circular geofences and one vehicle parked on
+// the boundary of each, the worst case for the old per-geofence lookups
+func benchmarkFleet(n int) ([]*model.Geofence, []model.VehicleLocation) {
+	geofences := make([]*model.Geofence, 0, n)
+	locations := make([]model.VehicleLocation, 0, n)
+	for i := 0; i < n; i++ {
+		lat := -6.3 + float64(i/100)*0.01
+		lng := 106.7 + float64(i%100)*0.01
+		geofences = append(geofences, &model.Geofence{
+			ID: int64(i + 1), Name: fmt.Sprintf("Zone %d", i), CenterLat: lat, CenterLng: lng, Radius: 100, Active: true,
+		})
+		locations = append(locations, model.VehicleLocation{
+			VehicleID: fmt.Sprintf("BUS-%04d", i), Latitude: lat + 0.0009, Longitude: lng,
+		})
+	}
+	return geofences, locations
+}
+
+// BenchmarkGeofenceQueriesPerMessage counts the repository calls each location
+// update makes, not the cost of evaluating it; see BenchmarkCheckGeofences
+func BenchmarkGeofenceQueriesPerMessage(b *testing.B) {
+	geofences, locations := benchmarkFleet(1000)
+
+	// Previous behavior: load every active geofence, then query the last event
+	// for each geofence whose boundary band contains the point
+	b.Run("PerMessageQueries", func(b *testing.B) {
+		repo := &fakeGeofenceRepo{geofences: geofences}
+		eventRepo := &fakeGeofenceEventRepo{}
+		active := true
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			loc := locations[i%len(locations)]
+			loaded, _ := repo.ListGeofences(context.Background(), repository.GeofenceFilter{Active: &active})
+			for _, g := range loaded {
+				distance := geo.Haversine(loc.Latitude, loc.Longitude, g.CenterLat, g.CenterLng)
+				if math.Abs(distance-g.Radius) <= model.DefaultGeofenceHysteresis {
+					eventRepo.lastEventType(loc.VehicleID, g.ID)
+				}
+			}
+		}
+		b.ReportMetric(float64(repo.queries+eventRepo.queries)/float64(b.N), "queries/op")
+	})
+
+	b.Run("Cached", func(b *testing.B) {
+		repo := &fakeGeofenceRepo{geofences: geofences}
+		eventRepo := &fakeGeofenceEventRepo{}
+		states := NewGeofenceStateCache(nil)
+		if err := states.Load(context.Background(), eventRepo); err != nil {
+			b.Fatal(err)
+		}
+		svc := NewGeofenceService(NewGeofenceCache(repo, nil, time.Hour), states, eventRepo, nil, nil)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _, _ = svc.detectEvents(context.Background(), locations[i%len(locations)])
+		}
+		b.ReportMetric(float64(repo.queries+eventRepo.queries)/float64(b.N), "queries/op")
+	})
+}
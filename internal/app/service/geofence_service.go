@@ -1,113 +1,211 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"time"
 
-	"github.com/redis/go-redis/v9"
 	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/geo"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/metrics"
 	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
-	"gorm.io/gorm"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/queue"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/repository"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var geofenceLog = logging.Component("geofence_service")
+
 type GeofenceEvent struct {
 	VehicleID    string
 	GeofenceID   int64
 	GeofenceName string
-	EventType    string // "entering_geofence" or "exiting_geofence"
+	EventType    string // one of the model.GeofenceEvent* constants
 	Location     model.VehicleLocation
 }
 
-// CheckGeofences detects geofence entry/exit events with a 5m buffer zone
-func CheckGeofences(loc model.VehicleLocation, geofences []model.Geofence, db *gorm.DB) []GeofenceEvent {
+// CheckGeofences detects geofence entry/exit events by comparing the
+// vehicle's recorded state with its current position. When the last location
+// is known, the segment travelled since then is also tested so a vehicle that
+// crosses a geofence between two samples still produces events. Scheduled
+// geofences are only evaluated inside their windows, by the location time, and
+// assigned geofences only for their vehicles. A vehicle recorded inside a
+// geofence that no longer applies to it exits. Locations older than the last
+// one evaluated for the vehicle produce no events.
+func CheckGeofences(ctx context.Context, loc model.VehicleLocation, geofences *GeofenceSet, states *GeofenceStateCache) []GeofenceEvent {
+	state := states.Vehicle(ctx, loc.VehicleID)
+	if state.Stale(observedAt(loc)) {
+		return nil
+	}
+	return evaluateGeofences(loc, geofences, state)
+}
+
+// evaluateGeofences is CheckGeofences against an already read vehicle state
+func evaluateGeofences(loc model.VehicleLocation, geofences *GeofenceSet, state VehicleGeofenceState) []GeofenceEvent {
 	var events []GeofenceEvent
-	for _, geofence := range geofences {
-		var bufferMin = geofence.Radius - 5
-		var bufferMax = geofence.Radius + 5
-		distance := geo.Haversine(loc.Latitude, loc.Longitude, geofence.CenterLat, geofence.CenterLng)
-		inside := distance <= geofence.Radius
-
-		if distance >= bufferMin && distance <= bufferMax {
-			// Check last event state near boundary
-			lastEventType := getLastGeofenceEventType(loc.VehicleID, geofence.ID, db)
-
-			// If lastEventType matches, do nothing (no state change)
-			// else: outside buffer, do nothing (assume state hasn't changed)
-			if inside && lastEventType != model.GeofenceEventEntry {
-				events = append(events, GeofenceEvent{
-					VehicleID:    loc.VehicleID,
-					GeofenceID:   geofence.ID,
-					GeofenceName: geofence.Name,
-					EventType:    model.GeofenceEventEntry,
-					Location:     loc,
-				})
-			} else if !inside && lastEventType != model.GeofenceEventExit {
-				events = append(events, GeofenceEvent{
-					VehicleID:    loc.VehicleID,
-					GeofenceID:   geofence.ID,
-					GeofenceName: geofence.Name,
-					EventType:    model.GeofenceEventExit,
-					Location:     loc,
-				})
+	point := geo.Point{Lat: loc.Latitude, Lng: loc.Longitude}
+
+	// Geofences the vehicle is recorded inside are always evaluated, since it
+	// may have jumped well clear of them
+	search := geo.BBox{MinLat: point.Lat, MinLng: point.Lng, MaxLat: point.Lat, MaxLng: point.Lng}
+	if state.LastLocation != nil {
+		prev := *state.LastLocation
+		search = search.Union(geo.BBox{MinLat: prev.Lat, MinLng: prev.Lng, MaxLat: prev.Lat, MaxLng: prev.Lng})
+	}
+	var inside []int64
+	for id := range state.Geofences {
+		if state.Inside(id) {
+			inside = append(inside, id)
+		}
+	}
+
+	now := observedAt(loc)
+	for _, geofence := range geofences.candidates(search, inside) {
+		wasInside := state.Inside(geofence.ID)
+		applies := geofences.appliesTo(geofence.ID, loc.VehicleID)
+		if !applies && !wasInside {
+			continue
+		}
+		var eventTypes []string
+		if applies && geofence.Schedule.ActiveAt(now) {
+			eventTypes = geofence.transitions(wasInside, state.LastLocation, point)
+			if wasInside && len(eventTypes) == 0 {
+				eventTypes = visitEvents(geofence.Geofence, state.Geofences[geofence.ID], now)
 			}
+		} else if wasInside {
+			// Outside its schedule, or once the vehicle is unassigned, the
+			// geofence is not enforced; close the open visit so it doesn't
+			// linger and suppress the next entry
+			eventTypes = []string{model.GeofenceEventExit}
+		}
+		for _, eventType := range eventTypes {
+			events = append(events, GeofenceEvent{
+				VehicleID:    loc.VehicleID,
+				GeofenceID:   geofence.ID,
+				GeofenceName: geofence.Name,
+				EventType:    eventType,
+				Location:     loc,
+			})
 		}
 	}
 	return events
 }
 
-// Get last geofence event type for vehicle and geofence
-func getLastGeofenceEventType(vehicleID string, geofenceID int64, db *gorm.DB) string {
-	if db == nil {
-		return ""
+// visitEvents returns the once-per-visit events due for a vehicle that is
+// still inside the geofence at now
+func visitEvents(geofence model.Geofence, visit GeofenceVisit, now time.Time) []string {
+	if visit.Since.IsZero() {
+		return nil
+	}
+	var eventTypes []string
+	if threshold := geofence.DwellThreshold(); threshold > 0 && !visit.DwellReported && now.Sub(visit.Since) >= threshold {
+		eventTypes = append(eventTypes, model.GeofenceEventDwell)
 	}
-	var lastEvent model.GeofenceEvent
-	err := db.Where("vehicle_id = ? AND geofence_id = ? AND event_type IN (?, ?)",
-		vehicleID, geofenceID, model.GeofenceEventEntry, model.GeofenceEventExit).
-		Order("timestamp DESC").
-		First(&lastEvent).Error
+	if deadline, ok := geofence.DepartureDeadline(visit.Since); ok && !visit.OverdueReported && !now.Before(deadline) {
+		eventTypes = append(eventTypes, model.GeofenceEventOverdue)
+	}
+	return eventTypes
+}
 
-	if err != nil {
-		return ""
+// observedAt returns when the location was reported, falling back to now for
+// updates without a timestamp
+func observedAt(loc model.VehicleLocation) time.Time {
+	if loc.Timestamp.IsZero() {
+		return time.Now()
 	}
-	return lastEvent.EventType
+	return loc.Timestamp
 }
 
-func CallCheckGeofences(loc model.VehicleLocation, db *gorm.DB, rdb *redis.Client, rabbitMQ *RabbitMQService) {
-	var geofences []model.Geofence
-	db.Where("active = ?", true).Find(&geofences)
+// GeofenceService evaluates location updates against the cached geofence set
+// and persists and publishes the resulting events
+type GeofenceService struct {
+	geofences *GeofenceCache
+	states    *GeofenceStateCache
+	eventRepo repository.GeofenceEventRepository
+	queue     queue.Queue
+	rabbitMQ  *RabbitMQService
+}
+
+func NewGeofenceService(geofences *GeofenceCache, states *GeofenceStateCache, eventRepo repository.GeofenceEventRepository, q queue.Queue, rabbitMQ *RabbitMQService) *GeofenceService {
+	return &GeofenceService{
+		geofences: geofences,
+		states:    states,
+		eventRepo: eventRepo,
+		queue:     q,
+		rabbitMQ:  rabbitMQ,
+	}
+}
+
+// CallCheckGeofences detects, saves and publishes the geofence events of loc.
+// Alerts are published before it returns, so waiting for it drains them.
+// A location older than the last one evaluated for the vehicle, such as a
+// retried update, is skipped so that its events can't land out of order.
+func (s *GeofenceService) CallCheckGeofences(ctx context.Context, loc model.VehicleLocation) {
+	ctx, span := tracing.Tracer().Start(ctx, "CallCheckGeofences",
+		trace.WithAttributes(attribute.String("vehicle.id", loc.VehicleID)))
+	events, stale, err := s.detectEvents(ctx, loc)
+	if err != nil {
+		geofenceLog.ErrorContext(ctx, "Failed to load geofences", logging.VehicleID(loc.VehicleID), logging.Err(err))
+	}
+	span.SetAttributes(attribute.Int("geofence.events", len(events)), attribute.Bool("geofence.stale", stale))
+	defer tracing.End(span, err)
+	if stale {
+		geofenceLog.WarnContext(ctx, "Skipped geofence check for out-of-order location", logging.VehicleID(loc.VehicleID),
+			slog.Time("timestamp", loc.Timestamp))
+		return
+	}
+	s.states.SetLastLocation(ctx, loc.VehicleID, geo.Point{Lat: loc.Latitude, Lng: loc.Longitude}, observedAt(loc))
 
-	events := CheckGeofences(loc, geofences, db)
 	for _, event := range events {
-		saveGeofenceEvent(event, db, rdb)
+		if !s.saveGeofenceEvent(ctx, event) {
+			continue
+		}
 
 		// Publish RabbitMQ alert
-		if event.EventType == model.GeofenceEventEntry && rabbitMQ != nil {
-			go func(e GeofenceEvent) {
-				if err := rabbitMQ.PublishGeofenceAlert(rdb, e.VehicleID, e.Location.Latitude, e.Location.Longitude, e.EventType); err != nil {
-					log.Printf("[GEOFENCE_SERVICE] Failed to publish RabbitMQ alert: %v", err)
-				}
-			}(event)
+		if s.rabbitMQ != nil {
+			if err := s.rabbitMQ.PublishGeofenceAlert(ctx, s.queue, event); err != nil {
+				geofenceLog.ErrorContext(ctx, "Failed to publish RabbitMQ alert", logging.VehicleID(event.VehicleID),
+					logging.EventType(event.EventType), slog.Int64("geofence_id", event.GeofenceID), logging.Err(err))
+			}
 		}
 	}
 }
 
-func saveGeofenceEvent(event GeofenceEvent, db *gorm.DB, rdb *redis.Client) {
+// detectEvents evaluates loc against the cached geofences and states without
+// touching the database, reporting whether loc was stale and skipped
+func (s *GeofenceService) detectEvents(ctx context.Context, loc model.VehicleLocation) ([]GeofenceEvent, bool, error) {
+	geofences, err := s.geofences.Geofences(ctx)
+	state := s.states.Vehicle(ctx, loc.VehicleID)
+	if state.Stale(observedAt(loc)) {
+		return nil, true, err
+	}
+	return evaluateGeofences(loc, geofences, state), false, err
+}
+
+// saveGeofenceEvent persists the event and records the new state, reporting
+// whether it was saved
+func (s *GeofenceService) saveGeofenceEvent(ctx context.Context, event GeofenceEvent) bool {
 	geofenceEvent := model.GeofenceEvent{
 		VehicleID:  event.VehicleID,
 		GeofenceID: event.GeofenceID,
 		EventType:  event.EventType,
-		Timestamp:  time.Now(),
+		Timestamp:  observedAt(event.Location),
 		Latitude:   event.Location.Latitude,
 		Longitude:  event.Location.Longitude,
 	}
 
 	payload, _ := json.Marshal(event)
-	if err := db.Create(&geofenceEvent).Error; err != nil {
-		log.Printf("[GEOFENCE_SERVICE] Failed to save geofence event: %v", err)
-		pushDeadLetter(rdb, string(payload), err)
-		return
+	if err := s.eventRepo.InsertGeofenceEvent(ctx, &geofenceEvent); err != nil {
+		geofenceLog.ErrorContext(ctx, "Failed to save geofence event", logging.VehicleID(event.VehicleID),
+			logging.EventType(event.EventType), slog.Int64("geofence_id", event.GeofenceID), logging.Err(err))
+		pushDeadLetter(ctx, s.queue, "", string(payload), err)
+		return false
 	}
+	s.states.Set(ctx, event.VehicleID, event.GeofenceID, event.EventType, geofenceEvent.Timestamp)
+	metrics.GeofenceEvents.WithLabelValues(event.EventType).Inc()
 
 	envelope := model.EventEnvelope{
 		EventType: event.EventType,
@@ -115,5 +213,6 @@ func saveGeofenceEvent(event GeofenceEvent, db *gorm.DB, rdb *redis.Client) {
 		Payload:   json.RawMessage(payload),
 		Timestamp: time.Now(),
 	}
-	sendEventToRedis(rdb, "event_log:queue", envelope)
+	sendEvent(ctx, s.queue, EventLogTopic, envelope)
+	return true
 }
@@ -1,20 +1,25 @@
 package service
 
 import (
+	"context"
+	"fmt"
+	"math/rand"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/geo"
 	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
 )
 
 func TestCheckGeofences_InsideGeofence(t *testing.T) {
-	// Test vehicle near geofence boundary (100m north of center)
+	// Test vehicle just inside the geofence boundary (about 90m north of center)
 	centerLat := -6.193125
 	centerLng := 106.820233
-	// 0.0009 degrees latitude ≈ 100m
-	vehicleLat := centerLat + 0.0009
+	// 0.0008 degrees latitude ≈ 89m
+	vehicleLat := centerLat + 0.0008
 
 	location := model.VehicleLocation{
 		VehicleID: "TEST001",
@@ -34,7 +39,7 @@ func TestCheckGeofences_InsideGeofence(t *testing.T) {
 
 	geofences := []model.Geofence{geofence}
 
-	events := CheckGeofences(location, geofences, nil)
+	events := CheckGeofences(context.Background(), location, NewGeofenceSet(geofences, nil), nil)
 
 	assert.Len(t, events, 1)
 }
@@ -58,7 +63,7 @@ func TestCheckGeofences_OutsideGeofence(t *testing.T) {
 
 	geofences := []model.Geofence{geofence}
 
-	events := CheckGeofences(location, geofences, nil)
+	events := CheckGeofences(context.Background(), location, NewGeofenceSet(geofences, nil), nil)
 
 	assert.Len(t, events, 0)
 }
@@ -82,7 +87,9 @@ func TestCheckGeofences_AlreadyInside(t *testing.T) {
 
 	geofences := []model.Geofence{geofence}
 
-	events := CheckGeofences(location, geofences, nil)
+	states := NewGeofenceStateCache(nil)
+	states.Set(context.Background(), location.VehicleID, geofence.ID, model.GeofenceEventEntry, time.Now())
+	events := CheckGeofences(context.Background(), location, NewGeofenceSet(geofences, nil), states)
 
 	assert.Len(t, events, 0)
 }
@@ -97,7 +104,422 @@ func TestCheckGeofences_NoActiveGeofences(t *testing.T) {
 
 	geofences := []model.Geofence{}
 
-	events := CheckGeofences(location, geofences, nil)
+	events := CheckGeofences(context.Background(), location, NewGeofenceSet(geofences, nil), nil)
 
 	assert.Len(t, events, 0)
 }
+
+func TestCheckGeofences_PolygonBoundary(t *testing.T) {
+	// Square depot around Bundaran HI, roughly 220m per side
+	geofence := model.Geofence{
+		ID:          2,
+		Name:        "Depot A",
+		Shape:       model.GeofenceShapePolygon,
+		Coordinates: []byte(`[[[106.819233,-6.194125],[106.821233,-6.194125],[106.821233,-6.192125],[106.819233,-6.192125],[106.819233,-6.194125]]]`),
+		Active:      true,
+	}
+
+	// 0.00002 degrees latitude ≈ 2m inside the north edge
+	nearEdge := model.VehicleLocation{
+		VehicleID: "TEST005",
+		Latitude:  -6.192145,
+		Longitude: 106.820233,
+		Timestamp: time.Now(),
+	}
+	events := CheckGeofences(context.Background(), nearEdge, NewGeofenceSet([]model.Geofence{geofence}, nil), nil)
+	assert.Len(t, events, 1)
+	assert.Equal(t, model.GeofenceEventEntry, events[0].EventType)
+
+	states := NewGeofenceStateCache(nil)
+	states.Set(context.Background(), nearEdge.VehicleID, geofence.ID, model.GeofenceEventEntry, time.Now())
+	center := nearEdge
+	center.Latitude = -6.193125
+	events = CheckGeofences(context.Background(), center, NewGeofenceSet([]model.Geofence{geofence}, nil), states)
+	assert.Len(t, events, 0)
+}
+
+func TestCheckGeofences_MultiPolygonHole(t *testing.T) {
+	// Outer square with a hole; the vehicle was inside and is now 10m into the hole
+	geofence := model.Geofence{
+		ID:    3,
+		Name:  "District",
+		Shape: model.GeofenceShapeMultiPolygon,
+		Coordinates: []byte(`[[
+			[[106.810,-6.200],[106.830,-6.200],[106.830,-6.180],[106.810,-6.180]],
+			[[106.815,-6.195],[106.825,-6.195],[106.825,-6.185],[106.815,-6.185]]
+		]]`),
+		Active: true,
+	}
+
+	location := model.VehicleLocation{
+		VehicleID: "TEST006",
+		Latitude:  -6.18509,
+		Longitude: 106.820,
+		Timestamp: time.Now(),
+	}
+	states := NewGeofenceStateCache(nil)
+	states.Set(context.Background(), location.VehicleID, geofence.ID, model.GeofenceEventEntry, time.Now())
+	events := CheckGeofences(context.Background(), location, NewGeofenceSet([]model.Geofence{geofence}, nil), states)
+	assert.Len(t, events, 1)
+	assert.Equal(t, model.GeofenceEventExit, events[0].EventType)
+}
+
+func TestCheckGeofences_InvalidPolygonSkipped(t *testing.T) {
+	geofence := model.Geofence{
+		ID:          4,
+		Name:        "Broken",
+		Shape:       model.GeofenceShapePolygon,
+		Coordinates: []byte(`[[[106.82,-6.19]]]`),
+		Active:      true,
+	}
+
+	location := model.VehicleLocation{
+		VehicleID: "TEST007",
+		Latitude:  -6.19,
+		Longitude: 106.82,
+		Timestamp: time.Now(),
+	}
+	events := CheckGeofences(context.Background(), location, NewGeofenceSet([]model.Geofence{geofence}, nil), nil)
+	assert.Len(t, events, 0)
+}
+
+// bundaranHI is a 100m circle used by the transition tests; 0.0009 degrees
+// latitude is about 100m
+var bundaranHI = model.Geofence{
+	ID:        1,
+	Name:      "Bundaran HI",
+	CenterLat: -6.193125,
+	CenterLng: 106.820233,
+	Radius:    100,
+	Active:    true,
+}
+
+// driveThrough feeds the latitudes to CheckGeofences in order, recording the
+// resulting state like the worker does, and returns the event types
+func driveThrough(geofence model.Geofence, latitudes ...float64) []string {
+	set := NewGeofenceSet([]model.Geofence{geofence}, nil)
+	states := NewGeofenceStateCache(nil)
+	var eventTypes []string
+	for _, lat := range latitudes {
+		loc := model.VehicleLocation{VehicleID: "TEST009", Latitude: lat, Longitude: geofence.CenterLng, Timestamp: time.Now()}
+		for _, e := range CheckGeofences(context.Background(), loc, set, states) {
+			states.Set(context.Background(), e.VehicleID, e.GeofenceID, e.EventType, observedAt(e.Location))
+			eventTypes = append(eventTypes, e.EventType)
+		}
+		states.SetLastLocation(context.Background(), loc.VehicleID, geo.Point{Lat: loc.Latitude, Lng: loc.Longitude}, loc.Timestamp)
+	}
+	return eventTypes
+}
+
+func TestCheckGeofences_FastCrossings(t *testing.T) {
+	c := bundaranHI.CenterLat
+	// Samples about 60m apart at highway speed; none land within 5m of the edge
+	tests := []struct {
+		name      string
+		latitudes []float64
+		want      []string
+	}{
+		{"entry", []float64{c + 0.0012, c + 0.0006}, []string{model.GeofenceEventEntry}},
+		{"exit", []float64{c + 0.0006, c + 0.0012}, []string{model.GeofenceEventEntry, model.GeofenceEventExit}},
+		{"pass through", []float64{c + 0.0012, c - 0.0012}, []string{model.GeofenceEventEntry, model.GeofenceEventExit}},
+		{"miss", []float64{c + 0.0012, c + 0.0020}, nil},
+		{"far jump out", []float64{c, c + 0.05}, []string{model.GeofenceEventEntry, model.GeofenceEventExit}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, driveThrough(bundaranHI, tt.latitudes...))
+		})
+	}
+}
+
+func TestCheckGeofences_PassThroughWithoutLastLocation(t *testing.T) {
+	// Without a previous position a sample beyond the fence is just outside
+	loc := model.VehicleLocation{VehicleID: "TEST010", Latitude: bundaranHI.CenterLat - 0.0012, Longitude: bundaranHI.CenterLng}
+	assert.Empty(t, CheckGeofences(context.Background(), loc, NewGeofenceSet([]model.Geofence{bundaranHI}, nil), NewGeofenceStateCache(nil)))
+}
+
+func TestCheckGeofences_JitterAtEdge(t *testing.T) {
+	c := bundaranHI.CenterLat
+	// Fixes wandering up to about 2m either side of the boundary
+	jitter := []float64{c + 0.00088, c + 0.00091, c + 0.00089, c + 0.00092, c + 0.00088, c + 0.00091}
+	assert.Equal(t, []string{model.GeofenceEventEntry}, driveThrough(bundaranHI, jitter...))
+
+	// Clearing the default 5m hysteresis band (8m out) exits
+	assert.Equal(t,
+		[]string{model.GeofenceEventEntry, model.GeofenceEventExit},
+		driveThrough(bundaranHI, append(jitter, c+0.000972)...))
+}
+
+func TestCheckGeofences_CustomHysteresis(t *testing.T) {
+	c := bundaranHI.CenterLat
+	geofence := bundaranHI
+	geofence.Hysteresis = 20
+
+	// 8m out stays inside a 20m band, 30m out leaves
+	assert.Equal(t, []string{model.GeofenceEventEntry}, driveThrough(geofence, c, c+0.000972))
+	assert.Equal(t,
+		[]string{model.GeofenceEventEntry, model.GeofenceEventExit},
+		driveThrough(geofence, c, c+0.000972, c+0.00117))
+}
+
+// visitAt evaluates a location at the center of geofence at the given time
+// and records the resulting events
+func visitAt(geofence model.Geofence, states *GeofenceStateCache, at time.Time) []string {
+	loc := model.VehicleLocation{VehicleID: "TEST011", Latitude: geofence.CenterLat, Longitude: geofence.CenterLng, Timestamp: at}
+	var eventTypes []string
+	for _, e := range CheckGeofences(context.Background(), loc, NewGeofenceSet([]model.Geofence{geofence}, nil), states) {
+		states.Set(context.Background(), e.VehicleID, e.GeofenceID, e.EventType, observedAt(e.Location))
+		eventTypes = append(eventTypes, e.EventType)
+	}
+	return eventTypes
+}
+
+func TestCheckGeofences_Dwell(t *testing.T) {
+	geofence := bundaranHI
+	geofence.DwellMinutes = 45
+	states := NewGeofenceStateCache(nil)
+	entered := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, []string{model.GeofenceEventEntry}, visitAt(geofence, states, entered))
+	assert.Empty(t, visitAt(geofence, states, entered.Add(30*time.Minute)))
+	assert.Equal(t, []string{model.GeofenceEventDwell}, visitAt(geofence, states, entered.Add(46*time.Minute)))
+	// Raised once per visit
+	assert.Empty(t, visitAt(geofence, states, entered.Add(2*time.Hour)))
+
+	// A new visit starts the clock again
+	states.Set(context.Background(), "TEST011", geofence.ID, model.GeofenceEventExit, entered.Add(3*time.Hour))
+	reentered := entered.Add(4 * time.Hour)
+	assert.Equal(t, []string{model.GeofenceEventEntry}, visitAt(geofence, states, reentered))
+	assert.Empty(t, visitAt(geofence, states, reentered.Add(44*time.Minute)))
+	assert.Equal(t, []string{model.GeofenceEventDwell}, visitAt(geofence, states, reentered.Add(45*time.Minute)))
+}
+
+func TestCheckGeofences_DwellDisabled(t *testing.T) {
+	states := NewGeofenceStateCache(nil)
+	entered := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	visitAt(bundaranHI, states, entered)
+	assert.Empty(t, visitAt(bundaranHI, states, entered.Add(24*time.Hour)))
+}
+
+func TestCheckGeofences_Overdue(t *testing.T) {
+	geofence := bundaranHI
+	geofence.DepartBy = "18:00"
+
+	states := NewGeofenceStateCache(nil)
+	entered := time.Date(2024, 5, 1, 7, 0, 0, 0, time.UTC)
+	visitAt(geofence, states, entered)
+	assert.Empty(t, visitAt(geofence, states, time.Date(2024, 5, 1, 17, 59, 0, 0, time.UTC)))
+	assert.Equal(t, []string{model.GeofenceEventOverdue}, visitAt(geofence, states, time.Date(2024, 5, 1, 18, 1, 0, 0, time.UTC)))
+	assert.Empty(t, visitAt(geofence, states, time.Date(2024, 5, 2, 18, 1, 0, 0, time.UTC)))
+
+	// Arriving after the cutoff is due the next day
+	states = NewGeofenceStateCache(nil)
+	visitAt(geofence, states, time.Date(2024, 5, 1, 19, 0, 0, 0, time.UTC))
+	assert.Empty(t, visitAt(geofence, states, time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)))
+	assert.Equal(t, []string{model.GeofenceEventOverdue}, visitAt(geofence, states, time.Date(2024, 5, 2, 18, 0, 0, 0, time.UTC)))
+}
+
+func TestCheckGeofences_OverdueInScheduleTimezone(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	require.NoError(t, err)
+	geofence := bundaranHI
+	geofence.DepartBy = "18:00"
+	geofence.Schedule = &model.GeofenceSchedule{
+		Timezone: "Asia/Jakarta",
+		Windows:  []model.ScheduleWindow{{Start: "00:00", End: model.ScheduleEndOfDay}},
+	}
+
+	// 18:00 in Jakarta is 11:00 UTC
+	states := NewGeofenceStateCache(nil)
+	visitAt(geofence, states, time.Date(2024, 5, 1, 7, 0, 0, 0, jakarta))
+	assert.Empty(t, visitAt(geofence, states, time.Date(2024, 5, 1, 10, 59, 0, 0, time.UTC)))
+	assert.Equal(t, []string{model.GeofenceEventOverdue}, visitAt(geofence, states, time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC)))
+}
+
+func TestCheckGeofences_DwellAndOverdueTogether(t *testing.T) {
+	geofence := bundaranHI
+	geofence.DwellMinutes = 45
+	geofence.DepartBy = "08:00"
+	states := NewGeofenceStateCache(nil)
+
+	visitAt(geofence, states, time.Date(2024, 5, 1, 7, 30, 0, 0, time.UTC))
+	assert.Equal(t,
+		[]string{model.GeofenceEventDwell, model.GeofenceEventOverdue},
+		visitAt(geofence, states, time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)))
+}
+
+func TestCheckGeofences_Schedule(t *testing.T) {
+	// School zone enforced 06:30-08:30 Jakarta time on weekdays
+	geofence := bundaranHI
+	geofence.Schedule = &model.GeofenceSchedule{
+		Timezone: "Asia/Jakarta",
+		Windows:  []model.ScheduleWindow{{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "06:30", End: "08:30"}},
+	}
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	require.NoError(t, err)
+	states := NewGeofenceStateCache(nil)
+
+	// Monday 2024-05-06: parked inside before the window opens
+	assert.Empty(t, visitAt(geofence, states, time.Date(2024, 5, 6, 6, 0, 0, 0, jakarta)))
+	assert.Equal(t, []string{model.GeofenceEventEntry}, visitAt(geofence, states, time.Date(2024, 5, 6, 6, 30, 0, 0, jakarta)))
+	assert.Empty(t, visitAt(geofence, states, time.Date(2024, 5, 6, 8, 0, 0, 0, jakarta)))
+	// The window closing ends the visit
+	assert.Equal(t, []string{model.GeofenceEventExit}, visitAt(geofence, states, time.Date(2024, 5, 6, 8, 30, 0, 0, jakarta)))
+	assert.Empty(t, visitAt(geofence, states, time.Date(2024, 5, 6, 12, 0, 0, 0, jakarta)))
+	// Weekends are never enforced
+	assert.Empty(t, visitAt(geofence, states, time.Date(2024, 5, 11, 7, 0, 0, 0, jakarta)))
+}
+
+func TestCheckGeofences_Assignments(t *testing.T) {
+	depotA := bundaranHI
+	depotA.ID, depotA.Name = 10, "Depot A"
+	depotB := bundaranHI
+	depotB.ID, depotB.Name = 11, "Depot B"
+	shared := bundaranHI
+	shared.ID, shared.Name = 12, "Shared"
+	nobody := bundaranHI
+	nobody.ID, nobody.Name = 13, "Empty group"
+
+	set := NewGeofenceSet([]model.Geofence{depotA, depotB, shared, nobody}, map[int64][]string{
+		10: {"BUS-A1", "BUS-A2"},
+		11: {"BUS-B1"},
+		13: {},
+	})
+
+	entered := func(vehicleID string) []int64 {
+		loc := model.VehicleLocation{VehicleID: vehicleID, Latitude: bundaranHI.CenterLat, Longitude: bundaranHI.CenterLng}
+		var ids []int64
+		for _, e := range CheckGeofences(context.Background(), loc, set, nil) {
+			ids = append(ids, e.GeofenceID)
+		}
+		return ids
+	}
+	assert.Equal(t, []int64{10, 12}, entered("BUS-A1"))
+	assert.Equal(t, []int64{11, 12}, entered("BUS-B1"))
+	assert.Equal(t, []int64{12}, entered("BUS-C1"))
+}
+
+func TestCheckGeofences_UnassignedVehicleExits(t *testing.T) {
+	states := NewGeofenceStateCache(nil)
+	check := func(assignments map[int64][]string) []string {
+		loc := model.VehicleLocation{VehicleID: "BUS-A1", Latitude: bundaranHI.CenterLat, Longitude: bundaranHI.CenterLng, Timestamp: time.Now()}
+		var eventTypes []string
+		for _, e := range CheckGeofences(context.Background(), loc, NewGeofenceSet([]model.Geofence{bundaranHI}, assignments), states) {
+			states.Set(context.Background(), e.VehicleID, e.GeofenceID, e.EventType, observedAt(e.Location))
+			eventTypes = append(eventTypes, e.EventType)
+		}
+		return eventTypes
+	}
+
+	assert.Equal(t, []string{model.GeofenceEventEntry}, check(map[int64][]string{1: {"BUS-A1"}}))
+	assert.Equal(t, []string{model.GeofenceEventExit}, check(map[int64][]string{1: {"BUS-B1"}}), "unassigning closes the visit")
+	assert.Empty(t, check(map[int64][]string{1: {"BUS-B1"}}))
+	assert.Equal(t, []string{model.GeofenceEventEntry}, check(map[int64][]string{1: {"BUS-A1"}}), "reassigning enters again")
+}
+
+func TestCheckGeofences_IgnoresOutOfOrderLocation(t *testing.T) {
+	set := NewGeofenceSet([]model.Geofence{bundaranHI}, nil)
+	states := NewGeofenceStateCache(nil)
+	evaluated := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	states.SetLastLocation(context.Background(), "TEST011", geo.Point{Lat: bundaranHI.CenterLat + 0.01, Lng: bundaranHI.CenterLng}, evaluated)
+
+	loc := model.VehicleLocation{VehicleID: "TEST011", Latitude: bundaranHI.CenterLat, Longitude: bundaranHI.CenterLng, Timestamp: evaluated.Add(-time.Minute)}
+	assert.Empty(t, CheckGeofences(context.Background(), loc, set, states), "an older location is not evaluated")
+
+	loc.Timestamp = evaluated.Add(time.Minute)
+	require.Len(t, CheckGeofences(context.Background(), loc, set, states), 1)
+}
+
+// randomGeofenceSet scatters circular and square geofences over greater Jakarta
+func randomGeofenceSet(n int, rng *rand.Rand) []model.Geofence {
+	geofences := make([]model.Geofence, n)
+	for i := range geofences {
+		lat := -6.4 + rng.Float64()*0.4
+		lng := 106.6 + rng.Float64()*0.4
+		geofences[i] = model.Geofence{ID: int64(i + 1), Name: fmt.Sprintf("Zone %d", i), Active: true}
+		if i%2 == 0 {
+			geofences[i].CenterLat, geofences[i].CenterLng, geofences[i].Radius = lat, lng, 50+rng.Float64()*450
+			continue
+		}
+		d := 0.001 + rng.Float64()*0.004
+		geofences[i].Shape = model.GeofenceShapePolygon
+		geofences[i].Coordinates = []byte(fmt.Sprintf("[[[%[1]f,%[2]f],[%[3]f,%[2]f],[%[3]f,%[4]f],[%[1]f,%[4]f]]]",
+			lng, lat, lng+d, lat+d))
+	}
+	return geofences
+}
+
+// checkAllGeofences evaluates every geofence without the spatial index
+func checkAllGeofences(loc model.VehicleLocation, set *GeofenceSet, states *GeofenceStateCache) []int64 {
+	var hits []int64
+	point := geo.Point{Lat: loc.Latitude, Lng: loc.Longitude}
+	state := states.Vehicle(context.Background(), loc.VehicleID)
+	for i := range set.geofences {
+		g := &set.geofences[i]
+		for range g.transitions(state.Inside(g.ID), state.LastLocation, point) {
+			hits = append(hits, g.ID)
+		}
+	}
+	return hits
+}
+
+func TestCheckGeofences_IndexMatchesLinearScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	set := NewGeofenceSet(randomGeofenceSet(2000, rng), nil)
+	require.Equal(t, 2000, set.Len())
+
+	// Drive vehicles around on random 1-500m hops and carry their state
+	// forward, so entries, exits and pass-throughs are all exercised
+	states := NewGeofenceStateCache(nil)
+	total := 0
+	for v := 0; v < 20; v++ {
+		loc := model.VehicleLocation{
+			VehicleID: fmt.Sprintf("TEST%03d", v),
+			Latitude:  -6.4 + rng.Float64()*0.4,
+			Longitude: 106.6 + rng.Float64()*0.4,
+		}
+		for i := 0; i < 200; i++ {
+			loc.Latitude += (rng.Float64()*2 - 1) * 0.0045
+			loc.Longitude += (rng.Float64()*2 - 1) * 0.0045
+
+			var got []int64
+			events := CheckGeofences(context.Background(), loc, set, states)
+			for _, e := range events {
+				got = append(got, e.GeofenceID)
+			}
+			require.Equal(t, checkAllGeofences(loc, set, states), got)
+			total += len(events)
+
+			for _, e := range events {
+				states.Set(context.Background(), e.VehicleID, e.GeofenceID, e.EventType, observedAt(e.Location))
+			}
+			states.SetLastLocation(context.Background(), loc.VehicleID, geo.Point{Lat: loc.Latitude, Lng: loc.Longitude}, loc.Timestamp)
+		}
+	}
+	assert.NotZero(t, total)
+}
+
+func BenchmarkCheckGeofences(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	set := NewGeofenceSet(randomGeofenceSet(10000, rng), nil)
+	locations := make([]model.VehicleLocation, 1024)
+	for i := range locations {
+		locations[i] = model.VehicleLocation{
+			VehicleID: fmt.Sprintf("BUS-%04d", i),
+			Latitude:  -6.4 + rng.Float64()*0.4,
+			Longitude: 106.6 + rng.Float64()*0.4,
+		}
+	}
+
+	b.Run("Linear", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			checkAllGeofences(locations[i%len(locations)], set, nil)
+		}
+	})
+
+	b.Run("Indexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			CheckGeofences(context.Background(), locations[i%len(locations)], set, nil)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"log/slog"
+	"math"
+	"sort"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/geo"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+)
+
+// GeofenceSet is an immutable collection of geofences with decoded shapes and
+// an R-tree over their bounding boxes, so each location update only runs
+// exact tests against nearby geofences
+type GeofenceSet struct {
+	geofences []compiledGeofence
+	byID      map[int64]int
+	index     *geo.RTree
+	// assigned holds the vehicles of geofences restricted by assignments;
+	// geofences missing from it apply to every vehicle
+	assigned map[int64]map[string]bool
+}
+
+type compiledGeofence struct {
+	model.Geofence
+	polygons geo.MultiPolygon // nil for circles
+}
+
+// NewGeofenceSet decodes and indexes the geofences. Geofences with invalid
+// coordinates are logged and left out. assignments maps geofence IDs to the
+// only vehicles they apply to, as returned by GeofenceVehicles; it may be nil.
+func NewGeofenceSet(geofences []model.Geofence, assignments map[int64][]string) *GeofenceSet {
+	compiled := make([]compiledGeofence, 0, len(geofences))
+	boxes := make([]geo.BBox, 0, len(geofences))
+	byID := make(map[int64]int, len(geofences))
+	for _, g := range geofences {
+		cg := compiledGeofence{Geofence: g}
+		var box geo.BBox
+		if g.IsCircle() {
+			box = geo.CircleBBox(geo.Point{Lat: g.CenterLat, Lng: g.CenterLng}, g.Radius)
+		} else {
+			polygons, err := g.Polygons()
+			if err != nil {
+				geofenceLog.Warn("Skipping geofence", slog.Int64("geofence_id", g.ID), logging.Err(err))
+				continue
+			}
+			cg.polygons = polygons
+			box = polygons.BBox()
+		}
+		byID[g.ID] = len(compiled)
+		compiled = append(compiled, cg)
+		boxes = append(boxes, box)
+	}
+
+	assigned := make(map[int64]map[string]bool, len(assignments))
+	for geofenceID, vehicleIDs := range assignments {
+		assigned[geofenceID] = make(map[string]bool, len(vehicleIDs))
+		for _, vehicleID := range vehicleIDs {
+			assigned[geofenceID][vehicleID] = true
+		}
+	}
+
+	return &GeofenceSet{
+		geofences: compiled,
+		byID:      byID,
+		index:     geo.NewRTree(boxes),
+		assigned:  assigned,
+	}
+}
+
+// Len returns the number of valid geofences in the set
+func (s *GeofenceSet) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.geofences)
+}
+
+// candidates returns the geofences whose bounding box intersects box plus the
+// geofences listed in include, in the order they were added to the set
+func (s *GeofenceSet) candidates(box geo.BBox, include []int64) []*compiledGeofence {
+	if s == nil {
+		return nil
+	}
+	matches := s.index.Search(box)
+	for _, id := range include {
+		if idx, ok := s.byID[id]; ok {
+			matches = append(matches, idx)
+		}
+	}
+	sort.Ints(matches)
+
+	result := make([]*compiledGeofence, 0, len(matches))
+	for i, idx := range matches {
+		if i > 0 && matches[i-1] == idx {
+			continue
+		}
+		result = append(result, &s.geofences[idx])
+	}
+	return result
+}
+
+// appliesTo reports whether the geofence is evaluated for the vehicle
+func (s *GeofenceSet) appliesTo(geofenceID int64, vehicleID string) bool {
+	vehicles, restricted := s.assigned[geofenceID]
+	return !restricted || vehicles[vehicleID]
+}
+
+// boundary reports whether p is inside the geofence and its distance in
+// meters to the nearest boundary
+func (g *compiledGeofence) boundary(p geo.Point) (bool, float64) {
+	if g.polygons == nil {
+		distance := geo.Haversine(p.Lat, p.Lng, g.CenterLat, g.CenterLng)
+		return distance <= g.Radius, math.Abs(distance - g.Radius)
+	}
+	return g.polygons.Contains(p), g.polygons.DistanceToEdge(p)
+}
+
+// crossedBy reports whether the segment a-b passes through the geofence
+func (g *compiledGeofence) crossedBy(a, b geo.Point) bool {
+	if g.polygons == nil {
+		return geo.DistanceToSegment(geo.Point{Lat: g.CenterLat, Lng: g.CenterLng}, a, b) < g.Radius
+	}
+	return g.polygons.IntersectsSegment(a, b)
+}
+
+// transitions returns the event types implied by a vehicle moving from prev
+// (nil if unknown) to p, given whether it was last recorded inside.
+// Entering takes effect at the boundary while leaving requires clearing the
+// hysteresis band, so GPS jitter at the edge does not flap the state.
+func (g *compiledGeofence) transitions(wasInside bool, prev *geo.Point, p geo.Point) []string {
+	inside, edgeDistance := g.boundary(p)
+	switch {
+	case !wasInside && inside:
+		return []string{model.GeofenceEventEntry}
+	case wasInside && !inside && edgeDistance > g.HysteresisMeters():
+		return []string{model.GeofenceEventExit}
+	case !wasInside && !inside && prev != nil && g.crossedBy(*prev, p):
+		// Passed straight through between two samples
+		return []string{model.GeofenceEventEntry, model.GeofenceEventExit}
+	}
+	return nil
+}
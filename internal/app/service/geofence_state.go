@@ -0,0 +1,360 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/geo"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/repository"
+)
+
+var stateLog = logging.Component("geofence_state")
+
+// geofenceStateKeyPrefix prefixes the per-vehicle Redis hash mirroring the
+// state cache; fields are geofence IDs and values the JSON encoded visit, plus
+// the last evaluated position and its time under lastLocationField and
+// lastEvaluatedField
+const (
+	geofenceStateKeyPrefix = "geofence:state:"
+	lastLocationField      = "last_location"
+	lastEvaluatedField     = "last_evaluated"
+)
+
+// geofenceStateTTL is how long a vehicle's Redis hash outlives its last
+// update, so retired vehicles don't leave keys behind. A vehicle silent for
+// longer starts again with no recorded state.
+const geofenceStateTTL = 30 * 24 * time.Hour
+
+// GeofenceVisit is a vehicle's state for one geofence: its last entry/exit
+// and which once-per-visit events have been raised since
+type GeofenceVisit struct {
+	EventType       string    `json:"event_type"` // last entry/exit event type
+	Since           time.Time `json:"since"`
+	DwellReported   bool      `json:"dwell_reported,omitempty"`
+	OverdueReported bool      `json:"overdue_reported,omitempty"`
+}
+
+// VehicleGeofenceState is the last known geofence state of one vehicle
+type VehicleGeofenceState struct {
+	Geofences     map[int64]GeofenceVisit
+	LastLocation  *geo.Point
+	LastEvaluated time.Time // when LastLocation was observed
+}
+
+// Inside reports whether the vehicle's last event for the geofence was an entry
+func (s VehicleGeofenceState) Inside(geofenceID int64) bool {
+	return s.Geofences[geofenceID].EventType == model.GeofenceEventEntry
+}
+
+// Stale reports whether a location observed at the given time predates the
+// last one evaluated for the vehicle, as a retried update can
+func (s VehicleGeofenceState) Stale(at time.Time) bool {
+	return at.Before(s.LastEvaluated)
+}
+
+// record applies an event raised at the given time to the visit
+func (v GeofenceVisit) record(eventType string, at time.Time) GeofenceVisit {
+	switch eventType {
+	case model.GeofenceEventDwell:
+		v.DwellReported = true
+	case model.GeofenceEventOverdue:
+		v.OverdueReported = true
+	default:
+		v = GeofenceVisit{EventType: eventType, Since: at}
+	}
+	return v
+}
+
+// GeofenceStateCache tracks the last entry/exit event type of every vehicle
+// for every geofence, and its last position, so location updates don't need
+// a database lookup. When a Redis client is set, Redis holds the shared state
+// for multiple workers and the local map is used as a fallback.
+// A nil cache behaves as if no vehicle has any recorded state.
+type GeofenceStateCache struct {
+	rdb *redis.Client
+
+	mu     sync.RWMutex
+	states map[string]*VehicleGeofenceState
+}
+
+func NewGeofenceStateCache(rdb *redis.Client) *GeofenceStateCache {
+	return &GeofenceStateCache{
+		rdb:    rdb,
+		states: make(map[string]*VehicleGeofenceState),
+	}
+}
+
+// Load rebuilds the cache from the latest recorded geofence events
+func (c *GeofenceStateCache) Load(ctx context.Context, repo repository.GeofenceEventRepository) error {
+	events, err := repo.LatestGeofenceStates(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Replay entries and exits before the once-per-visit events, which only
+	// count if they happened during the current visit
+	sort.SliceStable(events, func(i, j int) bool {
+		return isTransition(events[i].EventType) && !isTransition(events[j].EventType)
+	})
+	states := make(map[string]*VehicleGeofenceState)
+	for _, evt := range events {
+		if states[evt.VehicleID] == nil {
+			states[evt.VehicleID] = &VehicleGeofenceState{Geofences: make(map[int64]GeofenceVisit)}
+		}
+		visit := states[evt.VehicleID].Geofences[evt.GeofenceID]
+		if isTransition(evt.EventType) && evt.Timestamp.Before(visit.Since) {
+			continue
+		}
+		if !isTransition(evt.EventType) && (visit.EventType != model.GeofenceEventEntry || evt.Timestamp.Before(visit.Since)) {
+			continue
+		}
+		states[evt.VehicleID].Geofences[evt.GeofenceID] = visit.record(evt.EventType, evt.Timestamp)
+	}
+
+	c.mu.Lock()
+	c.states = states
+	c.mu.Unlock()
+
+	if c.rdb != nil {
+		// Visits newer than anything read were recorded by a live worker since
+		var newest time.Time
+		for _, evt := range events {
+			if evt.Timestamp.After(newest) {
+				newest = evt.Timestamp
+			}
+		}
+		for vehicleID, state := range states {
+			if err := c.mirrorLoaded(ctx, vehicleID, state.Geofences, newest); err != nil {
+				stateLog.ErrorContext(ctx, "Failed to mirror geofence state to Redis", logging.VehicleID(vehicleID), logging.Err(err))
+			}
+		}
+	}
+
+	stateLog.InfoContext(ctx, "Loaded geofence state", slog.Int("vehicles", len(states)))
+	return nil
+}
+
+// Vehicle returns a copy of the vehicle's state. Vehicles without recorded
+// state get an empty state with no last location.
+func (c *GeofenceStateCache) Vehicle(ctx context.Context, vehicleID string) VehicleGeofenceState {
+	if c == nil {
+		return VehicleGeofenceState{}
+	}
+
+	if c.rdb != nil {
+		fields, err := c.rdb.HGetAll(ctx, geofenceStateKeyPrefix+vehicleID).Result()
+		if err == nil {
+			return parseVehicleGeofenceState(fields)
+		}
+		stateLog.ErrorContext(ctx, "Failed to read geofence state from Redis", logging.VehicleID(vehicleID), logging.Err(err))
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	state, ok := c.states[vehicleID]
+	if !ok {
+		return VehicleGeofenceState{}
+	}
+	geofences := make(map[int64]GeofenceVisit, len(state.Geofences))
+	for id, visit := range state.Geofences {
+		geofences[id] = visit
+	}
+	return VehicleGeofenceState{Geofences: geofences, LastLocation: state.LastLocation, LastEvaluated: state.LastEvaluated}
+}
+
+// Set records an event raised at the given time for the vehicle and geofence.
+// Entries and exits start a new visit; dwell and overdue events are noted on
+// the current one.
+func (c *GeofenceStateCache) Set(ctx context.Context, vehicleID string, geofenceID int64, eventType string, at time.Time) {
+	if c == nil {
+		return
+	}
+
+	// Another worker may have started the visit in the shared state
+	shared, fromRedis := c.redisVisit(ctx, vehicleID, geofenceID)
+
+	c.mu.Lock()
+	state := c.vehicleLocked(vehicleID)
+	if fromRedis {
+		state.Geofences[geofenceID] = shared
+	}
+	visit := state.Geofences[geofenceID].record(eventType, at)
+	state.Geofences[geofenceID] = visit
+	c.mu.Unlock()
+
+	c.mirror(ctx, vehicleID, strconv.FormatInt(geofenceID, 10), encodeVisit(visit))
+}
+
+// SetLastLocation records the vehicle's latest evaluated position and when it
+// was observed
+func (c *GeofenceStateCache) SetLastLocation(ctx context.Context, vehicleID string, p geo.Point, at time.Time) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	state := c.vehicleLocked(vehicleID)
+	state.LastLocation = &p
+	state.LastEvaluated = at
+	c.mu.Unlock()
+
+	c.mirror(ctx, vehicleID,
+		lastLocationField, fmt.Sprintf("%f,%f", p.Lat, p.Lng),
+		lastEvaluatedField, at.Format(time.RFC3339Nano))
+}
+
+func (c *GeofenceStateCache) vehicleLocked(vehicleID string) *VehicleGeofenceState {
+	state, ok := c.states[vehicleID]
+	if !ok {
+		state = &VehicleGeofenceState{Geofences: make(map[int64]GeofenceVisit)}
+		c.states[vehicleID] = state
+	}
+	return state
+}
+
+// mirrorLoadedRetries bounds how often mirrorLoaded retries a vehicle whose
+// hash another worker changed in the meantime
+const mirrorLoadedRetries = 3
+
+// mirrorLoaded replaces the geofence visits in the vehicle's Redis hash with
+// the loaded ones. The last location fields are kept, and so is anything a
+// live worker wrote after the events were read: a visit that started later
+// than the loaded one, or a visit for another geofence that started after
+// newest. Other geofence fields are removed.
+func (c *GeofenceStateCache) mirrorLoaded(ctx context.Context, vehicleID string, loaded map[int64]GeofenceVisit, newest time.Time) error {
+	key := geofenceStateKeyPrefix + vehicleID
+	update := func(tx *redis.Tx) error {
+		existing, err := tx.HGetAll(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+
+		fields := make(map[string]interface{}, len(loaded))
+		for geofenceID, visit := range loaded {
+			field := strconv.FormatInt(geofenceID, 10)
+			if value, ok := existing[field]; ok {
+				visit = mergeVisit(decodeVisit(value), visit)
+			}
+			fields[field] = encodeVisit(visit)
+		}
+		var stale []string
+		for field, value := range existing {
+			id, err := strconv.ParseInt(field, 10, 64)
+			if err != nil {
+				continue
+			}
+			if _, ok := loaded[id]; !ok && !decodeVisit(value).Since.After(newest) {
+				stale = append(stale, field)
+			}
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if len(stale) > 0 {
+				pipe.HDel(ctx, key, stale...)
+			}
+			pipe.HSet(ctx, key, fields)
+			pipe.Expire(ctx, key, geofenceStateTTL)
+			return nil
+		})
+		return err
+	}
+
+	var err error
+	for range mirrorLoadedRetries {
+		if err = c.rdb.Watch(ctx, update, key); !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return err
+}
+
+// mergeVisit returns whichever of the two visits to one geofence started
+// later, with the once-per-visit flags of both when they are the same visit
+func mergeVisit(current, loaded GeofenceVisit) GeofenceVisit {
+	switch {
+	case current.Since.After(loaded.Since):
+		return current
+	case current.Since.Equal(loaded.Since) && current.EventType == loaded.EventType:
+		loaded.DwellReported = loaded.DwellReported || current.DwellReported
+		loaded.OverdueReported = loaded.OverdueReported || current.OverdueReported
+		return loaded
+	default:
+		return loaded
+	}
+}
+
+// mirror writes field/value pairs to the vehicle's Redis hash
+func (c *GeofenceStateCache) mirror(ctx context.Context, vehicleID string, fieldValues ...interface{}) {
+	if c.rdb == nil {
+		return
+	}
+	key := geofenceStateKeyPrefix + vehicleID
+	pipe := c.rdb.TxPipeline()
+	pipe.HSet(ctx, key, fieldValues...)
+	pipe.Expire(ctx, key, geofenceStateTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		stateLog.ErrorContext(ctx, "Failed to mirror geofence state to Redis", logging.VehicleID(vehicleID), logging.Err(err))
+	}
+}
+
+func (c *GeofenceStateCache) redisVisit(ctx context.Context, vehicleID string, geofenceID int64) (GeofenceVisit, bool) {
+	if c.rdb == nil {
+		return GeofenceVisit{}, false
+	}
+	value, err := c.rdb.HGet(ctx, geofenceStateKeyPrefix+vehicleID, strconv.FormatInt(geofenceID, 10)).Result()
+	if err != nil {
+		return GeofenceVisit{}, false
+	}
+	return decodeVisit(value), true
+}
+
+func isTransition(eventType string) bool {
+	return eventType == model.GeofenceEventEntry || eventType == model.GeofenceEventExit
+}
+
+func encodeVisit(visit GeofenceVisit) string {
+	data, _ := json.Marshal(visit)
+	return string(data)
+}
+
+// decodeVisit also accepts the bare event types written by older workers
+func decodeVisit(value string) GeofenceVisit {
+	var visit GeofenceVisit
+	if err := json.Unmarshal([]byte(value), &visit); err != nil {
+		return GeofenceVisit{EventType: value}
+	}
+	return visit
+}
+
+func parseVehicleGeofenceState(fields map[string]string) VehicleGeofenceState {
+	state := VehicleGeofenceState{Geofences: make(map[int64]GeofenceVisit, len(fields))}
+	for field, value := range fields {
+		switch field {
+		case lastLocationField:
+			var p geo.Point
+			if _, err := fmt.Sscanf(value, "%f,%f", &p.Lat, &p.Lng); err == nil {
+				state.LastLocation = &p
+			}
+			continue
+		case lastEvaluatedField:
+			if at, err := time.Parse(time.RFC3339Nano, value); err == nil {
+				state.LastEvaluated = at
+			}
+			continue
+		}
+		if id, err := strconv.ParseInt(field, 10, 64); err == nil {
+			state.Geofences[id] = decodeVisit(value)
+		}
+	}
+	return state
+}
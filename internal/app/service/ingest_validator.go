@@ -0,0 +1,148 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/queue"
+)
+
+// QuarantineTopic is the stream of MQTT messages refused at ingestion. Nothing
+// consumes it; it is kept, trimmed like every stream, for inspection.
+const QuarantineTopic = "location_quarantine:stream"
+
+// Reasons a location update is refused at ingestion
+const (
+	ReasonInvalidJSON           = "invalid_json"
+	ReasonSchemaViolation       = "schema_violation"
+	ReasonInvalidVehicleID      = "invalid_vehicle_id"
+	ReasonCoordinatesOutOfRange = "coordinates_out_of_range"
+	ReasonTimestampInFuture     = "timestamp_in_future"
+	ReasonTimestampTooOld       = "timestamp_too_old"
+	ReasonInvalidTelemetry      = "invalid_telemetry"
+)
+
+// IngestRejectReasons lists every reason code, in the order they are checked
+var IngestRejectReasons = []string{
+	ReasonInvalidJSON,
+	ReasonSchemaViolation,
+	ReasonInvalidVehicleID,
+	ReasonCoordinatesOutOfRange,
+	ReasonTimestampInFuture,
+	ReasonTimestampTooOld,
+	ReasonInvalidTelemetry,
+}
+
+// Fields every location update must carry, and fields only the server sets
+var (
+	requiredLocationFields = []string{"vehicle_id", "latitude", "longitude", "timestamp"}
+	serverLocationFields   = []string{"id", "received_at"}
+)
+
+// IngestError is why a location update was refused at ingestion
+type IngestError struct {
+	Reason string
+	Err    error
+}
+
+func (e *IngestError) Error() string {
+	return e.Reason + ": " + e.Err.Error()
+}
+
+func (e *IngestError) Unwrap() error {
+	return e.Err
+}
+
+func reject(reason, format string, args ...any) *IngestError {
+	return &IngestError{Reason: reason, Err: fmt.Errorf(format, args...)}
+}
+
+// LocationValidator checks MQTT location updates before they are queued for
+// the workers
+type LocationValidator struct {
+	vehicleID    *regexp.Regexp
+	maxClockSkew time.Duration
+	maxAge       time.Duration
+}
+
+// NewLocationValidator accepts vehicle IDs matching vehicleIDPattern and
+// timestamps from maxAge before receipt to maxClockSkew after it
+func NewLocationValidator(vehicleIDPattern string, maxClockSkew, maxAge time.Duration) (*LocationValidator, error) {
+	vehicleID, err := regexp.Compile(vehicleIDPattern)
+	if err != nil {
+		return nil, fmt.Errorf("compiling vehicle id pattern: %w", err)
+	}
+	return &LocationValidator{vehicleID: vehicleID, maxClockSkew: maxClockSkew, maxAge: maxAge}, nil
+}
+
+// Validate decodes a location update received at receivedAt and checks it,
+// stopping at the first check that fails with an *IngestError. The location
+// is returned whenever the payload decoded, so that it can be logged.
+func (v *LocationValidator) Validate(payload []byte, receivedAt time.Time) (*model.VehicleLocation, error) {
+	if !json.Valid(payload) {
+		return nil, reject(ReasonInvalidJSON, "payload is not valid JSON")
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil || fields == nil {
+		return nil, reject(ReasonSchemaViolation, "payload must be a JSON object")
+	}
+	for _, field := range requiredLocationFields {
+		if value, ok := fields[field]; !ok || string(value) == "null" {
+			return nil, reject(ReasonSchemaViolation, "%s is required", field)
+		}
+	}
+	for _, field := range serverLocationFields {
+		if _, ok := fields[field]; ok {
+			return nil, reject(ReasonSchemaViolation, "%s is set by the server", field)
+		}
+	}
+
+	var loc model.VehicleLocation
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&loc); err != nil {
+		return nil, &IngestError{Reason: ReasonSchemaViolation, Err: err}
+	}
+
+	if !v.vehicleID.MatchString(loc.VehicleID) {
+		return &loc, reject(ReasonInvalidVehicleID, "vehicle id %q does not match %s", loc.VehicleID, v.vehicleID)
+	}
+	if loc.Latitude < -90 || loc.Latitude > 90 || loc.Longitude < -180 || loc.Longitude > 180 {
+		return &loc, reject(ReasonCoordinatesOutOfRange, "coordinates (%g, %g) are outside [-90, 90] and [-180, 180]", loc.Latitude, loc.Longitude)
+	}
+	if loc.Timestamp.After(receivedAt.Add(v.maxClockSkew)) {
+		return &loc, reject(ReasonTimestampInFuture, "timestamp %s is more than %s after receipt", loc.Timestamp.Format(time.RFC3339), v.maxClockSkew)
+	}
+	if loc.Timestamp.Before(receivedAt.Add(-v.maxAge)) {
+		return &loc, reject(ReasonTimestampTooOld, "timestamp %s is more than %s before receipt", loc.Timestamp.Format(time.RFC3339), v.maxAge)
+	}
+	if err := loc.Validate(); err != nil {
+		return &loc, &IngestError{Reason: ReasonInvalidTelemetry, Err: err}
+	}
+	return &loc, nil
+}
+
+// QuarantineLocationUpdate publishes a refused MQTT message to QuarantineTopic
+// with the reason it was refused
+func QuarantineLocationUpdate(ctx context.Context, q queue.Queue, mqttTopic string, payload []byte, receivedAt time.Time, reason *IngestError) error {
+	message := model.QuarantinedMessage{
+		Reason:     reason.Reason,
+		Error:      reason.Err.Error(),
+		MQTTTopic:  mqttTopic,
+		Payload:    json.RawMessage(payload),
+		ReceivedAt: receivedAt,
+	}
+	if !json.Valid(payload) {
+		message.Payload, _ = json.Marshal(string(payload))
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return q.Publish(ctx, QuarantineTopic, data)
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/queue"
+)
+
+func newTestValidator(t *testing.T) *LocationValidator {
+	v, err := NewLocationValidator(`^[A-Z0-9]{3,12}$`, 5*time.Minute, 24*time.Hour)
+	require.NoError(t, err)
+	return v
+}
+
+func TestLocationValidator_AcceptsValidUpdate(t *testing.T) {
+	receivedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	payload := `{"vehicle_id":"B1234XYZ","latitude":-6.2,"longitude":106.8,"speed":40,"heading":90,
+		"timestamp":"2026-03-01T11:59:58Z","attributes":{"ignition":true}}`
+
+	loc, err := newTestValidator(t).Validate([]byte(payload), receivedAt)
+	require.NoError(t, err)
+	assert.Equal(t, "B1234XYZ", loc.VehicleID)
+	assert.Equal(t, receivedAt.Add(-2*time.Second), loc.Timestamp)
+}
+
+func TestLocationValidator_RejectsWithReason(t *testing.T) {
+	receivedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		payload string
+		reason  string
+	}{
+		{"not json", `{"vehicle_id":`, ReasonInvalidJSON},
+		{"trailing data", `{} {}`, ReasonInvalidJSON},
+		{"array", `[1, 2]`, ReasonSchemaViolation},
+		{"missing timestamp", `{"vehicle_id":"B1234XYZ","latitude":1,"longitude":1}`, ReasonSchemaViolation},
+		{"null latitude", `{"vehicle_id":"B1234XYZ","latitude":null,"longitude":1,"timestamp":"2026-03-01T12:00:00Z"}`, ReasonSchemaViolation},
+		{"wrong type", `{"vehicle_id":"B1234XYZ","latitude":"north","longitude":1,"timestamp":"2026-03-01T12:00:00Z"}`, ReasonSchemaViolation},
+		{"unknown field", `{"vehicle_id":"B1234XYZ","latitude":1,"longitude":1,"timestamp":"2026-03-01T12:00:00Z","lat":1}`, ReasonSchemaViolation},
+		{"server field", `{"id":7,"vehicle_id":"B1234XYZ","latitude":1,"longitude":1,"timestamp":"2026-03-01T12:00:00Z"}`, ReasonSchemaViolation},
+		{"vehicle id", `{"vehicle_id":"b 1234","latitude":1,"longitude":1,"timestamp":"2026-03-01T12:00:00Z"}`, ReasonInvalidVehicleID},
+		{"empty vehicle id", `{"vehicle_id":"","latitude":1,"longitude":1,"timestamp":"2026-03-01T12:00:00Z"}`, ReasonInvalidVehicleID},
+		{"latitude", `{"vehicle_id":"B1234XYZ","latitude":91,"longitude":1,"timestamp":"2026-03-01T12:00:00Z"}`, ReasonCoordinatesOutOfRange},
+		{"longitude", `{"vehicle_id":"B1234XYZ","latitude":1,"longitude":-181,"timestamp":"2026-03-01T12:00:00Z"}`, ReasonCoordinatesOutOfRange},
+		{"future", `{"vehicle_id":"B1234XYZ","latitude":1,"longitude":1,"timestamp":"2026-03-01T12:06:00Z"}`, ReasonTimestampInFuture},
+		{"ancient", `{"vehicle_id":"B1234XYZ","latitude":1,"longitude":1,"timestamp":"2026-02-28T11:59:00Z"}`, ReasonTimestampTooOld},
+		{"zero time", `{"vehicle_id":"B1234XYZ","latitude":1,"longitude":1,"timestamp":"0001-01-01T00:00:00Z"}`, ReasonTimestampTooOld},
+		{"speed", `{"vehicle_id":"B1234XYZ","latitude":1,"longitude":1,"speed":-3,"timestamp":"2026-03-01T12:00:00Z"}`, ReasonInvalidTelemetry},
+		{"attribute", `{"vehicle_id":"B1234XYZ","latitude":1,"longitude":1,"timestamp":"2026-03-01T12:00:00Z","attributes":{"fuel_level":140}}`, ReasonInvalidTelemetry},
+	}
+	v := newTestValidator(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.Validate([]byte(tt.payload), receivedAt)
+			var rejected *IngestError
+			require.True(t, errors.As(err, &rejected), "got %v", err)
+			assert.Equal(t, tt.reason, rejected.Reason)
+			assert.Contains(t, IngestRejectReasons, rejected.Reason)
+		})
+	}
+}
+
+func TestNewLocationValidator_RejectsInvalidPattern(t *testing.T) {
+	_, err := NewLocationValidator(`[A-Z`, time.Minute, time.Hour)
+	assert.ErrorContains(t, err, "vehicle id pattern")
+}
+
+func TestQuarantineLocationUpdate_PublishesReason(t *testing.T) {
+	q := queue.NewMemoryQueue()
+	defer q.Close()
+	receivedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	payload := []byte("not json")
+	_, err := newTestValidator(t).Validate(payload, receivedAt)
+	var rejected *IngestError
+	require.True(t, errors.As(err, &rejected))
+	require.NoError(t, QuarantineLocationUpdate(context.Background(), q, "fleet/vehicle/B1/location", payload, receivedAt, rejected))
+
+	messages := q.Messages(QuarantineTopic)
+	require.Len(t, messages, 1)
+	var quarantined model.QuarantinedMessage
+	require.NoError(t, json.Unmarshal(messages[0], &quarantined))
+	assert.Equal(t, ReasonInvalidJSON, quarantined.Reason)
+	assert.Equal(t, "payload is not valid JSON", quarantined.Error)
+	assert.Equal(t, "fleet/vehicle/B1/location", quarantined.MQTTTopic)
+	assert.JSONEq(t, `"not json"`, string(quarantined.Payload), "invalid JSON is quoted")
+	assert.True(t, receivedAt.Equal(quarantined.ReceivedAt))
+	assert.Empty(t, q.Messages(VehicleLocationTopic), "nothing reaches the workers")
+}
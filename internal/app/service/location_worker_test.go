@@ -0,0 +1,359 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/queue"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/repository"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/tracing"
+)
+
+// fakeVehicleRepo stores inserted locations in memory. It fails every insert
+// with err if set, any insert including the reject vehicle, and the next
+// flakes inserts including a location timestamped flaky.
+type fakeVehicleRepo struct {
+	repository.VehicleRepository
+	mu        sync.Mutex
+	locations []model.VehicleLocation
+	batches   []int
+	err       error
+	reject    string
+	flaky     time.Time
+	flakes    int
+}
+
+func (r *fakeVehicleRepo) InsertLocation(ctx context.Context, loc *model.VehicleLocation) error {
+	return r.InsertLocations(ctx, []*model.VehicleLocation{loc})
+}
+
+func (r *fakeVehicleRepo) InsertLocations(ctx context.Context, locs []*model.VehicleLocation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	for _, loc := range locs {
+		if loc.VehicleID == r.reject {
+			return errors.New("violates check constraint")
+		}
+		if r.flakes > 0 && loc.Timestamp.Equal(r.flaky) {
+			r.flakes--
+			return errors.New("deadlock detected")
+		}
+	}
+	r.batches = append(r.batches, len(locs))
+	for _, loc := range locs {
+		r.locations = append(r.locations, *loc)
+	}
+	return nil
+}
+
+func (r *fakeVehicleRepo) saved() []model.VehicleLocation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]model.VehicleLocation(nil), r.locations...)
+}
+
+// testRetryPolicy retries quickly enough for tests to wait on
+var testRetryPolicy = queue.RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Millisecond,
+	MaxDelay:    5 * time.Millisecond,
+}
+
+// testBatchPolicy collects everything published before the worker starts
+// into one batch
+var testBatchPolicy = queue.BatchPolicy{Size: 10, Wait: 10 * time.Millisecond}
+
+// runWorker runs a worker until done reports true, then stops it
+func runWorker(t *testing.T, run func(ctx context.Context) error, done func() bool) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() { stopped <- run(ctx) }()
+
+	assert.Eventually(t, done, time.Second, 5*time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-stopped, context.Canceled)
+}
+
+// publishedEvents decodes the envelopes published to topic
+func publishedEvents(t *testing.T, q *queue.MemoryQueue, topic string) []model.EventEnvelope {
+	t.Helper()
+	var envelopes []model.EventEnvelope
+	for _, body := range q.Messages(topic) {
+		var envelope model.EventEnvelope
+		require.NoError(t, json.Unmarshal(body, &envelope))
+		envelopes = append(envelopes, envelope)
+	}
+	return envelopes
+}
+
+// mustEnvelope wraps payload in a location update envelope
+func mustEnvelope(t *testing.T, payload []byte) []byte {
+	t.Helper()
+	body, err := json.Marshal(model.EventEnvelope{EventType: "location_update", Payload: payload})
+	require.NoError(t, err)
+	return body
+}
+
+func TestLocationWorker_SavesLocations(t *testing.T) {
+	q := queue.NewMemoryQueue()
+	repo := &fakeVehicleRepo{}
+	require.NoError(t, PushLocationUpdate(context.Background(), q, "location_update", "test", []byte(`{"vehicle_id":"B1234XYZ","latitude":-6.2,"longitude":106.8,"speed":42.5,"heading":90,"satellites":9}`)))
+	require.NoError(t, PushLocationUpdate(context.Background(), q, "location_update", "test", []byte(`{"vehicle_id":"B5678XYZ","latitude":-6.3,"longitude":106.9}`)))
+
+	worker := NewLocationWorker(q, repo, nil, testRetryPolicy, testBatchPolicy, 4)
+	runWorker(t, worker.Run, func() bool { return len(repo.saved()) == 2 })
+
+	saved := repo.saved()
+	assert.Equal(t, "B1234XYZ", saved[0].VehicleID)
+	assert.Equal(t, -6.2, saved[0].Latitude)
+	assert.Equal(t, 42.5, saved[0].Speed)
+	require.NotNil(t, saved[0].Heading)
+	assert.Equal(t, 90.0, *saved[0].Heading)
+	require.NotNil(t, saved[0].Satellites)
+	assert.Equal(t, 9, *saved[0].Satellites)
+	assert.Nil(t, saved[0].Altitude, "unreported telemetry stays unset")
+	assert.NotNil(t, saved[0].ReceivedAt, "the receive time comes from the envelope")
+	assert.Equal(t, "B5678XYZ", saved[1].VehicleID)
+
+	assert.Equal(t, []int{2}, repo.batches, "both updates are saved in one batch")
+
+	// Both updates were also published for the event log worker
+	assert.Len(t, publishedEvents(t, q, EventLogTopic), 2)
+}
+
+func TestLocationWorker_BadRowOnlyFailsItsOwnMessage(t *testing.T) {
+	q := queue.NewMemoryQueue()
+	repo := &fakeVehicleRepo{reject: "BAD"}
+	for _, vehicleID := range []string{"B1", "BAD", "B2"} {
+		payload := []byte(`{"vehicle_id":"` + vehicleID + `"}`)
+		require.NoError(t, q.Publish(context.Background(), VehicleLocationTopic, mustEnvelope(t, payload)))
+	}
+
+	worker := NewLocationWorker(q, repo, nil, testRetryPolicy, testBatchPolicy, 4)
+	runWorker(t, worker.Run, func() bool { return len(q.DeadLetters()) == 1 })
+
+	saved := repo.saved()
+	require.Len(t, saved, 2)
+	assert.Equal(t, "B1", saved[0].VehicleID)
+	assert.Equal(t, "B2", saved[1].VehicleID)
+	assert.Contains(t, q.DeadLetters()[0].EventJSON, `"BAD"`)
+}
+
+func TestLocationWorker_ReportsUndecodableUpdates(t *testing.T) {
+	q := queue.NewMemoryQueue()
+	repo := &fakeVehicleRepo{}
+	require.NoError(t, q.Publish(context.Background(), VehicleLocationTopic, []byte("not json")))
+	require.NoError(t, PushLocationUpdate(context.Background(), q, "location_update", "test", []byte(`"not a location"`)))
+
+	worker := NewLocationWorker(q, repo, nil, testRetryPolicy, testBatchPolicy, 4)
+	runWorker(t, worker.Run, func() bool {
+		// one passthrough copy of the second update plus two error reports
+		return len(q.Messages(EventLogTopic)) == 3
+	})
+
+	assert.Empty(t, repo.saved())
+	events := publishedEvents(t, q, EventLogTopic)
+	assert.Equal(t, "unmarshal_error", events[1].EventType)
+	assert.JSONEq(t, `"not json"`, string(events[1].Payload), "invalid JSON is reported as a string")
+	assert.Equal(t, "unmarshal_error", events[2].EventType)
+	assert.Equal(t, "LocationWorker", events[2].Source)
+}
+
+func TestLocationWorker_ReportsImpossibleLocations(t *testing.T) {
+	q := queue.NewMemoryQueue()
+	repo := &fakeVehicleRepo{}
+	for _, payload := range []string{
+		`{"vehicle_id":"B1","latitude":91,"longitude":106.8}`,
+		`{"vehicle_id":"B2","latitude":-6.2,"longitude":106.8,"speed":-3}`,
+		`{"vehicle_id":"B3","latitude":-6.2,"longitude":106.8,"heading":360}`,
+		`{"vehicle_id":"B4","latitude":-6.2,"longitude":106.8}`,
+	} {
+		require.NoError(t, q.Publish(context.Background(), VehicleLocationTopic, mustEnvelope(t, []byte(payload))))
+	}
+
+	worker := NewLocationWorker(q, repo, nil, testRetryPolicy, testBatchPolicy, 4)
+	runWorker(t, worker.Run, func() bool { return len(q.Messages(EventLogTopic)) == 3 })
+
+	saved := repo.saved()
+	require.Len(t, saved, 1)
+	assert.Equal(t, "B4", saved[0].VehicleID)
+	for _, event := range publishedEvents(t, q, EventLogTopic) {
+		assert.Equal(t, "validation_error", event.EventType)
+	}
+	assert.Empty(t, q.DeadLetters(), "invalid updates are reported, not retried")
+}
+
+func TestLocationWorker_DeadLettersSaveErrors(t *testing.T) {
+	q := queue.NewMemoryQueue()
+	repo := &fakeVehicleRepo{err: errors.New("connection refused")}
+	body := `{"event_type":"location_update","payload":{"vehicle_id":"B1234XYZ"}}`
+	require.NoError(t, q.Publish(context.Background(), VehicleLocationTopic, []byte(body)))
+
+	worker := NewLocationWorker(q, repo, nil, testRetryPolicy, testBatchPolicy, 4)
+	runWorker(t, worker.Run, func() bool { return len(q.DeadLetters()) == 1 })
+
+	entry := q.DeadLetters()[0]
+	assert.Equal(t, VehicleLocationTopic, entry.Queue)
+	assert.JSONEq(t, body, entry.EventJSON)
+	assert.Len(t, entry.Attempts, testRetryPolicy.MaxAttempts)
+	assert.Empty(t, q.Messages(EventLogTopic), "save errors are retried, not reported")
+}
+
+func TestLocationWorker_ChecksGeofencesInOrderPerVehicle(t *testing.T) {
+	geofences := &fakeGeofenceRepo{geofences: []*model.Geofence{{
+		ID: 1, Name: "Bundaran HI", CenterLat: -6.193125, CenterLng: 106.820233, Radius: 100, Active: true,
+	}}}
+	eventRepo := &fakeGeofenceEventRepo{}
+	q := queue.NewMemoryQueue()
+	geofenceService := NewGeofenceService(NewGeofenceCache(geofences, nil, time.Hour), NewGeofenceStateCache(nil), eventRepo, q, nil)
+
+	// Every vehicle drives in and out of the geofence several times, with
+	// all vehicles' updates interleaved on the topic
+	const vehicles, trips = 20, 5
+	base := time.Now()
+	for i := 0; i < trips*2; i++ {
+		lat := -6.193125
+		if i%2 == 1 {
+			lat += 0.01 // about 1.1km north
+		}
+		for v := 0; v < vehicles; v++ {
+			payload, err := json.Marshal(model.VehicleLocation{
+				VehicleID: fmt.Sprintf("BUS-%03d", v),
+				Latitude:  lat,
+				Longitude: 106.820233,
+				Timestamp: base.Add(time.Duration(i) * time.Minute),
+			})
+			require.NoError(t, err)
+			require.NoError(t, q.Publish(context.Background(), VehicleLocationTopic, mustEnvelope(t, payload)))
+		}
+	}
+
+	repo := &fakeVehicleRepo{}
+	worker := NewLocationWorker(q, repo, geofenceService, testRetryPolicy, testBatchPolicy, 4)
+	runWorker(t, worker.Run, func() bool { return len(repo.saved()) == vehicles*trips*2 })
+
+	eventRepo.mu.Lock()
+	defer eventRepo.mu.Unlock()
+	byVehicle := make(map[string][]string)
+	for _, event := range eventRepo.events {
+		byVehicle[event.VehicleID] = append(byVehicle[event.VehicleID], event.EventType)
+	}
+	require.Len(t, byVehicle, vehicles)
+	for vehicleID, events := range byVehicle {
+		require.Len(t, events, trips*2, vehicleID)
+		for i, eventType := range events {
+			want := model.GeofenceEventEntry
+			if i%2 == 1 {
+				want = model.GeofenceEventExit
+			}
+			assert.Equal(t, want, eventType, "%s event %d", vehicleID, i)
+		}
+	}
+}
+
+func TestLocationWorker_RetriedLocationKeepsGeofenceEventsInOrder(t *testing.T) {
+	geofences := &fakeGeofenceRepo{geofences: []*model.Geofence{{
+		ID: 1, Name: "Bundaran HI", CenterLat: -6.193125, CenterLng: 106.820233, Radius: 100, Active: true,
+	}}}
+	eventRepo := &fakeGeofenceEventRepo{}
+	q := queue.NewMemoryQueue()
+	geofenceService := NewGeofenceService(NewGeofenceCache(geofences, nil, time.Hour), NewGeofenceStateCache(nil), eventRepo, q, nil)
+
+	// The bus drives from south of the geofence, through it and out to the
+	// north. The first location inside fails to save, both in the batch and
+	// on its own, so it is retried after the rest of the batch.
+	base := time.Now().Truncate(time.Second)
+	latitudes := []float64{-6.203125, -6.193125, -6.193125, -6.183125}
+	for i, lat := range latitudes {
+		payload, err := json.Marshal(model.VehicleLocation{
+			VehicleID: "BUS-001",
+			Latitude:  lat,
+			Longitude: 106.820233,
+			Timestamp: base.Add(time.Duration(i) * time.Minute),
+		})
+		require.NoError(t, err)
+		require.NoError(t, q.Publish(context.Background(), VehicleLocationTopic, mustEnvelope(t, payload)))
+	}
+
+	repo := &fakeVehicleRepo{flaky: base.Add(time.Minute), flakes: 2}
+	worker := NewLocationWorker(q, repo, geofenceService, testRetryPolicy, testBatchPolicy, 4)
+	runWorker(t, worker.Run, func() bool { return len(repo.saved()) == len(latitudes) })
+
+	eventRepo.mu.Lock()
+	defer eventRepo.mu.Unlock()
+	var last time.Time
+	for i, event := range eventRepo.events {
+		want := model.GeofenceEventEntry
+		if i%2 == 1 {
+			want = model.GeofenceEventExit
+		}
+		assert.Equal(t, want, event.EventType, "event %d", i)
+		assert.False(t, event.Timestamp.Before(last), "event %d at %s is out of order", i, event.Timestamp)
+		last = event.Timestamp
+	}
+	require.NotEmpty(t, eventRepo.events)
+	assert.True(t, base.Add(time.Minute).Equal(eventRepo.events[0].Timestamp), "the retried location enters")
+}
+
+func TestLocationWorker_ContinuesPublisherTrace(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+
+	geofences := &fakeGeofenceRepo{geofences: []*model.Geofence{{
+		ID: 1, Name: "Bundaran HI", CenterLat: -6.193125, CenterLng: 106.820233, Radius: 100, Active: true,
+	}}}
+	q := queue.NewMemoryQueue()
+	geofenceService := NewGeofenceService(NewGeofenceCache(geofences, nil, time.Hour), NewGeofenceStateCache(nil), &fakeGeofenceEventRepo{}, q, nil)
+
+	ctx, root := tracing.Tracer().Start(context.Background(), "MessageHandler")
+	require.NoError(t, PushLocationUpdate(ctx, q, "location_update", "test", []byte(`{"vehicle_id":"B1","latitude":-6.193125,"longitude":106.820233}`)))
+	root.End()
+	traceID := root.SpanContext().TraceID()
+
+	repo := &fakeVehicleRepo{}
+	worker := NewLocationWorker(q, repo, geofenceService, testRetryPolicy, testBatchPolicy, 4)
+	runWorker(t, worker.Run, func() bool { return len(repo.saved()) == 1 })
+
+	byName := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range spans.Ended() {
+		byName[span.Name()] = span
+	}
+	require.Contains(t, byName, "SaveVehicleLocation")
+	require.Contains(t, byName, "CallCheckGeofences")
+	save, check := byName["SaveVehicleLocation"], byName["CallCheckGeofences"]
+	assert.Equal(t, traceID, save.SpanContext().TraceID())
+	assert.Equal(t, root.SpanContext().SpanID(), save.Parent().SpanID())
+	assert.Equal(t, save.SpanContext().SpanID(), check.Parent().SpanID())
+
+	require.Contains(t, byName, "InsertLocations")
+	require.Len(t, byName["InsertLocations"].Links(), 1)
+	assert.Equal(t, save.SpanContext().SpanID(), byName["InsertLocations"].Links()[0].SpanContext.SpanID())
+
+	// The entry event published to the event log carries the same trace
+	events := publishedEvents(t, q, EventLogTopic)
+	require.Len(t, events, 2)
+	assert.Contains(t, events[1].TraceContext["traceparent"], traceID.String())
+}
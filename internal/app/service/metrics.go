@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/metrics"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/queue"
+)
+
+// Worker names used as the worker label of the processing metrics
+const (
+	eventLogWorkerName = "event_log"
+	locationWorkerName = "location"
+)
+
+// observeWorker records how long a worker took over a delivery and the result
+// of each message in it
+func observeWorker(worker string, start time.Time, errs ...error) {
+	metrics.WorkerProcessingDuration.WithLabelValues(worker).Observe(metrics.Since(start))
+	for _, err := range errs {
+		metrics.WorkerMessages.WithLabelValues(worker, metrics.Result(err)).Inc()
+	}
+}
+
+// streamStatusTimeout bounds the Redis calls made during one scrape
+const streamStatusTimeout = 5 * time.Second
+
+var (
+	streamLengthDesc = prometheus.NewDesc(
+		"vehicle_tracker_stream_length",
+		"Messages held in a Redis stream.",
+		[]string{"stream"}, nil)
+	streamPendingDesc = prometheus.NewDesc(
+		"vehicle_tracker_stream_pending",
+		"Messages delivered to a consumer group but not yet acknowledged.",
+		[]string{"stream", "group"}, nil)
+	streamLagDesc = prometheus.NewDesc(
+		"vehicle_tracker_stream_lag",
+		"Messages not yet delivered to a consumer group.",
+		[]string{"stream", "group"}, nil)
+	scheduledRetriesDesc = prometheus.NewDesc(
+		"vehicle_tracker_scheduled_retries",
+		"Retries waiting to come due.",
+		nil, nil)
+	deadLettersDesc = prometheus.NewDesc(
+		"vehicle_tracker_dead_letters",
+		"Messages in the dead-letter store.",
+		nil, nil)
+)
+
+// StreamCollector reports the backlog of the worker streams, the scheduled
+// retries and the dead letters, read from Redis at scrape time
+type StreamCollector struct {
+	queue *queue.RedisQueue
+}
+
+func NewStreamCollector(q *queue.RedisQueue) *StreamCollector {
+	return &StreamCollector{queue: q}
+}
+
+func (c *StreamCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- streamLengthDesc
+	ch <- streamPendingDesc
+	ch <- streamLagDesc
+	ch <- scheduledRetriesDesc
+	ch <- deadLettersDesc
+}
+
+// Collect skips values it fails to read rather than failing the scrape
+func (c *StreamCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), streamStatusTimeout)
+	defer cancel()
+
+	for _, g := range workerGroups {
+		status, err := c.queue.Status(ctx, g[0], g[1])
+		if err != nil {
+			streamLog.ErrorContext(ctx, "Failed to read stream status", logging.Queue(g[0]), logging.Err(err))
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(streamLengthDesc, prometheus.GaugeValue, float64(status.Length), status.Stream)
+		ch <- prometheus.MustNewConstMetric(streamPendingDesc, prometheus.GaugeValue, float64(status.Pending), status.Stream, status.Group)
+		ch <- prometheus.MustNewConstMetric(streamLagDesc, prometheus.GaugeValue, float64(status.Lag), status.Stream, status.Group)
+	}
+	if scheduled, err := c.queue.Scheduled(ctx); err != nil {
+		streamLog.ErrorContext(ctx, "Failed to count scheduled retries", logging.Err(err))
+	} else {
+		ch <- prometheus.MustNewConstMetric(scheduledRetriesDesc, prometheus.GaugeValue, float64(scheduled))
+	}
+	if deadLetters, err := c.queue.DeadLetters(ctx); err != nil {
+		streamLog.ErrorContext(ctx, "Failed to count dead letters", logging.Err(err))
+	} else {
+		ch <- prometheus.MustNewConstMetric(deadLettersDesc, prometheus.GaugeValue, float64(deadLetters))
+	}
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/queue"
+)
+
+func TestStreamCollector_ReportsBacklog(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	q := queue.NewRedisQueue(rdb, "")
+
+	for _, g := range workerGroups {
+		require.NoError(t, rdb.XGroupCreateMkStream(ctx, g[0], g[1], "0").Err())
+	}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, q.Publish(ctx, VehicleLocationTopic, []byte(`{}`)))
+	}
+	require.NoError(t, q.Publish(ctx, EventLogTopic, []byte(`{}`)))
+	require.NoError(t, q.Schedule(ctx, EventLogTopic, queue.Message{ID: "1", Body: []byte(`{}`)}, time.Now().Add(time.Hour)))
+	require.NoError(t, q.DeadLetter(ctx, EventLogTopic, queue.Message{Body: []byte(`{}`)}, errors.New("boom")))
+
+	expected := `
+# HELP vehicle_tracker_dead_letters Messages in the dead-letter store.
+# TYPE vehicle_tracker_dead_letters gauge
+vehicle_tracker_dead_letters 1
+# HELP vehicle_tracker_scheduled_retries Retries waiting to come due.
+# TYPE vehicle_tracker_scheduled_retries gauge
+vehicle_tracker_scheduled_retries 1
+# HELP vehicle_tracker_stream_length Messages held in a Redis stream.
+# TYPE vehicle_tracker_stream_length gauge
+vehicle_tracker_stream_length{stream="event_log:stream"} 1
+vehicle_tracker_stream_length{stream="vehicle_location:stream"} 3
+`
+	require.NoError(t, testutil.CollectAndCompare(NewStreamCollector(q), strings.NewReader(expected),
+		"vehicle_tracker_dead_letters", "vehicle_tracker_scheduled_retries", "vehicle_tracker_stream_length"))
+}
@@ -1,21 +1,116 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
-	"github.com/redis/go-redis/v9"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/config"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/metrics"
 	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/queue"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-type GeofenceAlert struct {
-	EventType string  `json:"event_type"`
-	VehicleID string  `json:"vehicle_id"`
-	Latitude  float64 `json:"latitude"`
-	Longitude float64 `json:"longitude"`
-	Timestamp int64   `json:"timestamp"`
+var alertLog = logging.Component("rabbitmq_service")
+
+// AlertExchange is the topic exchange all alerts are published to. Routing
+// keys start with "alert." followed by the alert kind, e.g.
+// alert.geofence.entry.<geofence_id>, so consumers bind only what they need.
+const AlertExchange = "alerts"
+
+// GeofenceEventQueue is the durable default queue. Unless the default queue is
+// turned off in config.AlertsConfig, publishers keep it bound to
+// GeofenceEventBinding, so geofence alerts wait there even before any consumer
+// has started; filtered consumers should use their own queue.
+const GeofenceEventQueue = "geofence.event"
+
+// GeofenceEventBinding binds GeofenceEventQueue to every geofence alert
+const GeofenceEventBinding = "alert.geofence.#"
+
+// alertChannel is the part of *amqp.Channel that declares the alert topology
+type alertChannel interface {
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+}
+
+// DeclareAlertExchange declares AlertExchange. It is idempotent, so
+// publishers and consumers both call it.
+func DeclareAlertExchange(ch alertChannel) error {
+	return ch.ExchangeDeclare(
+		AlertExchange,
+		"topic",
+		true,  // durable
+		false, // auto-deleted
+		false, // internal
+		false, // no-wait
+		nil,   // arguments
+	)
+}
+
+// geofenceEventQueueArgs returns the arguments GeofenceEventQueue is declared
+// with. Once it holds maxLength alerts RabbitMQ drops the oldest; 0 leaves it
+// unbounded.
+func geofenceEventQueueArgs(maxLength int) amqp.Table {
+	if maxLength <= 0 {
+		return nil
+	}
+	return amqp.Table{"x-max-length": int64(maxLength)}
+}
+
+// declareGeofenceEventQueue declares GeofenceEventQueue and binds it to
+// GeofenceEventBinding on AlertExchange
+func declareGeofenceEventQueue(ch alertChannel, maxLength int) error {
+	_, err := ch.QueueDeclare(
+		GeofenceEventQueue,
+		true,  // durable
+		false, // delete when unused
+		false, // exclusive
+		false, // no-wait
+		geofenceEventQueueArgs(maxLength),
+	)
+	if err != nil {
+		return err
+	}
+	return ch.QueueBind(GeofenceEventQueue, GeofenceEventBinding, AlertExchange, false, nil)
+}
+
+// DeclareGeofenceEventQueue declares GeofenceEventQueue bound to
+// GeofenceEventBinding and returns the channel to carry on with. RabbitMQ
+// refuses to redeclare a queue with other arguments, such as one created
+// before the length limit existed, and closes the channel; the existing queue
+// is then kept as it is, with a warning, and a new channel is returned.
+func DeclareGeofenceEventQueue(conn *amqp.Connection, ch *amqp.Channel, maxLength int) (*amqp.Channel, error) {
+	err := declareGeofenceEventQueue(ch, maxLength)
+	var amqpErr *amqp.Error
+	if !errors.As(err, &amqpErr) || amqpErr.Code != amqp.PreconditionFailed {
+		return ch, err
+	}
+
+	alertLog.Warn("Default alert queue exists with other arguments, keeping it as it is; delete it to apply the length limit",
+		logging.Queue(GeofenceEventQueue), slog.Int("max_length", maxLength), logging.Err(err))
+	if ch, err = conn.Channel(); err != nil {
+		return nil, err
+	}
+	if err := ch.QueueBind(GeofenceEventQueue, GeofenceEventBinding, AlertExchange, false, nil); err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+// GeofenceAlertRoutingKey returns the routing key of a geofence alert, e.g.
+// alert.geofence.exit.7 for a geofence_exit event on geofence 7
+func GeofenceAlertRoutingKey(eventType string, geofenceID int64) string {
+	return fmt.Sprintf("alert.geofence.%s.%d", strings.TrimPrefix(eventType, "geofence_"), geofenceID)
 }
 
 type RabbitMQService struct {
@@ -23,7 +118,9 @@ type RabbitMQService struct {
 	channel *amqp.Channel
 }
 
-func NewRabbitMQService(amqpURL string) (*RabbitMQService, error) {
+// NewRabbitMQService connects to RabbitMQ and declares AlertExchange, and
+// GeofenceEventQueue when alerts.DefaultQueue is set
+func NewRabbitMQService(amqpURL string, alerts config.AlertsConfig) (*RabbitMQService, error) {
 	conn, err := amqp.Dial(amqpURL)
 	if err != nil {
 		return nil, err
@@ -34,18 +131,14 @@ func NewRabbitMQService(amqpURL string) (*RabbitMQService, error) {
 		return nil, err
 	}
 
-	// Declare geofence events queue
-	_, err = ch.QueueDeclare(
-		"geofence.event",
-		true,  // durable
-		false, // delete when unused
-		false, // exclusive
-		false, // no-wait
-		nil,   // arguments
-	)
-	if err != nil {
+	if err := DeclareAlertExchange(ch); err != nil {
 		return nil, err
 	}
+	if alerts.DefaultQueue {
+		if ch, err = DeclareGeofenceEventQueue(conn, ch, alerts.DefaultQueueMaxLength); err != nil {
+			return nil, err
+		}
+	}
 
 	return &RabbitMQService{
 		conn:    conn,
@@ -53,49 +146,80 @@ func NewRabbitMQService(amqpURL string) (*RabbitMQService, error) {
 	}, nil
 }
 
-func (r *RabbitMQService) PublishGeofenceAlert(rdb *redis.Client, vehicleID string, latitude, longitude float64, eventType string) error {
-	alert := GeofenceAlert{
-		EventType: eventType,
-		VehicleID: vehicleID,
-		Latitude:  latitude,
-		Longitude: longitude,
-		Timestamp: time.Now().Unix(),
-	}
+// PublishGeofenceAlert publishes the event as a versioned model.GeofenceAlert,
+// with the trace context of ctx in the message headers
+func (r *RabbitMQService) PublishGeofenceAlert(ctx context.Context, q queue.Queue, event GeofenceEvent) (err error) {
+	alert := newGeofenceAlert(event, time.Now())
+	routingKey := GeofenceAlertRoutingKey(alert.EventType, alert.GeofenceID)
+
+	ctx, span := tracing.Tracer().Start(ctx, "PublishGeofenceAlert",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("messaging.destination.name", AlertExchange),
+			attribute.String("messaging.rabbitmq.destination.routing_key", routingKey)))
+	defer func() { tracing.End(span, err) }()
 
 	body, err := json.Marshal(alert)
 	if err != nil {
 		return err
 	}
 
-	err = r.channel.Publish(
-		"",               // default exchange
-		"geofence.event", // routing key
-		false,            // mandatory
-		false,            // immediate
+	headers := amqp.Table{"alert_version": int32(model.GeofenceAlertVersion)}
+	tracing.InjectAMQP(ctx, headers)
+	err = r.channel.PublishWithContext(
+		ctx,
+		AlertExchange,
+		routingKey,
+		false, // mandatory
+		false, // immediate
 		amqp.Publishing{
 			DeliveryMode: amqp.Persistent,
 			ContentType:  "application/json",
+			Type:         "geofence_alert",
+			Headers:      headers,
+			Timestamp:    alert.PublishedAt,
 			Body:         body,
 		},
 	)
 
 	if err != nil {
-		log.Printf("[RABBITMQ_SERVICE] ⚠️ Failed to publish geofence alert: %v", err)
+		metrics.RabbitMQPublishFailures.WithLabelValues(AlertExchange).Inc()
+		alertLog.ErrorContext(ctx, "Failed to publish geofence alert", logging.Queue(AlertExchange), logging.EventType(alert.EventType),
+			logging.VehicleID(alert.VehicleID), slog.Int64("geofence_id", alert.GeofenceID), logging.Err(err))
 		return err
 	}
 
-	log.Printf("[RABBITMQ_SERVICE] 📡 Published geofence alert: %s for vehicle %s at (%.4f, %.4f)",
-		eventType, vehicleID, latitude, longitude)
+	alertLog.InfoContext(ctx, "Published geofence alert", logging.Queue(AlertExchange), logging.EventType(alert.EventType),
+		logging.VehicleID(alert.VehicleID), slog.Int64("geofence_id", alert.GeofenceID),
+		slog.Float64("latitude", alert.Latitude), slog.Float64("longitude", alert.Longitude))
 	envelope := model.EventEnvelope{
-		EventType: eventType,
+		EventType: alert.EventType,
 		Source:    "geofence_service",
 		Payload:   json.RawMessage(body),
 		Timestamp: time.Now(),
 	}
-	sendEventToRedis(rdb, "event_log:queue", envelope)
+	sendEvent(ctx, q, EventLogTopic, envelope)
 	return nil
 }
 
+// newGeofenceAlert builds the alert for an event, stamped with the time of the
+// location that triggered it
+func newGeofenceAlert(event GeofenceEvent, publishedAt time.Time) model.GeofenceAlert {
+	located := observedAt(event.Location)
+	return model.GeofenceAlert{
+		Version:           model.GeofenceAlertVersion,
+		EventType:         event.EventType,
+		VehicleID:         event.VehicleID,
+		GeofenceID:        event.GeofenceID,
+		GeofenceName:      event.GeofenceName,
+		Latitude:          event.Location.Latitude,
+		Longitude:         event.Location.Longitude,
+		Speed:             event.Location.Speed,
+		Timestamp:         located.Unix(),
+		LocationTimestamp: located,
+		PublishedAt:       publishedAt,
+	}
+}
+
 func (r *RabbitMQService) Close() {
 	if r.channel != nil {
 		r.channel.Close()
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+)
+
+func TestNewGeofenceAlert(t *testing.T) {
+	located := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	published := located.Add(3 * time.Second)
+	event := GeofenceEvent{
+		VehicleID:    "BUS-001",
+		GeofenceID:   7,
+		GeofenceName: "Depot A",
+		EventType:    model.GeofenceEventExit,
+		Location: model.VehicleLocation{
+			VehicleID: "BUS-001", Latitude: -6.19, Longitude: 106.82, Speed: 42.5, Timestamp: located,
+		},
+	}
+
+	alert := newGeofenceAlert(event, published)
+	assert.Equal(t, model.GeofenceAlert{
+		Version:           model.GeofenceAlertVersion,
+		EventType:         model.GeofenceEventExit,
+		VehicleID:         "BUS-001",
+		GeofenceID:        7,
+		GeofenceName:      "Depot A",
+		Latitude:          -6.19,
+		Longitude:         106.82,
+		Speed:             42.5,
+		Timestamp:         located.Unix(),
+		LocationTimestamp: located,
+		PublishedAt:       published,
+	}, alert)
+}
+
+func TestGeofenceAlertRoutingKey(t *testing.T) {
+	assert.Equal(t, "alert.geofence.entry.7", GeofenceAlertRoutingKey(model.GeofenceEventEntry, 7))
+	assert.Equal(t, "alert.geofence.exit.7", GeofenceAlertRoutingKey(model.GeofenceEventExit, 7))
+	assert.Equal(t, "alert.geofence.dwell.12", GeofenceAlertRoutingKey(model.GeofenceEventDwell, 12))
+	assert.Equal(t, "alert.geofence.overdue.12", GeofenceAlertRoutingKey(model.GeofenceEventOverdue, 12))
+}
+
+// fakeAlertBroker records the topology declared on it and routes by topic
+type fakeAlertBroker struct {
+	exchanges map[string]string
+	queues    map[string]amqp.Table
+	bindings  map[string][]string // queue -> routing key patterns
+}
+
+func newFakeAlertBroker() *fakeAlertBroker {
+	return &fakeAlertBroker{exchanges: map[string]string{}, queues: map[string]amqp.Table{}, bindings: map[string][]string{}}
+}
+
+func (b *fakeAlertBroker) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error {
+	b.exchanges[name] = kind
+	return nil
+}
+
+func (b *fakeAlertBroker) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	if !durable {
+		return amqp.Queue{}, errors.New("fake broker only holds durable queues")
+	}
+	b.queues[name] = args
+	return amqp.Queue{Name: name}, nil
+}
+
+func (b *fakeAlertBroker) QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error {
+	if exchange == AlertExchange {
+		b.bindings[name] = append(b.bindings[name], key)
+	}
+	return nil
+}
+
+// route returns the queues a message published to AlertExchange with
+// routingKey reaches
+func (b *fakeAlertBroker) route(routingKey string) []string {
+	var routed []string
+	for name, patterns := range b.bindings {
+		for _, pattern := range patterns {
+			if topicMatches(strings.Split(pattern, "."), strings.Split(routingKey, ".")) {
+				routed = append(routed, name)
+				break
+			}
+		}
+	}
+	return routed
+}
+
+// topicMatches applies the topic exchange rules: * matches one word, # zero
+// or more
+func topicMatches(pattern, words []string) bool {
+	if len(pattern) == 0 {
+		return len(words) == 0
+	}
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(words); i++ {
+			if topicMatches(pattern[1:], words[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(words) > 0 && topicMatches(pattern[1:], words[1:])
+	default:
+		return len(words) > 0 && words[0] == pattern[0] && topicMatches(pattern[1:], words[1:])
+	}
+}
+
+func TestDeclareGeofenceEventQueueKeepsAlertsBeforeConsumersStart(t *testing.T) {
+	broker := newFakeAlertBroker()
+	require.NoError(t, DeclareAlertExchange(broker))
+	require.NoError(t, declareGeofenceEventQueue(broker, 1000))
+
+	assert.Equal(t, "topic", broker.exchanges[AlertExchange])
+	assert.Equal(t, amqp.Table{"x-max-length": int64(1000)}, broker.queues[GeofenceEventQueue])
+
+	// No consumer has declared a queue yet; every geofence alert still has
+	// somewhere to wait
+	for _, eventType := range []string{model.GeofenceEventEntry, model.GeofenceEventExit, model.GeofenceEventDwell, model.GeofenceEventOverdue} {
+		routingKey := GeofenceAlertRoutingKey(eventType, 7)
+		assert.Equal(t, []string{GeofenceEventQueue}, broker.route(routingKey), routingKey)
+	}
+	assert.Empty(t, broker.route("alert.vehicle.offline.7"))
+}
+
+func TestGeofenceEventQueueArgs(t *testing.T) {
+	assert.Equal(t, amqp.Table{"x-max-length": int64(100000)}, geofenceEventQueueArgs(100000))
+	assert.Nil(t, geofenceEventQueueArgs(0), "0 leaves the queue unbounded")
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// shardQueueSize is how many tasks each shard buffers before Submit blocks
+const shardQueueSize = 64
+
+// ShardPool runs tasks on a fixed number of goroutines. Tasks submitted with
+// the same key always run on the same goroutine, so they run one at a time in
+// submission order, while tasks for other keys run in parallel.
+type ShardPool struct {
+	shards []chan func()
+	wg     sync.WaitGroup
+}
+
+// NewShardPool starts size goroutines; sizes below 1 are treated as 1
+func NewShardPool(size int) *ShardPool {
+	p := &ShardPool{shards: make([]chan func(), max(size, 1))}
+	for i := range p.shards {
+		tasks := make(chan func(), shardQueueSize)
+		p.shards[i] = tasks
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for task := range tasks {
+				task()
+			}
+		}()
+	}
+	return p
+}
+
+// Submit queues task on the shard for key, blocking while that shard's queue
+// is full
+func (p *ShardPool) Submit(key string, task func()) {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	p.shards[h.Sum32()%uint32(len(p.shards))] <- task
+}
+
+// Close waits for queued tasks to finish and stops the goroutines. Submit
+// must not be called afterwards.
+func (p *ShardPool) Close() {
+	for _, tasks := range p.shards {
+		close(tasks)
+	}
+	p.wg.Wait()
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardPool_KeepsOrderPerKey(t *testing.T) {
+	pool := NewShardPool(4)
+
+	const keys, tasks = 20, 200
+	var mu sync.Mutex
+	seen := make(map[string][]int)
+	for i := 0; i < tasks; i++ {
+		for k := 0; k < keys; k++ {
+			key := fmt.Sprintf("BUS-%03d", k)
+			pool.Submit(key, func() {
+				mu.Lock()
+				seen[key] = append(seen[key], i)
+				mu.Unlock()
+			})
+		}
+	}
+	pool.Close()
+
+	require.Len(t, seen, keys)
+	for key, order := range seen {
+		require.Len(t, order, tasks, key)
+		for i, n := range order {
+			require.Equal(t, i, n, "%s ran out of order", key)
+		}
+	}
+}
+
+func TestShardPool_RunsShardsInParallel(t *testing.T) {
+	pool := NewShardPool(2)
+	defer pool.Close()
+
+	// Find two keys on different shards
+	shard := func(key string) uint32 {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		return h.Sum32() % 2
+	}
+	first, second := "BUS-000", ""
+	for i := 1; second == ""; i++ {
+		if key := fmt.Sprintf("BUS-%03d", i); shard(key) != shard(first) {
+			second = key
+		}
+	}
+
+	// Each task waits for the other, so they only finish if both run at once
+	var started sync.WaitGroup
+	started.Add(2)
+	done := make(chan struct{}, 2)
+	for _, key := range []string{first, second} {
+		pool.Submit(key, func() {
+			started.Done()
+			started.Wait()
+			done <- struct{}{}
+		})
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("tasks on different shards did not run in parallel")
+		}
+	}
+}
+
+func TestShardPool_MinimumSize(t *testing.T) {
+	pool := NewShardPool(0)
+	ran := false
+	pool.Submit("BUS-001", func() { ran = true })
+	pool.Close()
+	assert.True(t, ran)
+}
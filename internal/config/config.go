@@ -0,0 +1,426 @@
+// Package config loads the settings of every command into one typed Config.
+// Values come from built-in defaults, then an optional YAML or TOML file named
+// by CONFIG_FILE, then environment variables, each overriding the last.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/redis/go-redis/v9"
+	"gopkg.in/yaml.v3"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/queue"
+)
+
+// Config holds the settings of all commands; each uses the sections it needs
+type Config struct {
+	Postgres PostgresConfig `yaml:"postgres" toml:"postgres"`
+	Redis    RedisConfig    `yaml:"redis" toml:"redis"`
+	RabbitMQ RabbitMQConfig `yaml:"rabbitmq" toml:"rabbitmq"`
+	MQTT     MQTTConfig     `yaml:"mqtt" toml:"mqtt"`
+	API      APIConfig      `yaml:"api" toml:"api"`
+	Worker   WorkerConfig   `yaml:"worker" toml:"worker"`
+	Ingest   IngestConfig   `yaml:"ingest" toml:"ingest"`
+	Alerts   AlertsConfig   `yaml:"alerts" toml:"alerts"`
+	Metrics  MetricsConfig  `yaml:"metrics" toml:"metrics"`
+	Tracing  TracingConfig  `yaml:"tracing" toml:"tracing"`
+	Logging  LoggingConfig  `yaml:"logging" toml:"logging"`
+	// ShutdownTimeout is how long in-flight work gets to finish once a stop
+	// signal arrives
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+}
+
+type PostgresConfig struct {
+	// DSN, when set, is used as is instead of the individual fields
+	DSN      string `yaml:"dsn" toml:"dsn"`
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	User     string `yaml:"user" toml:"user"`
+	Password string `yaml:"password" toml:"password"`
+	Name     string `yaml:"name" toml:"name"`
+	SSLMode  string `yaml:"sslmode" toml:"sslmode"`
+	// LogLevel is the GORM log level: silent, error, warn or info
+	LogLevel string `yaml:"log_level" toml:"log_level"`
+}
+
+// ConnString returns DSN if set, otherwise a connection string built from the
+// individual fields
+func (c PostgresConfig) ConnString() string {
+	if c.DSN != "" {
+		return c.DSN
+	}
+	return fmt.Sprintf("host=%s user=%s port=%d password=%s dbname=%s sslmode=%s",
+		c.Host, c.User, c.Port, c.Password, c.Name, c.SSLMode)
+}
+
+type RedisConfig struct {
+	// Addr is host:port. Left empty, the API runs without Redis and the other
+	// commands connect to localhost:6379.
+	Addr     string `yaml:"addr" toml:"addr"`
+	Password string `yaml:"password" toml:"password"`
+	DB       int    `yaml:"db" toml:"db"`
+}
+
+// Options returns the client options for go-redis
+func (c RedisConfig) Options() *redis.Options {
+	return &redis.Options{Addr: c.Addr, Password: c.Password, DB: c.DB}
+}
+
+type RabbitMQConfig struct {
+	// URL, when set, is used as is instead of the individual fields
+	URL      string `yaml:"url" toml:"url"`
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	User     string `yaml:"user" toml:"user"`
+	Password string `yaml:"password" toml:"password"`
+}
+
+// ConnString returns URL if set, otherwise an AMQP URL built from the
+// individual fields
+func (c RabbitMQConfig) ConnString() string {
+	if c.URL != "" {
+		return c.URL
+	}
+	u := url.URL{
+		Scheme: "amqp",
+		User:   url.UserPassword(c.User, c.Password),
+		Host:   fmt.Sprintf("%s:%d", c.Host, c.Port),
+		Path:   "/",
+	}
+	return u.String()
+}
+
+type MQTTConfig struct {
+	// Broker is a host name, or a full URL such as tcp://mqtt:1883 that
+	// takes precedence over Port
+	Broker   string `yaml:"broker" toml:"broker"`
+	Port     int    `yaml:"port" toml:"port"`
+	ClientID string `yaml:"client_id" toml:"client_id"`
+	Username string `yaml:"username" toml:"username"`
+	Password string `yaml:"password" toml:"password"`
+	// Topic is subscribed to as is. The publisher replaces its first +
+	// wildcard with the vehicle ID.
+	Topic string `yaml:"topic" toml:"topic"`
+}
+
+// BrokerURL returns the URL to connect to
+func (c MQTTConfig) BrokerURL() string {
+	if strings.Contains(c.Broker, "://") {
+		return c.Broker
+	}
+	return fmt.Sprintf("tcp://%s:%d", c.Broker, c.Port)
+}
+
+type APIConfig struct {
+	Port int `yaml:"port" toml:"port"`
+}
+
+type WorkerConfig struct {
+	// ID names this process's consumers; empty means <hostname>-<pid>
+	ID string `yaml:"id" toml:"id"`
+	// GeofenceStateRedis shares per-vehicle geofence state through Redis
+	GeofenceStateRedis bool           `yaml:"geofence_state_redis" toml:"geofence_state_redis"`
+	EventLog           EventLogConfig `yaml:"event_log" toml:"event_log"`
+	Location           LocationConfig `yaml:"location" toml:"location"`
+}
+
+type EventLogConfig struct {
+	Retry RetryConfig `yaml:"retry" toml:"retry"`
+}
+
+type LocationConfig struct {
+	Retry RetryConfig `yaml:"retry" toml:"retry"`
+	// BatchSize is the most locations saved in one batch
+	BatchSize int `yaml:"batch_size" toml:"batch_size"`
+	// BatchWait is how long to keep filling a batch after its first location
+	BatchWait time.Duration `yaml:"batch_wait" toml:"batch_wait"`
+	// Concurrency is the number of geofence detection goroutines; 0 means
+	// one per CPU
+	Concurrency int `yaml:"concurrency" toml:"concurrency"`
+}
+
+// BatchPolicy returns the batching settings as a queue.BatchPolicy
+func (c LocationConfig) BatchPolicy() queue.BatchPolicy {
+	return queue.BatchPolicy{Size: c.BatchSize, Wait: c.BatchWait}
+}
+
+// RetryConfig mirrors queue.RetryPolicy
+type RetryConfig struct {
+	MaxAttempts int           `yaml:"max_attempts" toml:"max_attempts"`
+	BaseDelay   time.Duration `yaml:"base_delay" toml:"base_delay"`
+	MaxDelay    time.Duration `yaml:"max_delay" toml:"max_delay"`
+	Jitter      float64       `yaml:"jitter" toml:"jitter"`
+}
+
+func (r RetryConfig) Policy() queue.RetryPolicy {
+	return queue.RetryPolicy{
+		MaxAttempts: r.MaxAttempts,
+		BaseDelay:   r.BaseDelay,
+		MaxDelay:    r.MaxDelay,
+		Jitter:      r.Jitter,
+	}
+}
+
+// IngestConfig sets what the subscriber accepts from MQTT. Updates that fail
+// these checks are quarantined instead of queued for the workers.
+type IngestConfig struct {
+	// VehicleIDPattern is the regular expression a vehicle ID must match
+	VehicleIDPattern string `yaml:"vehicle_id_pattern" toml:"vehicle_id_pattern"`
+	// MaxClockSkew is how far past the time of receipt a timestamp may be
+	MaxClockSkew time.Duration `yaml:"max_clock_skew" toml:"max_clock_skew"`
+	// MaxAge is how old a timestamp may be, allowing for trackers that
+	// buffer updates while offline
+	MaxAge time.Duration `yaml:"max_age" toml:"max_age"`
+}
+
+// AlertsConfig selects what cmd/rabbitmq_consumer consumes, and whether
+// publishers keep the default geofence alert queue
+type AlertsConfig struct {
+	// Queue is the queue to consume; empty for a temporary queue
+	Queue string `yaml:"queue" toml:"queue"`
+	// Bindings is a comma-separated list of routing key patterns
+	Bindings string `yaml:"bindings" toml:"bindings"`
+	// DefaultQueue makes publishers declare the geofence.event queue, so
+	// alerts are kept until a consumer reads them. Turn it off when every
+	// consumer uses its own queue, or nothing drains it.
+	DefaultQueue bool `yaml:"default_queue" toml:"default_queue"`
+	// DefaultQueueMaxLength caps geofence.event; the oldest alerts are
+	// dropped beyond it. 0 means no limit.
+	DefaultQueueMaxLength int `yaml:"default_queue_max_length" toml:"default_queue_max_length"`
+}
+
+type MetricsConfig struct {
+	// Addr is where the worker, subscriber and alert consumer serve
+	// /metrics; empty disables it. The API serves /metrics on its own port.
+	Addr string `yaml:"addr" toml:"addr"`
+}
+
+type TracingConfig struct {
+	// Exporter is where spans go: none, stdout, file or otlp
+	Exporter string `yaml:"exporter" toml:"exporter"`
+	// File is the path the file exporter appends spans to
+	File string `yaml:"file" toml:"file"`
+	// Endpoint is the host:port of the OTLP/HTTP collector
+	Endpoint string `yaml:"endpoint" toml:"endpoint"`
+	// Insecure sends OTLP over plain HTTP instead of HTTPS
+	Insecure bool `yaml:"insecure" toml:"insecure"`
+	// SampleRatio is the fraction of new traces recorded. Traces started
+	// upstream follow the caller's decision.
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+type LoggingConfig struct {
+	// Level is the lowest level logged: debug, info, warn or error
+	Level string `yaml:"level" toml:"level"`
+	// Format is json or text
+	Format string `yaml:"format" toml:"format"`
+}
+
+// Default returns the configuration used when nothing overrides it
+func Default() Config {
+	return Config{
+		Postgres: PostgresConfig{
+			Host:     "localhost",
+			Port:     5432,
+			User:     "admin",
+			Name:     "vehicle_tracker",
+			SSLMode:  "disable",
+			LogLevel: "warn",
+		},
+		RabbitMQ: RabbitMQConfig{
+			Host: "localhost",
+			Port: 5672,
+			User: "guest",
+			// The RabbitMQ default account, which only accepts local connections
+			Password: "guest",
+		},
+		MQTT: MQTTConfig{
+			Broker:   "localhost",
+			Port:     1883,
+			ClientID: "vehicle-tracker-client",
+			Topic:    "fleet/vehicle/+/location",
+		},
+		API: APIConfig{Port: 8080},
+		Worker: WorkerConfig{
+			EventLog: EventLogConfig{Retry: defaultRetry},
+			Location: LocationConfig{
+				Retry:     defaultRetry,
+				BatchSize: queue.DefaultBatchPolicy.Size,
+				BatchWait: queue.DefaultBatchPolicy.Wait,
+			},
+		},
+		Ingest: IngestConfig{
+			VehicleIDPattern: `^[A-Za-z0-9_-]{1,64}$`,
+			MaxClockSkew:     5 * time.Minute,
+			MaxAge:           7 * 24 * time.Hour,
+		},
+		Alerts: AlertsConfig{
+			Queue:                 "geofence.event",
+			Bindings:              "alert.geofence.#",
+			DefaultQueue:          true,
+			DefaultQueueMaxLength: 100000,
+		},
+		Metrics: MetricsConfig{Addr: ":2112"},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			File:        "traces.json",
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			SampleRatio: 1,
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		ShutdownTimeout: 30 * time.Second,
+	}
+}
+
+var defaultRetry = RetryConfig{
+	MaxAttempts: queue.DefaultRetryPolicy.MaxAttempts,
+	BaseDelay:   queue.DefaultRetryPolicy.BaseDelay,
+	MaxDelay:    queue.DefaultRetryPolicy.MaxDelay,
+	Jitter:      queue.DefaultRetryPolicy.Jitter,
+}
+
+// Load reads a .env file if there is one, then builds and validates the
+// configuration
+func Load() (Config, error) {
+	godotenv.Load()
+
+	cfg := Default()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+	if err := applyEnv(&cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// loadFile decodes a YAML or TOML file, chosen by its extension, over cfg
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".toml":
+		// Decode through YAML, which parses durations such as "30s"
+		var values map[string]any
+		if err = toml.Unmarshal(data, &values); err == nil {
+			data, _ = yaml.Marshal(values)
+			err = yaml.Unmarshal(data, cfg)
+		}
+	default:
+		return fmt.Errorf("config file %s must end in .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	if c.Postgres.DSN == "" {
+		check(c.Postgres.Host != "", "postgres host is required")
+		check(validPort(c.Postgres.Port), "postgres port %d is out of range", c.Postgres.Port)
+		check(c.Postgres.User != "", "postgres user is required")
+		check(c.Postgres.Name != "", "postgres database name is required")
+	}
+	switch c.Postgres.LogLevel {
+	case "silent", "error", "warn", "info":
+	default:
+		errs = append(errs, fmt.Errorf("postgres log level %q must be silent, error, warn or info", c.Postgres.LogLevel))
+	}
+	check(c.Redis.DB >= 0, "redis db must not be negative")
+
+	if c.RabbitMQ.URL != "" {
+		u, err := url.Parse(c.RabbitMQ.URL)
+		check(err == nil && (u.Scheme == "amqp" || u.Scheme == "amqps"), "rabbitmq url must be an amqp:// or amqps:// URL")
+	} else {
+		check(c.RabbitMQ.Host != "", "rabbitmq host is required")
+		check(validPort(c.RabbitMQ.Port), "rabbitmq port %d is out of range", c.RabbitMQ.Port)
+	}
+
+	check(c.MQTT.Broker != "", "mqtt broker is required")
+	check(strings.Contains(c.MQTT.Broker, "://") || validPort(c.MQTT.Port), "mqtt port %d is out of range", c.MQTT.Port)
+	check(c.MQTT.Topic != "", "mqtt topic is required")
+	check(validPort(c.API.Port), "api port %d is out of range", c.API.Port)
+
+	errs = append(errs, c.Worker.EventLog.Retry.validate("worker event log retry")...)
+	errs = append(errs, c.Worker.Location.Retry.validate("worker location retry")...)
+	check(c.Worker.Location.BatchSize > 0, "worker location batch size must be positive")
+	check(c.Worker.Location.BatchWait >= 0, "worker location batch wait must not be negative")
+	check(c.Worker.Location.Concurrency >= 0, "worker location concurrency must not be negative")
+
+	_, err := regexp.Compile(c.Ingest.VehicleIDPattern)
+	check(c.Ingest.VehicleIDPattern != "" && err == nil, "ingest vehicle id pattern %q must be a valid regular expression", c.Ingest.VehicleIDPattern)
+	check(c.Ingest.MaxClockSkew >= 0, "ingest max clock skew must not be negative")
+	check(c.Ingest.MaxAge > 0, "ingest max age must be positive")
+	check(c.Alerts.DefaultQueueMaxLength >= 0, "alerts default queue max length must not be negative")
+
+	if c.Metrics.Addr != "" {
+		_, port, err := net.SplitHostPort(c.Metrics.Addr)
+		check(err == nil && port != "", "metrics addr %q must be host:port or :port", c.Metrics.Addr)
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	case "file":
+		check(c.Tracing.File != "", "tracing file is required by the file exporter")
+	default:
+		errs = append(errs, fmt.Errorf("tracing exporter %q must be none, stdout, file or otlp", c.Tracing.Exporter))
+	}
+	check(c.Tracing.Exporter != "otlp" || c.Tracing.Endpoint != "", "tracing endpoint is required by the otlp exporter")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing sample ratio must be between 0 and 1")
+
+	if _, err := logging.ParseLevel(c.Logging.Level); err != nil {
+		errs = append(errs, err)
+	}
+	check(c.Logging.Format == "json" || c.Logging.Format == "text", "log format %q must be json or text", c.Logging.Format)
+
+	check(c.ShutdownTimeout > 0, "shutdown timeout must be positive")
+	return errors.Join(errs...)
+}
+
+func (r RetryConfig) validate(name string) []error {
+	var errs []error
+	if r.MaxAttempts < 0 {
+		errs = append(errs, fmt.Errorf("%s max attempts must not be negative", name))
+	}
+	if r.BaseDelay < 0 || r.MaxDelay < 0 {
+		errs = append(errs, fmt.Errorf("%s delays must not be negative", name))
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		errs = append(errs, fmt.Errorf("%s jitter must be between 0 and 1", name))
+	}
+	return errs
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
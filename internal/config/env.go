@@ -0,0 +1,143 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// applyEnv overrides cfg with the environment variables that are set.
+// Variables set to an empty string are ignored, except ALERT_QUEUE where
+// empty selects a temporary queue and METRICS_ADDR where empty disables the
+// metrics server.
+func applyEnv(cfg *Config) error {
+	e := &envReader{}
+
+	e.string("DB_DSN", &cfg.Postgres.DSN)
+	e.string("POSTGRES_HOST", &cfg.Postgres.Host)
+	e.int("POSTGRES_PORT", &cfg.Postgres.Port)
+	e.string("POSTGRES_USER", &cfg.Postgres.User)
+	e.string("POSTGRES_PASSWORD", &cfg.Postgres.Password)
+	e.string("POSTGRES_DB", &cfg.Postgres.Name)
+	e.string("POSTGRES_SSLMODE", &cfg.Postgres.SSLMode)
+	e.string("POSTGRES_LOG_LEVEL", &cfg.Postgres.LogLevel)
+
+	e.string("REDIS_ADDR", &cfg.Redis.Addr)
+	e.string("REDIS_PASSWORD", &cfg.Redis.Password)
+	e.int("REDIS_DB", &cfg.Redis.DB)
+
+	e.string("RABBITMQ_URL", &cfg.RabbitMQ.URL)
+	e.string("RABBITMQ_HOST", &cfg.RabbitMQ.Host)
+	e.int("RABBITMQ_PORT", &cfg.RabbitMQ.Port)
+	e.string("RABBITMQ_USER", &cfg.RabbitMQ.User)
+	e.string("RABBITMQ_PASSWORD", &cfg.RabbitMQ.Password)
+
+	e.string("MQTT_BROKER", &cfg.MQTT.Broker)
+	e.int("MQTT_PORT", &cfg.MQTT.Port)
+	e.string("MQTT_CLIENT_ID", &cfg.MQTT.ClientID)
+	e.string("MQTT_USERNAME", &cfg.MQTT.Username)
+	e.string("MQTT_PASSWORD", &cfg.MQTT.Password)
+	e.string("MQTT_TOPIC", &cfg.MQTT.Topic)
+
+	e.int("PORT", &cfg.API.Port)
+
+	e.string("WORKER_ID", &cfg.Worker.ID)
+	e.bool("GEOFENCE_STATE_REDIS", &cfg.Worker.GeofenceStateRedis)
+	e.retry("EVENTLOG", &cfg.Worker.EventLog.Retry)
+	e.retry("LOCATION", &cfg.Worker.Location.Retry)
+	e.int("LOCATION_BATCH_SIZE", &cfg.Worker.Location.BatchSize)
+	e.duration("LOCATION_BATCH_WAIT", &cfg.Worker.Location.BatchWait)
+	e.int("LOCATION_CONCURRENCY", &cfg.Worker.Location.Concurrency)
+
+	e.string("INGEST_VEHICLE_ID_PATTERN", &cfg.Ingest.VehicleIDPattern)
+	e.duration("INGEST_MAX_CLOCK_SKEW", &cfg.Ingest.MaxClockSkew)
+	e.duration("INGEST_MAX_AGE", &cfg.Ingest.MaxAge)
+
+	if v, ok := os.LookupEnv("ALERT_QUEUE"); ok {
+		cfg.Alerts.Queue = v
+	}
+	e.string("ALERT_BINDINGS", &cfg.Alerts.Bindings)
+	e.bool("ALERT_DEFAULT_QUEUE", &cfg.Alerts.DefaultQueue)
+	e.int("ALERT_DEFAULT_QUEUE_MAX_LENGTH", &cfg.Alerts.DefaultQueueMaxLength)
+
+	if v, ok := os.LookupEnv("METRICS_ADDR"); ok {
+		cfg.Metrics.Addr = v
+	}
+
+	e.string("TRACING_EXPORTER", &cfg.Tracing.Exporter)
+	e.string("TRACING_FILE", &cfg.Tracing.File)
+	e.string("TRACING_ENDPOINT", &cfg.Tracing.Endpoint)
+	e.bool("TRACING_INSECURE", &cfg.Tracing.Insecure)
+	e.float("TRACING_SAMPLE_RATIO", &cfg.Tracing.SampleRatio)
+
+	e.string("LOG_LEVEL", &cfg.Logging.Level)
+	e.string("LOG_FORMAT", &cfg.Logging.Format)
+
+	e.duration("SHUTDOWN_TIMEOUT", &cfg.ShutdownTimeout)
+	return errors.Join(e.errs...)
+}
+
+// envReader parses environment variables into config fields, collecting
+// parse errors so they can all be reported together
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) string(key string, dst *string) {
+	if v := os.Getenv(key); v != "" {
+		*dst = v
+	}
+}
+
+func (e *envReader) int(key string, dst *int) {
+	e.parse(key, func(v string) error {
+		n, err := strconv.Atoi(v)
+		*dst = n
+		return err
+	})
+}
+
+func (e *envReader) bool(key string, dst *bool) {
+	e.parse(key, func(v string) error {
+		b, err := strconv.ParseBool(v)
+		*dst = b
+		return err
+	})
+}
+
+func (e *envReader) float(key string, dst *float64) {
+	e.parse(key, func(v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		*dst = f
+		return err
+	})
+}
+
+func (e *envReader) duration(key string, dst *time.Duration) {
+	e.parse(key, func(v string) error {
+		d, err := time.ParseDuration(v)
+		*dst = d
+		return err
+	})
+}
+
+// retry reads the <prefix>_RETRY_MAX_ATTEMPTS, _BASE_DELAY, _MAX_DELAY and
+// _JITTER variables
+func (e *envReader) retry(prefix string, dst *RetryConfig) {
+	e.int(prefix+"_RETRY_MAX_ATTEMPTS", &dst.MaxAttempts)
+	e.duration(prefix+"_RETRY_BASE_DELAY", &dst.BaseDelay)
+	e.duration(prefix+"_RETRY_MAX_DELAY", &dst.MaxDelay)
+	e.float(prefix+"_RETRY_JITTER", &dst.Jitter)
+}
+
+func (e *envReader) parse(key string, set func(string) error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return
+	}
+	if err := set(v); err != nil {
+		e.errs = append(e.errs, fmt.Errorf("invalid %s %q: %w", key, v, err))
+	}
+}
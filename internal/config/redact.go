@@ -0,0 +1,66 @@
+package config
+
+import (
+	"log/slog"
+	"net/url"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces secrets in logged configuration
+const redacted = "REDACTED"
+
+// dsnPassword matches the password of a key=value Postgres connection string
+var dsnPassword = regexp.MustCompile(`(password=)\S*`)
+
+// Redacted returns a copy of c with passwords removed, including those inside
+// connection strings, so that it can be logged
+func (c Config) Redacted() Config {
+	c.Postgres.Password = redactSecret(c.Postgres.Password)
+	c.Postgres.DSN = redactConnString(c.Postgres.DSN)
+	c.Redis.Password = redactSecret(c.Redis.Password)
+	c.RabbitMQ.Password = redactSecret(c.RabbitMQ.Password)
+	c.RabbitMQ.URL = redactConnString(c.RabbitMQ.URL)
+	c.MQTT.Password = redactSecret(c.MQTT.Password)
+	return c
+}
+
+// String formats the redacted configuration as YAML
+func (c Config) String() string {
+	out, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return "<invalid config: " + err.Error() + ">"
+	}
+	return string(out)
+}
+
+// loggedConfig has Config's fields but not its methods, so slog logs it as is
+// instead of resolving LogValue again
+type loggedConfig Config
+
+// LogValue logs the redacted configuration, so that passing c to slog never
+// writes its secrets
+func (c Config) LogValue() slog.Value {
+	return slog.AnyValue(loggedConfig(c.Redacted()))
+}
+
+func redactSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
+
+// redactConnString removes the password from a URL or key=value connection
+// string
+func redactConnString(conn string) string {
+	if u, err := url.Parse(conn); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+			return u.String()
+		}
+		return conn
+	}
+	return dsnPassword.ReplaceAllString(conn, "${1}"+redacted)
+}
@@ -1,30 +1,99 @@
 package db
 
 import (
-	"fmt"
-	"log"
-	"os"
+	"errors"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
-)
-
-func ConnectGorm() *gorm.DB {
-	host := os.Getenv("POSTGRES_HOST")
-	user := os.Getenv("POSTGRES_USER")
-	port := os.Getenv("POSTGRES_PORT")
-	password := os.Getenv("POSTGRES_PASSWORD")
-	dbname := os.Getenv("POSTGRES_DB")
 
-	dsn := fmt.Sprintf("host=%s user=%s port=%s password=%s dbname=%s sslmode=disable",
-		host, user, port, password, dbname)
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/config"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/metrics"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+// ConnectGorm opens the database described by cfg, exiting if it can't
+func ConnectGorm(cfg config.PostgresConfig) *gorm.DB {
+	db, err := gorm.Open(postgres.Open(cfg.ConnString()), &gorm.Config{
+		Logger: newGormLogger(logLevel(cfg.LogLevel)),
 	})
 	if err != nil {
-		log.Fatalf("[DATABASE] Failed to connect to database: %v", err)
+		logging.Fatal(dbLog, "Failed to connect to database", logging.Err(err))
+	}
+	if err := registerMetrics(db); err != nil {
+		logging.Fatal(dbLog, "Failed to register metrics callbacks", logging.Err(err))
+	}
+	if err := registerTracing(db); err != nil {
+		logging.Fatal(dbLog, "Failed to register tracing callbacks", logging.Err(err))
 	}
 	return db
 }
+
+// registerMetrics counts failed inserts by table
+func registerMetrics(db *gorm.DB) error {
+	return db.Callback().Create().After("gorm:create").Register("metrics:insert_failures", func(tx *gorm.DB) {
+		if tx.Error != nil {
+			metrics.DBInsertFailures.WithLabelValues(tx.Statement.Table).Inc()
+		}
+	})
+}
+
+// spanKey stores the span of a statement between its tracing callbacks
+const spanKey = "tracing:span"
+
+// registerTracing wraps inserts and queries in spans under the statement's
+// context
+func registerTracing(db *gorm.DB) error {
+	create, query := db.Callback().Create(), db.Callback().Query()
+	return errors.Join(
+		create.Before("gorm:create").Register("tracing:before_create", startSpan("INSERT")),
+		create.After("gorm:create").Register("tracing:after_create", endSpan),
+		query.Before("gorm:query").Register("tracing:before_query", startSpan("SELECT")),
+		query.After("gorm:query").Register("tracing:after_query", endSpan),
+	)
+}
+
+func startSpan(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		_, span := tracing.Tracer().Start(tx.Statement.Context, operation+" "+tx.Statement.Table,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", "postgresql"),
+				attribute.String("db.collection.name", tx.Statement.Table),
+			))
+		tx.InstanceSet(spanKey, span)
+	}
+}
+
+func endSpan(tx *gorm.DB) {
+	if v, ok := tx.InstanceGet(spanKey); ok {
+		span := v.(trace.Span)
+		span.SetAttributes(attribute.Int64("db.rows_affected", tx.RowsAffected))
+		tracing.End(span, tx.Error)
+	}
+}
+
+func logLevel(level string) logger.LogLevel {
+	switch level {
+	case "silent":
+		return logger.Silent
+	case "error":
+		return logger.Error
+	case "info":
+		return logger.Info
+	default:
+		return logger.Warn
+	}
+}
+
+// Close closes the connection pool behind db
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
+)
+
+// slowQueryThreshold is how long a statement runs before it is logged as slow
+const slowQueryThreshold = 200 * time.Millisecond
+
+var dbLog = logging.Component("database")
+
+// gormLogger writes GORM's logs through slog. Statements are logged with the
+// context they ran under, so they carry the request ID and trace ID of the
+// call that issued them.
+type gormLogger struct {
+	level logger.LogLevel
+}
+
+func newGormLogger(level logger.LogLevel) logger.Interface {
+	return gormLogger{level: level}
+}
+
+func (l gormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return gormLogger{level: level}
+}
+
+func (l gormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Info {
+		dbLog.InfoContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l gormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Warn {
+		dbLog.WarnContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l gormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Error {
+		dbLog.ErrorContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Trace logs failed statements at error, slow ones at warn and, at the info
+// level, every statement. Missing records are not failures.
+func (l gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && l.level >= logger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		dbLog.ErrorContext(ctx, "Query failed", slog.String("sql", sql), slog.Int64("rows", rows), slog.Duration("duration", elapsed), logging.Err(err))
+	case elapsed > slowQueryThreshold && l.level >= logger.Warn:
+		sql, rows := fc()
+		dbLog.WarnContext(ctx, "Slow query", slog.String("sql", sql), slog.Int64("rows", rows), slog.Duration("duration", elapsed))
+	case l.level >= logger.Info:
+		sql, rows := fc()
+		dbLog.InfoContext(ctx, "Query", slog.String("sql", sql), slog.Int64("rows", rows), slog.Duration("duration", elapsed))
+	}
+}
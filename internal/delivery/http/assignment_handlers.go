@@ -0,0 +1,345 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/repository"
+)
+
+// AssignmentHandler manages vehicle groups and the assignment of geofences to
+// vehicles and groups
+type AssignmentHandler struct {
+	groupRepo      repository.VehicleGroupRepository
+	assignmentRepo repository.GeofenceAssignmentRepository
+	geofenceRepo   repository.GeofenceRepository
+	notifier       GeofenceChangeNotifier
+}
+
+// NewAssignmentHandler creates the handler. notifier may be nil, in which case
+// workers pick up changes on their next periodic refresh.
+func NewAssignmentHandler(groupRepo repository.VehicleGroupRepository, assignmentRepo repository.GeofenceAssignmentRepository, geofenceRepo repository.GeofenceRepository, notifier GeofenceChangeNotifier) *AssignmentHandler {
+	return &AssignmentHandler{
+		groupRepo:      groupRepo,
+		assignmentRepo: assignmentRepo,
+		geofenceRepo:   geofenceRepo,
+		notifier:       notifier,
+	}
+}
+
+// CreateVehicleGroup godoc
+// @Summary      Create vehicle group
+// @Description  Create a named group of vehicles that geofences can be assigned to
+// @Tags         vehicle-groups
+// @Accept       json
+// @Produce      json
+// @Param        group body VehicleGroupRequest true "Vehicle group"
+// @Success      201  {object}  model.VehicleGroup
+// @Failure      400  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Router       /vehicle-groups [post]
+func (h *AssignmentHandler) CreateVehicleGroup(c *gin.Context) {
+	var req VehicleGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseBadRequest(c, "invalid request body")
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		ResponseBadRequest(c, "name is required")
+		return
+	}
+
+	group := &model.VehicleGroup{Name: name}
+	if err := h.groupRepo.InsertVehicleGroup(c.Request.Context(), group); err != nil {
+		if errors.Is(err, repository.ErrVehicleGroupExists) {
+			ResponseError(c, http.StatusConflict, "vehicle group already exists")
+			return
+		}
+		ResponseError(c, http.StatusInternalServerError, "failed to create vehicle group")
+		return
+	}
+
+	ResponseCreated(c, group)
+}
+
+// ListVehicleGroups godoc
+// @Summary      List vehicle groups
+// @Tags         vehicle-groups
+// @Produce      json
+// @Success      200  {array}   model.VehicleGroup
+// @Failure      500  {object}  ErrorResponse
+// @Router       /vehicle-groups [get]
+func (h *AssignmentHandler) ListVehicleGroups(c *gin.Context) {
+	groups, err := h.groupRepo.ListVehicleGroups(c.Request.Context())
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "failed to list vehicle groups")
+		return
+	}
+	ResponseSuccess(c, groups)
+}
+
+// DeleteVehicleGroup godoc
+// @Summary      Delete vehicle group
+// @Description  Delete a group along with its memberships. A group that is still assigned to geofences can't be deleted.
+// @Tags         vehicle-groups
+// @Produce      json
+// @Param        id path int true "Vehicle group ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Router       /vehicle-groups/{id} [delete]
+func (h *AssignmentHandler) DeleteVehicleGroup(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "invalid vehicle group id")
+	if !ok {
+		return
+	}
+
+	if err := h.groupRepo.DeleteVehicleGroup(c.Request.Context(), id); err != nil {
+		respondAssignmentError(c, err, "failed to delete vehicle group")
+		return
+	}
+	h.notifyChanged(c.Request.Context())
+
+	ResponseSuccess(c, gin.H{
+		"id":      id,
+		"deleted": true,
+	})
+}
+
+// ListGroupVehicles godoc
+// @Summary      List group vehicles
+// @Tags         vehicle-groups
+// @Produce      json
+// @Param        id path int true "Vehicle group ID"
+// @Success      200  {array}   string
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /vehicle-groups/{id}/vehicles [get]
+func (h *AssignmentHandler) ListGroupVehicles(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "invalid vehicle group id")
+	if !ok {
+		return
+	}
+
+	vehicleIDs, err := h.groupRepo.ListGroupVehicles(c.Request.Context(), id)
+	if err != nil {
+		respondAssignmentError(c, err, "failed to list group vehicles")
+		return
+	}
+	ResponseSuccess(c, vehicleIDs)
+}
+
+// AddGroupVehicle godoc
+// @Summary      Add vehicle to group
+// @Tags         vehicle-groups
+// @Produce      json
+// @Param        id path int true "Vehicle group ID"
+// @Param        vehicle_id path string true "Vehicle ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /vehicle-groups/{id}/vehicles/{vehicle_id} [put]
+func (h *AssignmentHandler) AddGroupVehicle(c *gin.Context) {
+	h.setGroupVehicle(c, true)
+}
+
+// RemoveGroupVehicle godoc
+// @Summary      Remove vehicle from group
+// @Tags         vehicle-groups
+// @Produce      json
+// @Param        id path int true "Vehicle group ID"
+// @Param        vehicle_id path string true "Vehicle ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /vehicle-groups/{id}/vehicles/{vehicle_id} [delete]
+func (h *AssignmentHandler) RemoveGroupVehicle(c *gin.Context) {
+	h.setGroupVehicle(c, false)
+}
+
+// ListGeofenceAssignments godoc
+// @Summary      List geofence assignments
+// @Description  List the vehicles and groups a geofence is restricted to. A geofence without assignments applies to every vehicle.
+// @Tags         geofences
+// @Produce      json
+// @Param        id path int true "Geofence ID"
+// @Success      200  {array}   model.GeofenceAssignment
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /geofences/{id}/assignments [get]
+func (h *AssignmentHandler) ListGeofenceAssignments(c *gin.Context) {
+	geofenceID, ok := h.existingGeofenceID(c)
+	if !ok {
+		return
+	}
+
+	assignments, err := h.assignmentRepo.ListGeofenceAssignments(c.Request.Context(), geofenceID)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "failed to list geofence assignments")
+		return
+	}
+	ResponseSuccess(c, assignments)
+}
+
+// CreateGeofenceAssignment godoc
+// @Summary      Assign geofence
+// @Description  Restrict a geofence to a vehicle or a vehicle group. Exactly one of vehicle_id and group_id must be set.
+// @Tags         geofences
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "Geofence ID"
+// @Param        assignment body GeofenceAssignmentRequest true "Assignment"
+// @Success      201  {object}  model.GeofenceAssignment
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /geofences/{id}/assignments [post]
+func (h *AssignmentHandler) CreateGeofenceAssignment(c *gin.Context) {
+	geofenceID, ok := h.existingGeofenceID(c)
+	if !ok {
+		return
+	}
+
+	var req GeofenceAssignmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseBadRequest(c, "invalid request body")
+		return
+	}
+	vehicleID := strings.TrimSpace(req.VehicleID)
+	if (vehicleID == "") == (req.GroupID == 0) {
+		ResponseBadRequest(c, "exactly one of vehicle_id and group_id is required")
+		return
+	}
+
+	assignment := &model.GeofenceAssignment{GeofenceID: geofenceID}
+	if vehicleID != "" {
+		assignment.VehicleID = &vehicleID
+	} else {
+		assignment.GroupID = &req.GroupID
+	}
+	if err := h.assignmentRepo.InsertGeofenceAssignment(c.Request.Context(), assignment); err != nil {
+		respondAssignmentError(c, err, "failed to assign geofence")
+		return
+	}
+	h.notifyGeofenceChanged(c.Request.Context(), geofenceID)
+
+	ResponseCreated(c, assignment)
+}
+
+// DeleteGeofenceAssignment godoc
+// @Summary      Delete geofence assignment
+// @Tags         geofences
+// @Produce      json
+// @Param        id path int true "Geofence ID"
+// @Param        assignment_id path int true "Assignment ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /geofences/{id}/assignments/{assignment_id} [delete]
+func (h *AssignmentHandler) DeleteGeofenceAssignment(c *gin.Context) {
+	geofenceID, ok := parseGeofenceID(c)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "assignment_id", "invalid assignment id")
+	if !ok {
+		return
+	}
+
+	if err := h.assignmentRepo.DeleteGeofenceAssignment(c.Request.Context(), geofenceID, id); err != nil {
+		respondAssignmentError(c, err, "failed to delete geofence assignment")
+		return
+	}
+	h.notifyGeofenceChanged(c.Request.Context(), geofenceID)
+
+	ResponseSuccess(c, gin.H{
+		"id":      id,
+		"deleted": true,
+	})
+}
+
+func (h *AssignmentHandler) setGroupVehicle(c *gin.Context, member bool) {
+	id, ok := parseIDParam(c, "id", "invalid vehicle group id")
+	if !ok {
+		return
+	}
+	vehicleID := strings.TrimSpace(c.Param("vehicle_id"))
+	if vehicleID == "" {
+		ResponseBadRequest(c, "vehicle_id is required")
+		return
+	}
+
+	var err error
+	if member {
+		err = h.groupRepo.AddGroupVehicle(c.Request.Context(), id, vehicleID)
+	} else {
+		err = h.groupRepo.RemoveGroupVehicle(c.Request.Context(), id, vehicleID)
+	}
+	if err != nil {
+		respondAssignmentError(c, err, "failed to update vehicle group")
+		return
+	}
+	h.notifyChanged(c.Request.Context())
+
+	ResponseSuccess(c, gin.H{
+		"group_id":   id,
+		"vehicle_id": vehicleID,
+		"member":     member,
+	})
+}
+
+// existingGeofenceID parses the geofence ID and responds 404 if it doesn't exist
+func (h *AssignmentHandler) existingGeofenceID(c *gin.Context) (int64, bool) {
+	id, ok := parseGeofenceID(c)
+	if !ok {
+		return 0, false
+	}
+	if _, err := h.geofenceRepo.GetGeofence(c.Request.Context(), id); err != nil {
+		respondAssignmentError(c, err, "failed to get geofence")
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *AssignmentHandler) notifyGeofenceChanged(ctx context.Context, id int64) {
+	if h.notifier != nil {
+		h.notifier.NotifyGeofenceChanged(ctx, id)
+	}
+}
+
+// notifyChanged announces group changes, which may affect any geofence
+func (h *AssignmentHandler) notifyChanged(ctx context.Context) {
+	h.notifyGeofenceChanged(ctx, 0)
+}
+
+func respondAssignmentError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrGeofenceNotFound):
+		ResponseNotFound(c, "geofence not found")
+	case errors.Is(err, repository.ErrVehicleGroupNotFound):
+		ResponseNotFound(c, "vehicle group not found")
+	case errors.Is(err, repository.ErrAssignmentNotFound):
+		ResponseNotFound(c, "geofence assignment not found")
+	case errors.Is(err, repository.ErrVehicleNotFound):
+		ResponseNotFound(c, "vehicle not in group")
+	case errors.Is(err, repository.ErrVehicleGroupAssigned):
+		ResponseError(c, http.StatusConflict, "vehicle group is assigned to geofences; delete its assignments first")
+	default:
+		ResponseError(c, http.StatusInternalServerError, message)
+	}
+}
+
+func parseIDParam(c *gin.Context, key, message string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(key), 10, 64)
+	if err != nil || id <= 0 {
+		ResponseBadRequest(c, message)
+		return 0, false
+	}
+	return id, true
+}
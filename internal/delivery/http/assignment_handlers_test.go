@@ -0,0 +1,287 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/repository"
+)
+
+type mockVehicleGroupRepo struct {
+	mock.Mock
+}
+
+func (m *mockVehicleGroupRepo) InsertVehicleGroup(ctx context.Context, group *model.VehicleGroup) error {
+	args := m.Called(group)
+	group.ID = 1
+	return args.Error(0)
+}
+
+func (m *mockVehicleGroupRepo) ListVehicleGroups(ctx context.Context) ([]*model.VehicleGroup, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.VehicleGroup), args.Error(1)
+}
+
+func (m *mockVehicleGroupRepo) DeleteVehicleGroup(ctx context.Context, id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *mockVehicleGroupRepo) ListGroupVehicles(ctx context.Context, groupID int64) ([]string, error) {
+	args := m.Called(groupID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *mockVehicleGroupRepo) AddGroupVehicle(ctx context.Context, groupID int64, vehicleID string) error {
+	args := m.Called(groupID, vehicleID)
+	return args.Error(0)
+}
+
+func (m *mockVehicleGroupRepo) RemoveGroupVehicle(ctx context.Context, groupID int64, vehicleID string) error {
+	args := m.Called(groupID, vehicleID)
+	return args.Error(0)
+}
+
+type mockAssignmentRepo struct {
+	mock.Mock
+}
+
+func (m *mockAssignmentRepo) InsertGeofenceAssignment(ctx context.Context, assignment *model.GeofenceAssignment) error {
+	args := m.Called(assignment)
+	assignment.ID = 1
+	return args.Error(0)
+}
+
+func (m *mockAssignmentRepo) ListGeofenceAssignments(ctx context.Context, geofenceID int64) ([]*model.GeofenceAssignment, error) {
+	args := m.Called(geofenceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.GeofenceAssignment), args.Error(1)
+}
+
+func (m *mockAssignmentRepo) DeleteGeofenceAssignment(ctx context.Context, geofenceID, id int64) error {
+	args := m.Called(geofenceID, id)
+	return args.Error(0)
+}
+
+func (m *mockAssignmentRepo) GeofenceVehicles(ctx context.Context) (map[int64][]string, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[int64][]string), args.Error(1)
+}
+
+// recordingNotifier records the geofence IDs it is notified about
+type recordingNotifier struct {
+	ids []int64
+}
+
+func (n *recordingNotifier) NotifyGeofenceChanged(ctx context.Context, id int64) {
+	n.ids = append(n.ids, id)
+}
+
+func setupAssignmentRouter(groupRepo *mockVehicleGroupRepo, assignmentRepo *mockAssignmentRepo, geofenceRepo *mockGeofenceRepo, notifier GeofenceChangeNotifier) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	return SetupRouter(
+		NewVehicleHandler(new(mockVehicleRepo)),
+		NewGeofenceHandler(geofenceRepo, notifier),
+		NewAssignmentHandler(groupRepo, assignmentRepo, geofenceRepo, notifier),
+
+		nil,
+	)
+}
+
+func TestCreateVehicleGroup(t *testing.T) {
+	groupRepo := new(mockVehicleGroupRepo)
+	groupRepo.On("InsertVehicleGroup", mock.MatchedBy(func(g *model.VehicleGroup) bool {
+		return g.Name == "Depot A"
+	})).Return(nil)
+
+	req, _ := http.NewRequest("POST", "/api/v1/vehicle-groups", strings.NewReader(`{"name":" Depot A "}`))
+	w := httptest.NewRecorder()
+	setupAssignmentRouter(groupRepo, new(mockAssignmentRepo), new(mockGeofenceRepo), nil).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"Depot A"`)
+	groupRepo.AssertExpectations(t)
+}
+
+func TestCreateVehicleGroup_Duplicate(t *testing.T) {
+	groupRepo := new(mockVehicleGroupRepo)
+	groupRepo.On("InsertVehicleGroup", mock.Anything).Return(repository.ErrVehicleGroupExists)
+
+	req, _ := http.NewRequest("POST", "/api/v1/vehicle-groups", strings.NewReader(`{"name":"Depot A"}`))
+	w := httptest.NewRecorder()
+	setupAssignmentRouter(groupRepo, new(mockAssignmentRepo), new(mockGeofenceRepo), nil).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "CONFLICT")
+}
+
+func TestDeleteVehicleGroup_Assigned(t *testing.T) {
+	groupRepo := new(mockVehicleGroupRepo)
+	groupRepo.On("DeleteVehicleGroup", int64(3)).Return(repository.ErrVehicleGroupAssigned)
+	notifier := &recordingNotifier{}
+
+	req, _ := http.NewRequest("DELETE", "/api/v1/vehicle-groups/3", nil)
+	w := httptest.NewRecorder()
+	setupAssignmentRouter(groupRepo, new(mockAssignmentRepo), new(mockGeofenceRepo), notifier).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "delete its assignments first")
+	assert.Empty(t, notifier.ids)
+	groupRepo.AssertExpectations(t)
+}
+
+func TestAddGroupVehicle(t *testing.T) {
+	groupRepo := new(mockVehicleGroupRepo)
+	groupRepo.On("AddGroupVehicle", int64(3), "BUS-001").Return(nil)
+	notifier := &recordingNotifier{}
+
+	req, _ := http.NewRequest("PUT", "/api/v1/vehicle-groups/3/vehicles/BUS-001", nil)
+	w := httptest.NewRecorder()
+	setupAssignmentRouter(groupRepo, new(mockAssignmentRepo), new(mockGeofenceRepo), notifier).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int64{0}, notifier.ids)
+	groupRepo.AssertExpectations(t)
+}
+
+func TestAddGroupVehicle_GroupNotFound(t *testing.T) {
+	groupRepo := new(mockVehicleGroupRepo)
+	groupRepo.On("AddGroupVehicle", int64(3), "BUS-001").Return(repository.ErrVehicleGroupNotFound)
+
+	req, _ := http.NewRequest("PUT", "/api/v1/vehicle-groups/3/vehicles/BUS-001", nil)
+	w := httptest.NewRecorder()
+	setupAssignmentRouter(groupRepo, new(mockAssignmentRepo), new(mockGeofenceRepo), nil).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "vehicle group not found")
+}
+
+func TestCreateGeofenceAssignment(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		match func(a *model.GeofenceAssignment) bool
+	}{
+		{"vehicle", `{"vehicle_id":"BUS-001"}`, func(a *model.GeofenceAssignment) bool {
+			return a.GeofenceID == 7 && a.VehicleID != nil && *a.VehicleID == "BUS-001" && a.GroupID == nil
+		}},
+		{"group", `{"group_id":3}`, func(a *model.GeofenceAssignment) bool {
+			return a.GeofenceID == 7 && a.VehicleID == nil && a.GroupID != nil && *a.GroupID == 3
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			geofenceRepo := new(mockGeofenceRepo)
+			geofenceRepo.On("GetGeofence", int64(7)).Return(&model.Geofence{ID: 7}, nil)
+			assignmentRepo := new(mockAssignmentRepo)
+			assignmentRepo.On("InsertGeofenceAssignment", mock.MatchedBy(tt.match)).Return(nil)
+			notifier := &recordingNotifier{}
+
+			req, _ := http.NewRequest("POST", "/api/v1/geofences/7/assignments", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			setupAssignmentRouter(new(mockVehicleGroupRepo), assignmentRepo, geofenceRepo, notifier).ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusCreated, w.Code)
+			assert.Equal(t, []int64{7}, notifier.ids)
+			assignmentRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCreateGeofenceAssignment_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		code    int
+		message string
+	}{
+		{"neither", `{}`, http.StatusBadRequest, "exactly one of"},
+		{"both", `{"vehicle_id":"BUS-001","group_id":3}`, http.StatusBadRequest, "exactly one of"},
+		{"blank vehicle", `{"vehicle_id":"  "}`, http.StatusBadRequest, "exactly one of"},
+		{"malformed body", `{"vehicle_id":`, http.StatusBadRequest, "invalid request body"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			geofenceRepo := new(mockGeofenceRepo)
+			geofenceRepo.On("GetGeofence", int64(7)).Return(&model.Geofence{ID: 7}, nil)
+			assignmentRepo := new(mockAssignmentRepo)
+
+			req, _ := http.NewRequest("POST", "/api/v1/geofences/7/assignments", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			setupAssignmentRouter(new(mockVehicleGroupRepo), assignmentRepo, geofenceRepo, nil).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.code, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+			assignmentRepo.AssertNotCalled(t, "InsertGeofenceAssignment", mock.Anything)
+		})
+	}
+}
+
+func TestCreateGeofenceAssignment_NotFound(t *testing.T) {
+	t.Run("geofence", func(t *testing.T) {
+		geofenceRepo := new(mockGeofenceRepo)
+		geofenceRepo.On("GetGeofence", int64(7)).Return(nil, repository.ErrGeofenceNotFound)
+
+		req, _ := http.NewRequest("POST", "/api/v1/geofences/7/assignments", strings.NewReader(`{"group_id":3}`))
+		w := httptest.NewRecorder()
+		setupAssignmentRouter(new(mockVehicleGroupRepo), new(mockAssignmentRepo), geofenceRepo, nil).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "geofence not found")
+	})
+
+	t.Run("group", func(t *testing.T) {
+		geofenceRepo := new(mockGeofenceRepo)
+		geofenceRepo.On("GetGeofence", int64(7)).Return(&model.Geofence{ID: 7}, nil)
+		assignmentRepo := new(mockAssignmentRepo)
+		assignmentRepo.On("InsertGeofenceAssignment", mock.Anything).Return(repository.ErrVehicleGroupNotFound)
+
+		req, _ := http.NewRequest("POST", "/api/v1/geofences/7/assignments", strings.NewReader(`{"group_id":3}`))
+		w := httptest.NewRecorder()
+		setupAssignmentRouter(new(mockVehicleGroupRepo), assignmentRepo, geofenceRepo, nil).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "vehicle group not found")
+	})
+}
+
+func TestDeleteGeofenceAssignment(t *testing.T) {
+	assignmentRepo := new(mockAssignmentRepo)
+	assignmentRepo.On("DeleteGeofenceAssignment", int64(7), int64(2)).Return(nil)
+	assignmentRepo.On("DeleteGeofenceAssignment", int64(7), int64(9)).Return(repository.ErrAssignmentNotFound)
+	router := setupAssignmentRouter(new(mockVehicleGroupRepo), assignmentRepo, new(mockGeofenceRepo), nil)
+
+	req, _ := http.NewRequest("DELETE", "/api/v1/geofences/7/assignments/2", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("DELETE", "/api/v1/geofences/7/assignments/9", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	req, _ = http.NewRequest("DELETE", "/api/v1/geofences/7/assignments/abc", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
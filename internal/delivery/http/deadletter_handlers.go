@@ -0,0 +1,198 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/queue"
+)
+
+// maxDeadLetterList caps a single dead letter listing
+const maxDeadLetterList = 1000
+
+// DeadLetterHandler exposes the dead letter store to operators
+type DeadLetterHandler struct {
+	store queue.DeadLetterStore
+}
+
+func NewDeadLetterHandler(store queue.DeadLetterStore) *DeadLetterHandler {
+	return &DeadLetterHandler{store: store}
+}
+
+// ListDeadLetters godoc
+// @Summary      List dead letters
+// @Description  List dead-lettered entries, oldest first, optionally filtered by queue, error and failure time
+// @Tags         admin
+// @Produce      json
+// @Param        queue query string false "Original queue"
+// @Param        error query string false "Case-insensitive error message match"
+// @Param        since query string false "Failed at or after (RFC3339)"
+// @Param        until query string false "Failed at or before (RFC3339)"
+// @Param        limit query int false "Maximum number of results (default and max 1000)"
+// @Success      200  {object}  DeadLetterListResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /admin/dead-letters [get]
+func (h *DeadLetterHandler) ListDeadLetters(c *gin.Context) {
+	filter := queue.DeadLetterFilter{
+		Queue: c.Query("queue"),
+		Error: c.Query("error"),
+	}
+
+	var err error
+	if filter.Since, err = parseTimeQuery(c, "since"); err != nil {
+		ResponseBadRequest(c, err.Error())
+		return
+	}
+	if filter.Until, err = parseTimeQuery(c, "until"); err != nil {
+		ResponseBadRequest(c, err.Error())
+		return
+	}
+	if filter.Limit, err = parseNonNegativeQuery(c, "limit"); err != nil {
+		ResponseBadRequest(c, err.Error())
+		return
+	}
+	if filter.Limit == 0 || filter.Limit > maxDeadLetterList {
+		filter.Limit = maxDeadLetterList
+	}
+
+	entries, err := h.store.ListDeadLetters(c.Request.Context(), filter)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "failed to list dead letters")
+		return
+	}
+	if entries == nil {
+		entries = []model.DeadLetterEntry{}
+	}
+	ResponseSuccess(c, DeadLetterListResponse{
+		Count:   len(entries),
+		Entries: entries,
+	})
+}
+
+// GetDeadLetter godoc
+// @Summary      Get dead letter
+// @Tags         admin
+// @Produce      json
+// @Param        id path string true "Dead letter ID"
+// @Success      200  {object}  model.DeadLetterEntry
+// @Failure      404  {object}  ErrorResponse
+// @Router       /admin/dead-letters/{id} [get]
+func (h *DeadLetterHandler) GetDeadLetter(c *gin.Context) {
+	entry, err := h.store.GetDeadLetter(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondDeadLetterError(c, err, "failed to get dead letter")
+		return
+	}
+	ResponseSuccess(c, entry)
+}
+
+// DeleteDeadLetter godoc
+// @Summary      Delete dead letter
+// @Tags         admin
+// @Produce      json
+// @Param        id path string true "Dead letter ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      404  {object}  ErrorResponse
+// @Router       /admin/dead-letters/{id} [delete]
+func (h *DeadLetterHandler) DeleteDeadLetter(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.store.DeleteDeadLetter(c.Request.Context(), id); err != nil {
+		respondDeadLetterError(c, err, "failed to delete dead letter")
+		return
+	}
+	ResponseSuccess(c, gin.H{
+		"id":      id,
+		"deleted": true,
+	})
+}
+
+// ReplayDeadLetters godoc
+// @Summary      Replay dead letters
+// @Description  Publish the selected entries back onto their original queues and remove them. Entries without a queue are reported as failed.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        selection body DeadLetterSelection true "Entries to replay"
+// @Success      200  {object}  queue.DeadLetterResult
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /admin/dead-letters/replay [post]
+func (h *DeadLetterHandler) ReplayDeadLetters(c *gin.Context) {
+	ids, ok := h.selectDeadLetters(c)
+	if !ok {
+		return
+	}
+	ResponseSuccess(c, queue.ReplayDeadLetters(c.Request.Context(), h.store, ids))
+}
+
+// PurgeDeadLetters godoc
+// @Summary      Purge dead letters
+// @Description  Permanently delete the selected entries
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        selection body DeadLetterSelection true "Entries to purge"
+// @Success      200  {object}  queue.DeadLetterResult
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /admin/dead-letters/purge [post]
+func (h *DeadLetterHandler) PurgeDeadLetters(c *gin.Context) {
+	ids, ok := h.selectDeadLetters(c)
+	if !ok {
+		return
+	}
+	ResponseSuccess(c, queue.PurgeDeadLetters(c.Request.Context(), h.store, ids))
+}
+
+// selectDeadLetters binds the selection and resolves it to entry IDs
+func (h *DeadLetterHandler) selectDeadLetters(c *gin.Context) ([]string, bool) {
+	var req DeadLetterSelection
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseBadRequest(c, "invalid request body")
+		return nil, false
+	}
+
+	filter := queue.DeadLetterFilter{Queue: req.Queue, Error: req.Error}
+	if req.Since != nil {
+		filter.Since = *req.Since
+	}
+	if req.Until != nil {
+		filter.Until = *req.Until
+	}
+	if len(req.IDs) == 0 && filter == (queue.DeadLetterFilter{}) && !req.All {
+		ResponseBadRequest(c, "select entries by ids or filter, or set all to true")
+		return nil, false
+	}
+
+	ids, err := queue.SelectDeadLetters(c.Request.Context(), h.store, req.IDs, filter)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "failed to list dead letters")
+		return nil, false
+	}
+	return ids, true
+}
+
+func respondDeadLetterError(c *gin.Context, err error, message string) {
+	if errors.Is(err, queue.ErrDeadLetterNotFound) {
+		ResponseNotFound(c, "dead letter not found")
+		return
+	}
+	ResponseError(c, http.StatusInternalServerError, message)
+}
+
+func parseTimeQuery(c *gin.Context, key string) (time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC3339 timestamp", key)
+	}
+	return t, nil
+}
@@ -0,0 +1,205 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/queue"
+)
+
+type mockDeadLetterStore struct {
+	mock.Mock
+}
+
+func (m *mockDeadLetterStore) ListDeadLetters(ctx context.Context, filter queue.DeadLetterFilter) ([]model.DeadLetterEntry, error) {
+	args := m.Called(filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.DeadLetterEntry), args.Error(1)
+}
+
+func (m *mockDeadLetterStore) GetDeadLetter(ctx context.Context, id string) (model.DeadLetterEntry, error) {
+	args := m.Called(id)
+	return args.Get(0).(model.DeadLetterEntry), args.Error(1)
+}
+
+func (m *mockDeadLetterStore) ReplayDeadLetter(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *mockDeadLetterStore) DeleteDeadLetter(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func setupDeadLetterRouter(store *mockDeadLetterStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	return SetupRouter(
+		NewVehicleHandler(new(mockVehicleRepo)),
+		NewGeofenceHandler(new(mockGeofenceRepo), nil),
+		NewAssignmentHandler(new(mockVehicleGroupRepo), new(mockAssignmentRepo), new(mockGeofenceRepo), nil),
+		NewDeadLetterHandler(store),
+	)
+}
+
+func TestListDeadLetters_Filters(t *testing.T) {
+	store := new(mockDeadLetterStore)
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.On("ListDeadLetters", queue.DeadLetterFilter{
+		Queue: "event_log:stream",
+		Error: "refused",
+		Since: since,
+		Limit: 50,
+	}).Return([]model.DeadLetterEntry{{ID: "1", Queue: "event_log:stream", ErrorMsg: "connection refused"}}, nil)
+
+	router := setupDeadLetterRouter(store)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/admin/dead-letters?queue=event_log:stream&error=refused&since=2025-01-01T00:00:00Z&limit=50", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"count":1`)
+	assert.Contains(t, w.Body.String(), `"error_msg":"connection refused"`)
+	store.AssertExpectations(t)
+}
+
+func TestListDeadLetters_DefaultLimit(t *testing.T) {
+	store := new(mockDeadLetterStore)
+	store.On("ListDeadLetters", queue.DeadLetterFilter{Limit: maxDeadLetterList}).Return([]model.DeadLetterEntry{}, nil)
+
+	router := setupDeadLetterRouter(store)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/admin/dead-letters?limit=5000", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	store.AssertExpectations(t)
+}
+
+func TestListDeadLetters_InvalidTime(t *testing.T) {
+	router := setupDeadLetterRouter(new(mockDeadLetterStore))
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/admin/dead-letters?until=yesterday", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "until must be an RFC3339 timestamp")
+}
+
+func TestGetDeadLetter(t *testing.T) {
+	store := new(mockDeadLetterStore)
+	store.On("GetDeadLetter", "7").Return(model.DeadLetterEntry{ID: "7", EventJSON: `{"n":1}`, RetryCount: 2}, nil)
+	store.On("GetDeadLetter", "8").Return(model.DeadLetterEntry{}, queue.ErrDeadLetterNotFound)
+
+	router := setupDeadLetterRouter(store)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/admin/dead-letters/7", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"retry_count":2`)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/admin/dead-letters/8", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDeleteDeadLetter(t *testing.T) {
+	store := new(mockDeadLetterStore)
+	store.On("DeleteDeadLetter", "7").Return(nil)
+
+	router := setupDeadLetterRouter(store)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/v1/admin/dead-letters/7", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	store.AssertExpectations(t)
+}
+
+func TestReplayDeadLetters_ByID(t *testing.T) {
+	store := new(mockDeadLetterStore)
+	store.On("ReplayDeadLetter", "1").Return(nil)
+	store.On("ReplayDeadLetter", "2").Return(queue.ErrNotReplayable)
+
+	router := setupDeadLetterRouter(store)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/admin/dead-letters/replay", strings.NewReader(`{"ids":["1","2"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data queue.DeadLetterResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []string{"1"}, resp.Data.Succeeded)
+	assert.Equal(t, map[string]string{"2": queue.ErrNotReplayable.Error()}, resp.Data.Failed)
+	store.AssertNotCalled(t, "ListDeadLetters", mock.Anything)
+}
+
+func TestPurgeDeadLetters_ByFilter(t *testing.T) {
+	store := new(mockDeadLetterStore)
+	store.On("ListDeadLetters", queue.DeadLetterFilter{Error: "invalid"}).
+		Return([]model.DeadLetterEntry{{ID: "3"}, {ID: "4"}}, nil)
+	store.On("DeleteDeadLetter", "3").Return(nil)
+	store.On("DeleteDeadLetter", "4").Return(nil)
+
+	router := setupDeadLetterRouter(store)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/admin/dead-letters/purge", strings.NewReader(`{"error":"invalid"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"succeeded":["3","4"]`)
+	store.AssertExpectations(t)
+}
+
+func TestPurgeDeadLetters_RequiresSelection(t *testing.T) {
+	store := new(mockDeadLetterStore)
+	router := setupDeadLetterRouter(store)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/admin/dead-letters/purge", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	store.AssertNotCalled(t, "ListDeadLetters", mock.Anything)
+
+	store.On("ListDeadLetters", queue.DeadLetterFilter{}).Return(nil, errors.New("redis down"))
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/admin/dead-letters/purge", strings.NewReader(`{"all":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestDeadLetterRoutes_DisabledWithoutStore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := SetupRouter(
+		NewVehicleHandler(new(mockVehicleRepo)),
+		NewGeofenceHandler(new(mockGeofenceRepo), nil),
+		NewAssignmentHandler(new(mockVehicleGroupRepo), new(mockAssignmentRepo), new(mockGeofenceRepo), nil),
+		nil,
+	)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/admin/dead-letters", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
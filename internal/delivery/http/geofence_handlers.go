@@ -0,0 +1,396 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/repository"
+)
+
+// maxGeofenceRadius caps circular geofences at 100km
+const maxGeofenceRadius = 100000
+
+// maxGeofenceHysteresis caps the exit hysteresis band at 1km
+const maxGeofenceHysteresis = 1000
+
+// GeofenceChangeNotifier is told about every geofence change so workers can
+// refresh their cached geofence set. id is 0 for changes such as vehicle group
+// membership that may affect any geofence.
+type GeofenceChangeNotifier interface {
+	NotifyGeofenceChanged(ctx context.Context, id int64)
+}
+
+type GeofenceHandler struct {
+	geofenceRepo repository.GeofenceRepository
+	notifier     GeofenceChangeNotifier
+}
+
+// NewGeofenceHandler creates the handler. notifier may be nil, in which case
+// workers pick up changes on their next periodic refresh.
+func NewGeofenceHandler(geofenceRepo repository.GeofenceRepository, notifier GeofenceChangeNotifier) *GeofenceHandler {
+	return &GeofenceHandler{
+		geofenceRepo: geofenceRepo,
+		notifier:     notifier,
+	}
+}
+
+// CreateGeofence godoc
+// @Summary      Create geofence
+// @Description  Create a circular, polygon or multipolygon geofence
+// @Tags         geofences
+// @Accept       json
+// @Produce      json
+// @Param        geofence body GeofenceRequest true "Geofence"
+// @Success      201  {object}  model.Geofence
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /geofences [post]
+func (h *GeofenceHandler) CreateGeofence(c *gin.Context) {
+	var req GeofenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseBadRequest(c, "invalid request body")
+		return
+	}
+
+	geofence, err := geofenceFromRequest(req)
+	if err != nil {
+		ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.geofenceRepo.InsertGeofence(c.Request.Context(), geofence); err != nil {
+		ResponseError(c, http.StatusInternalServerError, "failed to create geofence")
+		return
+	}
+	h.notifyChanged(c.Request.Context(), geofence.ID)
+
+	ResponseCreated(c, geofence)
+}
+
+// ListGeofences godoc
+// @Summary      List geofences
+// @Description  List geofences, optionally filtered by active flag, shape and name
+// @Tags         geofences
+// @Produce      json
+// @Param        active query bool false "Filter by active flag"
+// @Param        shape query string false "Filter by shape" Enums(circle, polygon, multipolygon)
+// @Param        name query string false "Case-insensitive name match"
+// @Param        limit query int false "Maximum number of results"
+// @Param        offset query int false "Number of results to skip"
+// @Success      200  {object}  GeofenceListResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /geofences [get]
+func (h *GeofenceHandler) ListGeofences(c *gin.Context) {
+	filter := repository.GeofenceFilter{
+		Shape: c.Query("shape"),
+		Name:  strings.TrimSpace(c.Query("name")),
+	}
+
+	if activeStr := c.Query("active"); activeStr != "" {
+		active, err := strconv.ParseBool(activeStr)
+		if err != nil {
+			ResponseBadRequest(c, "active must be true or false")
+			return
+		}
+		filter.Active = &active
+	}
+
+	if filter.Shape != "" && !isValidGeofenceShape(filter.Shape) {
+		ResponseBadRequest(c, "shape must be one of circle, polygon, multipolygon")
+		return
+	}
+
+	var err error
+	if filter.Limit, err = parseNonNegativeQuery(c, "limit"); err != nil {
+		ResponseBadRequest(c, err.Error())
+		return
+	}
+	if filter.Offset, err = parseNonNegativeQuery(c, "offset"); err != nil {
+		ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	geofences, err := h.geofenceRepo.ListGeofences(c.Request.Context(), filter)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "failed to list geofences")
+		return
+	}
+
+	ResponseSuccess(c, GeofenceListResponse{
+		Count:     len(geofences),
+		Geofences: geofences,
+	})
+}
+
+// GetGeofence godoc
+// @Summary      Get geofence
+// @Description  Get a geofence by ID
+// @Tags         geofences
+// @Produce      json
+// @Param        id path int true "Geofence ID"
+// @Success      200  {object}  model.Geofence
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /geofences/{id} [get]
+func (h *GeofenceHandler) GetGeofence(c *gin.Context) {
+	id, ok := parseGeofenceID(c)
+	if !ok {
+		return
+	}
+
+	geofence, err := h.geofenceRepo.GetGeofence(c.Request.Context(), id)
+	if err != nil {
+		h.respondRepoError(c, err, "failed to get geofence")
+		return
+	}
+
+	ResponseSuccess(c, geofence)
+}
+
+// UpdateGeofence godoc
+// @Summary      Update geofence
+// @Description  Replace a geofence definition; active is left unchanged when omitted
+// @Tags         geofences
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "Geofence ID"
+// @Param        geofence body GeofenceRequest true "Geofence"
+// @Success      200  {object}  model.Geofence
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /geofences/{id} [put]
+func (h *GeofenceHandler) UpdateGeofence(c *gin.Context) {
+	id, ok := parseGeofenceID(c)
+	if !ok {
+		return
+	}
+
+	var req GeofenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseBadRequest(c, "invalid request body")
+		return
+	}
+
+	geofence, err := geofenceFromRequest(req)
+	if err != nil {
+		ResponseBadRequest(c, err.Error())
+		return
+	}
+	geofence.ID = id
+
+	// Leaving out active keeps the stored flag, so an edit doesn't undo a
+	// deactivation
+	if req.Active == nil {
+		existing, err := h.geofenceRepo.GetGeofence(c.Request.Context(), id)
+		if err != nil {
+			h.respondRepoError(c, err, "failed to update geofence")
+			return
+		}
+		geofence.Active = existing.Active
+	}
+
+	if err := h.geofenceRepo.UpdateGeofence(c.Request.Context(), geofence); err != nil {
+		h.respondRepoError(c, err, "failed to update geofence")
+		return
+	}
+	h.notifyChanged(c.Request.Context(), id)
+
+	ResponseSuccess(c, geofence)
+}
+
+// DeactivateGeofence godoc
+// @Summary      Deactivate geofence
+// @Description  Stop evaluating a geofence without deleting it
+// @Tags         geofences
+// @Produce      json
+// @Param        id path int true "Geofence ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /geofences/{id}/deactivate [post]
+func (h *GeofenceHandler) DeactivateGeofence(c *gin.Context) {
+	h.setActive(c, false)
+}
+
+// ActivateGeofence godoc
+// @Summary      Activate geofence
+// @Description  Resume evaluating a deactivated geofence
+// @Tags         geofences
+// @Produce      json
+// @Param        id path int true "Geofence ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /geofences/{id}/activate [post]
+func (h *GeofenceHandler) ActivateGeofence(c *gin.Context) {
+	h.setActive(c, true)
+}
+
+// DeleteGeofence godoc
+// @Summary      Delete geofence
+// @Description  Permanently delete a geofence
+// @Tags         geofences
+// @Produce      json
+// @Param        id path int true "Geofence ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /geofences/{id} [delete]
+func (h *GeofenceHandler) DeleteGeofence(c *gin.Context) {
+	id, ok := parseGeofenceID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.geofenceRepo.DeleteGeofence(c.Request.Context(), id); err != nil {
+		h.respondRepoError(c, err, "failed to delete geofence")
+		return
+	}
+	h.notifyChanged(c.Request.Context(), id)
+
+	ResponseSuccess(c, gin.H{
+		"id":      id,
+		"deleted": true,
+	})
+}
+
+func (h *GeofenceHandler) setActive(c *gin.Context, active bool) {
+	id, ok := parseGeofenceID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.geofenceRepo.SetGeofenceActive(c.Request.Context(), id, active); err != nil {
+		h.respondRepoError(c, err, "failed to update geofence")
+		return
+	}
+	h.notifyChanged(c.Request.Context(), id)
+
+	ResponseSuccess(c, gin.H{
+		"id":     id,
+		"active": active,
+	})
+}
+
+func (h *GeofenceHandler) notifyChanged(ctx context.Context, id int64) {
+	if h.notifier != nil {
+		h.notifier.NotifyGeofenceChanged(ctx, id)
+	}
+}
+
+func (h *GeofenceHandler) respondRepoError(c *gin.Context, err error, message string) {
+	if errors.Is(err, repository.ErrGeofenceNotFound) {
+		ResponseNotFound(c, "geofence not found")
+		return
+	}
+	ResponseError(c, http.StatusInternalServerError, message)
+}
+
+func parseGeofenceID(c *gin.Context) (int64, bool) {
+	return parseIDParam(c, "id", "invalid geofence id")
+}
+
+func parseNonNegativeQuery(c *gin.Context, key string) (int, error) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", key)
+	}
+	return n, nil
+}
+
+func isValidGeofenceShape(shape string) bool {
+	switch shape {
+	case model.GeofenceShapeCircle, model.GeofenceShapePolygon, model.GeofenceShapeMultiPolygon:
+		return true
+	}
+	return false
+}
+
+// geofenceFromRequest validates the request and builds the geofence model
+func geofenceFromRequest(req GeofenceRequest) (*model.Geofence, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	shape := req.Shape
+	if shape == "" {
+		shape = model.GeofenceShapeCircle
+	}
+	if !isValidGeofenceShape(shape) {
+		return nil, errors.New("shape must be one of circle, polygon, multipolygon")
+	}
+
+	// A band can't be switched off, since GPS jitter at the boundary would
+	// then flip vehicles in and out; omitting it selects the default
+	hysteresis := model.DefaultGeofenceHysteresis
+	if req.Hysteresis != nil {
+		if *req.Hysteresis <= 0 || *req.Hysteresis > maxGeofenceHysteresis {
+			return nil, fmt.Errorf("hysteresis must be greater than 0 and at most %d meters", maxGeofenceHysteresis)
+		}
+		hysteresis = *req.Hysteresis
+	}
+
+	if req.DwellMinutes < 0 {
+		return nil, errors.New("dwell_minutes must not be negative")
+	}
+	if req.DepartBy != "" {
+		if _, err := time.Parse(model.DepartByLayout, req.DepartBy); err != nil {
+			return nil, errors.New("depart_by must be a time of day in HH:MM format")
+		}
+	}
+
+	if req.Schedule != nil {
+		if err := req.Schedule.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	geofence := &model.Geofence{
+		Name:         name,
+		Shape:        shape,
+		Hysteresis:   hysteresis,
+		DwellMinutes: req.DwellMinutes,
+		DepartBy:     req.DepartBy,
+		Schedule:     req.Schedule,
+		Active:       req.Active == nil || *req.Active,
+	}
+
+	if shape == model.GeofenceShapeCircle {
+		if req.CenterLat < -90 || req.CenterLat > 90 {
+			return nil, errors.New("center_lat must be between -90 and 90")
+		}
+		if req.CenterLng < -180 || req.CenterLng > 180 {
+			return nil, errors.New("center_lng must be between -180 and 180")
+		}
+		if req.Radius <= 0 || req.Radius > maxGeofenceRadius {
+			return nil, fmt.Errorf("radius must be greater than 0 and at most %d meters", maxGeofenceRadius)
+		}
+		geofence.CenterLat = req.CenterLat
+		geofence.CenterLng = req.CenterLng
+		geofence.Radius = req.Radius
+		return geofence, nil
+	}
+
+	if len(req.Coordinates) == 0 {
+		return nil, fmt.Errorf("coordinates are required for %s geofences", shape)
+	}
+	geofence.Coordinates = req.Coordinates
+	if _, err := geofence.Polygons(); err != nil {
+		return nil, err
+	}
+	return geofence, nil
+}
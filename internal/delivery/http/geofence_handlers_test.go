@@ -0,0 +1,283 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/repository"
+)
+
+type mockGeofenceRepo struct {
+	mock.Mock
+}
+
+func (m *mockGeofenceRepo) InsertGeofence(ctx context.Context, geofence *model.Geofence) error {
+	args := m.Called(geofence)
+	geofence.ID = 1
+	return args.Error(0)
+}
+
+func (m *mockGeofenceRepo) GetGeofence(ctx context.Context, id int64) (*model.Geofence, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Geofence), args.Error(1)
+}
+
+func (m *mockGeofenceRepo) ListGeofences(ctx context.Context, filter repository.GeofenceFilter) ([]*model.Geofence, error) {
+	args := m.Called(filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Geofence), args.Error(1)
+}
+
+func (m *mockGeofenceRepo) UpdateGeofence(ctx context.Context, geofence *model.Geofence) error {
+	args := m.Called(geofence)
+	return args.Error(0)
+}
+
+func (m *mockGeofenceRepo) SetGeofenceActive(ctx context.Context, id int64, active bool) error {
+	args := m.Called(id, active)
+	return args.Error(0)
+}
+
+func (m *mockGeofenceRepo) DeleteGeofence(ctx context.Context, id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func setupGeofenceRouter(repo *mockGeofenceRepo) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	return SetupRouter(
+		NewVehicleHandler(new(mockVehicleRepo)),
+		NewGeofenceHandler(repo, nil),
+		NewAssignmentHandler(new(mockVehicleGroupRepo), new(mockAssignmentRepo), repo, nil),
+
+		nil,
+	)
+}
+
+func TestCreateGeofence_Circle(t *testing.T) {
+	mockRepo := new(mockGeofenceRepo)
+	mockRepo.On("InsertGeofence", mock.MatchedBy(func(g *model.Geofence) bool {
+		return g.Name == "Bundaran HI" && g.Shape == model.GeofenceShapeCircle && g.Radius == 100 && g.Active &&
+			g.DwellMinutes == 45 && g.DepartBy == "18:00" && g.Hysteresis == model.DefaultGeofenceHysteresis
+	})).Return(nil)
+
+	body := `{"name":"Bundaran HI","center_lat":-6.193125,"center_lng":106.820233,"radius":100,"dwell_minutes":45,"depart_by":"18:00"}`
+	req, _ := http.NewRequest("POST", "/api/v1/geofences", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	setupGeofenceRouter(mockRepo).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":1`)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateGeofence_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		message string
+	}{
+		{"missing name", `{"center_lat":0,"center_lng":0,"radius":100}`, "name is required"},
+		{"zero radius", `{"name":"A","center_lat":0,"center_lng":0,"radius":0}`, "radius must be"},
+		{"radius too large", `{"name":"A","center_lat":0,"center_lng":0,"radius":200000}`, "radius must be"},
+		{"latitude out of range", `{"name":"A","center_lat":91,"center_lng":0,"radius":100}`, "center_lat"},
+		{"longitude out of range", `{"name":"A","center_lat":0,"center_lng":-181,"radius":100}`, "center_lng"},
+		{"unknown shape", `{"name":"A","shape":"hexagon"}`, "shape must be"},
+		{"polygon without coordinates", `{"name":"A","shape":"polygon"}`, "coordinates are required"},
+		{"degenerate polygon", `{"name":"A","shape":"polygon","coordinates":[[[0,0],[1,1]]]}`, "ring must have"},
+		{"negative hysteresis", `{"name":"A","center_lat":0,"center_lng":0,"radius":100,"hysteresis":-1}`, "hysteresis must be"},
+		{"zero hysteresis", `{"name":"A","center_lat":0,"center_lng":0,"radius":100,"hysteresis":0}`, "hysteresis must be greater than 0"},
+		{"hysteresis too large", `{"name":"A","center_lat":0,"center_lng":0,"radius":100,"hysteresis":5000}`, "hysteresis must be"},
+		{"negative dwell", `{"name":"A","center_lat":0,"center_lng":0,"radius":100,"dwell_minutes":-5}`, "dwell_minutes"},
+		{"invalid depart_by", `{"name":"A","center_lat":0,"center_lng":0,"radius":100,"depart_by":"6pm"}`, "depart_by must be"},
+		{"schedule without windows", `{"name":"A","center_lat":0,"center_lng":0,"radius":100,"schedule":{"timezone":"Asia/Jakarta"}}`, "at least one window"},
+		{"schedule with unknown timezone", `{"name":"A","center_lat":0,"center_lng":0,"radius":100,
+			"schedule":{"timezone":"Nowhere/City","windows":[{"start":"06:00","end":"08:00"}]}}`, "unknown timezone"},
+		{"malformed body", `{"name":`, "invalid request body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockGeofenceRepo)
+			req, _ := http.NewRequest("POST", "/api/v1/geofences", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			setupGeofenceRouter(mockRepo).ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+			mockRepo.AssertNotCalled(t, "InsertGeofence", mock.Anything)
+		})
+	}
+}
+
+func TestCreateGeofence_Polygon(t *testing.T) {
+	mockRepo := new(mockGeofenceRepo)
+	mockRepo.On("InsertGeofence", mock.MatchedBy(func(g *model.Geofence) bool {
+		return g.Shape == model.GeofenceShapePolygon && !g.Active && g.Radius == 0 && g.Hysteresis == 15
+	})).Return(nil)
+
+	body := `{"name":"Depot A","shape":"polygon","radius":50,"active":false,"hysteresis":15,
+		"coordinates":[[[106.81,-6.19],[106.82,-6.19],[106.82,-6.18],[106.81,-6.18]]]}`
+	req, _ := http.NewRequest("POST", "/api/v1/geofences", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	setupGeofenceRouter(mockRepo).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateGeofence_Schedule(t *testing.T) {
+	mockRepo := new(mockGeofenceRepo)
+	mockRepo.On("InsertGeofence", mock.MatchedBy(func(g *model.Geofence) bool {
+		return g.Schedule != nil && g.Schedule.Timezone == "Asia/Jakarta" &&
+			len(g.Schedule.Windows) == 1 && g.Schedule.Windows[0].Start == "06:30"
+	})).Return(nil)
+
+	body := `{"name":"School Zone","center_lat":-6.193125,"center_lng":106.820233,"radius":100,
+		"schedule":{"timezone":"Asia/Jakarta","windows":[{"days":["mon","fri"],"start":"06:30","end":"08:30"}]}}`
+	req, _ := http.NewRequest("POST", "/api/v1/geofences", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	setupGeofenceRouter(mockRepo).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"timezone":"Asia/Jakarta"`)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestListGeofences_Filters(t *testing.T) {
+	mockRepo := new(mockGeofenceRepo)
+	active := true
+	mockRepo.On("ListGeofences", repository.GeofenceFilter{
+		Active: &active,
+		Shape:  model.GeofenceShapeCircle,
+		Name:   "depot",
+		Limit:  10,
+	}).Return([]*model.Geofence{{ID: 1, Name: "Depot A", Active: true}}, nil)
+
+	req, _ := http.NewRequest("GET", "/api/v1/geofences?active=true&shape=circle&name=depot&limit=10", nil)
+	w := httptest.NewRecorder()
+	setupGeofenceRouter(mockRepo).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"count":1`)
+	assert.Contains(t, w.Body.String(), "Depot A")
+}
+
+func TestListGeofences_InvalidFilter(t *testing.T) {
+	mockRepo := new(mockGeofenceRepo)
+
+	for _, query := range []string{"active=maybe", "shape=hexagon", "limit=-1", "offset=x"} {
+		req, _ := http.NewRequest("GET", "/api/v1/geofences?"+query, nil)
+		w := httptest.NewRecorder()
+		setupGeofenceRouter(mockRepo).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+	mockRepo.AssertNotCalled(t, "ListGeofences", mock.Anything)
+}
+
+func TestGetGeofence_NotFound(t *testing.T) {
+	mockRepo := new(mockGeofenceRepo)
+	mockRepo.On("GetGeofence", int64(42)).Return(nil, repository.ErrGeofenceNotFound)
+
+	req, _ := http.NewRequest("GET", "/api/v1/geofences/42", nil)
+	w := httptest.NewRecorder()
+	setupGeofenceRouter(mockRepo).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "geofence not found")
+}
+
+func TestGetGeofence_InvalidID(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/api/v1/geofences/abc", nil)
+	w := httptest.NewRecorder()
+	setupGeofenceRouter(new(mockGeofenceRepo)).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUpdateGeofence_Success(t *testing.T) {
+	mockRepo := new(mockGeofenceRepo)
+	mockRepo.On("GetGeofence", int64(7)).Return(&model.Geofence{ID: 7, Active: true}, nil)
+	mockRepo.On("UpdateGeofence", mock.MatchedBy(func(g *model.Geofence) bool {
+		return g.ID == 7 && g.Radius == 250 && g.Active
+	})).Return(nil)
+
+	body := `{"name":"Bundaran HI","center_lat":-6.193125,"center_lng":106.820233,"radius":250}`
+	req, _ := http.NewRequest("PUT", "/api/v1/geofences/7", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	setupGeofenceRouter(mockRepo).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateGeofence_KeepsDeactivated(t *testing.T) {
+	mockRepo := new(mockGeofenceRepo)
+	mockRepo.On("GetGeofence", int64(7)).Return(&model.Geofence{ID: 7, Name: "Old name", Active: false}, nil)
+	mockRepo.On("UpdateGeofence", mock.MatchedBy(func(g *model.Geofence) bool {
+		return g.ID == 7 && g.Name == "Renamed" && !g.Active
+	})).Return(nil)
+
+	body := `{"name":"Renamed","center_lat":-6.193125,"center_lng":106.820233,"radius":250}`
+	req, _ := http.NewRequest("PUT", "/api/v1/geofences/7", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	setupGeofenceRouter(mockRepo).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"active":false`)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateGeofence_ExplicitActive(t *testing.T) {
+	mockRepo := new(mockGeofenceRepo)
+	mockRepo.On("UpdateGeofence", mock.MatchedBy(func(g *model.Geofence) bool {
+		return g.ID == 7 && g.Active
+	})).Return(nil)
+
+	body := `{"name":"Renamed","center_lat":-6.193125,"center_lng":106.820233,"radius":250,"active":true}`
+	req, _ := http.NewRequest("PUT", "/api/v1/geofences/7", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	setupGeofenceRouter(mockRepo).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertNotCalled(t, "GetGeofence", int64(7))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDeactivateGeofence(t *testing.T) {
+	mockRepo := new(mockGeofenceRepo)
+	mockRepo.On("SetGeofenceActive", int64(3), false).Return(nil)
+
+	req, _ := http.NewRequest("POST", "/api/v1/geofences/3/deactivate", nil)
+	w := httptest.NewRecorder()
+	setupGeofenceRouter(mockRepo).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"active":false`)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDeleteGeofence_NotFound(t *testing.T) {
+	mockRepo := new(mockGeofenceRepo)
+	mockRepo.On("DeleteGeofence", int64(9)).Return(repository.ErrGeofenceNotFound)
+
+	req, _ := http.NewRequest("DELETE", "/api/v1/geofences/9", nil)
+	w := httptest.NewRecorder()
+	setupGeofenceRouter(mockRepo).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -1,10 +1,16 @@
 package http
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
 	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/repository"
 )
 
@@ -17,6 +23,14 @@ func ResponseSuccess(c *gin.Context, data interface{}) {
 	})
 }
 
+func ResponseCreated(c *gin.Context, data interface{}) {
+	c.JSON(http.StatusCreated, Response{
+		Code:    http.StatusCreated,
+		Message: "created",
+		Data:    data,
+	})
+}
+
 func ResponseError(c *gin.Context, code int, message string) {
 	c.JSON(code, ErrorResponse{
 		Error: message,
@@ -45,6 +59,8 @@ func getErrorCode(statusCode int) string {
 		return "BAD_REQUEST"
 	case http.StatusNotFound:
 		return "NOT_FOUND"
+	case http.StatusConflict:
+		return "CONFLICT"
 	case http.StatusInternalServerError:
 		return "INTERNAL_ERROR"
 	default:
@@ -67,6 +83,7 @@ func NewVehicleHandler(vehicleRepo repository.VehicleRepository) *VehicleHandler
 // @Description  Get the latest location for a vehicle
 // @Tags         vehicles
 // @Param        vehicle_id path string true "Vehicle ID"
+// @Param        attributes query string false "Comma-separated attribute keys to return, e.g. fuel_level,ignition; all when omitted"
 // @Success      200  {object}  LocationResponse
 // @Failure      404  {object}  ErrorResponse
 // @Router       /vehicles/{vehicle_id}/location [get]
@@ -77,7 +94,7 @@ func (h *VehicleHandler) GetLatestLocation(c *gin.Context) {
 		return
 	}
 
-	loc, err := h.vehicleRepo.GetLatestLocation(vehicleID)
+	loc, err := h.vehicleRepo.GetLatestLocation(c.Request.Context(), vehicleID)
 
 	// Handle errors
 	if err != nil {
@@ -89,6 +106,7 @@ func (h *VehicleHandler) GetLatestLocation(c *gin.Context) {
 		return
 	}
 
+	projectAttributes(c, loc)
 	ResponseSuccess(c, loc)
 }
 
@@ -99,9 +117,12 @@ func (h *VehicleHandler) GetLatestLocation(c *gin.Context) {
 // @Param        vehicle_id path string true "Vehicle ID"
 // @Param        start query string true "Start time (RFC3339)"
 // @Param        end query string true "End time (RFC3339)"
+// @Param        attr query string false "Attribute filter given as attr[key]=value, e.g. attr[ignition]=false; repeat for more keys. true and false match booleans and numeric values match numbers, which must be finite. Wrap a value in double quotes to match it as a string."
+// @Param        attributes query string false "Comma-separated attribute keys to return, e.g. fuel_level,ignition; all when omitted"
 // @Success      200  {object}  map[string]interface{}
 // @Failure      400  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
 // @Router       /vehicles/{vehicle_id}/history [get]
 func (h *VehicleHandler) GetLocationHistory(c *gin.Context) {
 	vehicleID := c.Param("vehicle_id")
@@ -135,11 +156,31 @@ func (h *VehicleHandler) GetLocationHistory(c *gin.Context) {
 		return
 	}
 
-	history, err := h.vehicleRepo.GetLocationHistory(vehicleID, start, end)
-	if err != nil {
-		ResponseNotFound(c, "vehicle not found")
-		return
+	var history []*model.VehicleLocation
+	if query := c.QueryMap("attr"); len(query) > 0 {
+		attributes, err := parseAttributeFilter(query)
+		if err != nil {
+			ResponseBadRequest(c, err.Error())
+			return
+		}
+		history, err = h.vehicleRepo.FindLocations(c.Request.Context(), repository.LocationFilter{
+			VehicleID:  vehicleID,
+			Start:      start,
+			End:        end,
+			Attributes: attributes,
+		})
+		if err != nil {
+			ResponseError(c, http.StatusInternalServerError, "failed to query location history")
+			return
+		}
+	} else {
+		history, err = h.vehicleRepo.GetLocationHistory(c.Request.Context(), vehicleID, start, end)
+		if err != nil {
+			ResponseNotFound(c, "vehicle not found")
+			return
+		}
 	}
+	projectAttributes(c, history...)
 
 	ResponseSuccess(c, gin.H{
 		"vehicle_id": vehicleID,
@@ -150,6 +191,54 @@ func (h *VehicleHandler) GetLocationHistory(c *gin.Context) {
 	})
 }
 
+// parseAttributeFilter types attribute filter values the way the tracker
+// would have sent them: true and false as booleans, numbers as numbers and
+// anything else as a string. A value in double quotes is always a string, so
+// attr[driver]="007" matches the string "007". Numbers JSON can't hold, such
+// as NaN, Inf or 1e999, are rejected.
+func parseAttributeFilter(query map[string]string) (map[string]any, error) {
+	attributes := make(map[string]any, len(query))
+	for key, value := range query {
+		if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+			attributes[key] = value[1 : len(value)-1]
+			continue
+		}
+		switch value {
+		case "true":
+			attributes[key] = true
+		case "false":
+			attributes[key] = false
+		default:
+			n, err := strconv.ParseFloat(value, 64)
+			switch {
+			case errors.Is(err, strconv.ErrRange) || (err == nil && (math.IsNaN(n) || math.IsInf(n, 0))):
+				return nil, fmt.Errorf("attr[%s] must be a finite number", key)
+			case err == nil:
+				attributes[key] = n
+			default:
+				attributes[key] = value
+			}
+		}
+	}
+	return attributes, nil
+}
+
+// projectAttributes keeps only the attributes named by the attributes query
+// parameter, if given
+func projectAttributes(c *gin.Context, locs ...*model.VehicleLocation) {
+	param := c.Query("attributes")
+	if param == "" {
+		return
+	}
+	keys := strings.Split(param, ",")
+	for i := range keys {
+		keys[i] = strings.TrimSpace(keys[i])
+	}
+	for _, loc := range locs {
+		loc.Attributes = loc.Attributes.Only(keys...)
+	}
+}
+
 // HealthCheck godoc
 // @Summary      Health check
 // @Description  Check if the API is up
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -12,18 +13,24 @@ import (
 	"github.com/stretchr/testify/mock"
 
 	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/repository"
 )
 
 type mockVehicleRepo struct {
 	mock.Mock
 }
 
-func (m *mockVehicleRepo) InsertLocation(loc *model.VehicleLocation) error {
+func (m *mockVehicleRepo) InsertLocation(ctx context.Context, loc *model.VehicleLocation) error {
 	args := m.Called(loc)
 	return args.Error(0)
 }
 
-func (m *mockVehicleRepo) GetLatestLocation(vehicleID string) (*model.VehicleLocation, error) {
+func (m *mockVehicleRepo) InsertLocations(ctx context.Context, locs []*model.VehicleLocation) error {
+	args := m.Called(locs)
+	return args.Error(0)
+}
+
+func (m *mockVehicleRepo) GetLatestLocation(ctx context.Context, vehicleID string) (*model.VehicleLocation, error) {
 	args := m.Called(vehicleID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -31,11 +38,19 @@ func (m *mockVehicleRepo) GetLatestLocation(vehicleID string) (*model.VehicleLoc
 	return args.Get(0).(*model.VehicleLocation), args.Error(1)
 }
 
-func (m *mockVehicleRepo) GetLocationHistory(vehicleID string, start, end time.Time) ([]*model.VehicleLocation, error) {
+func (m *mockVehicleRepo) GetLocationHistory(ctx context.Context, vehicleID string, start, end time.Time) ([]*model.VehicleLocation, error) {
 	args := m.Called(vehicleID, start, end)
 	return nil, args.Error(1)
 }
 
+func (m *mockVehicleRepo) FindLocations(ctx context.Context, filter repository.LocationFilter) ([]*model.VehicleLocation, error) {
+	args := m.Called(filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.VehicleLocation), args.Error(1)
+}
+
 func TestGetLatestLocation_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	mockRepo := new(mockVehicleRepo)
@@ -81,3 +96,110 @@ func TestGetLatestLocation_NotFound(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, w.Code)
 	assert.Contains(t, w.Body.String(), "vehicle not found")
 }
+
+func TestGetLatestLocation_ReturnsTelemetry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockRepo := new(mockVehicleRepo)
+	handler := NewVehicleHandler(mockRepo)
+
+	heading, satellites := 270.0, 11
+	receivedAt := time.Date(2024, 5, 1, 8, 0, 2, 0, time.UTC)
+	mockRepo.On("GetLatestLocation", "TEST123").Return(&model.VehicleLocation{
+		VehicleID:  "TEST123",
+		Latitude:   -6.2,
+		Longitude:  106.8,
+		Speed:      42.5,
+		Heading:    &heading,
+		Satellites: &satellites,
+		Timestamp:  time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC),
+		ReceivedAt: &receivedAt,
+	}, nil)
+
+	r := gin.New()
+	r.GET("/vehicles/:vehicle_id/location", handler.GetLatestLocation)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/vehicles/TEST123/location", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `"speed":42.5`)
+	assert.Contains(t, body, `"heading":270`)
+	assert.Contains(t, body, `"satellites":11`)
+	assert.Contains(t, body, `"received_at":"2024-05-01T08:00:02Z"`)
+	assert.NotContains(t, body, `"altitude"`, "unreported telemetry is left out")
+}
+
+func TestGetLocationHistory_FiltersAndProjectsAttributes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockRepo := new(mockVehicleRepo)
+	handler := NewVehicleHandler(mockRepo)
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	mockRepo.On("FindLocations", repository.LocationFilter{
+		VehicleID:  "TEST123",
+		Start:      start,
+		End:        end,
+		Attributes: map[string]any{"ignition": false, "fuel_level": 20.0, "driver": "D-17"},
+	}).Return([]*model.VehicleLocation{{
+		VehicleID:  "TEST123",
+		Timestamp:  start.Add(time.Hour),
+		Attributes: model.LocationAttributes{"ignition": false, "fuel_level": 20.0, "driver": "D-17", "door_open": true},
+	}}, nil)
+
+	r := gin.New()
+	r.GET("/vehicles/:vehicle_id/history", handler.GetLocationHistory)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/vehicles/TEST123/history?start=2024-05-01T00:00:00Z&end=2024-05-02T00:00:00Z"+
+		"&attr[ignition]=false&attr[fuel_level]=20&attr[driver]=D-17&attributes=fuel_level,%20door_open", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+	assert.Contains(t, w.Body.String(), `"attributes":{"door_open":true,"fuel_level":20}`)
+}
+
+func TestGetLocationHistory_QuotedAttributeFilterIsString(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockRepo := new(mockVehicleRepo)
+	handler := NewVehicleHandler(mockRepo)
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	mockRepo.On("FindLocations", repository.LocationFilter{
+		VehicleID:  "TEST123",
+		Start:      start,
+		End:        start.Add(24 * time.Hour),
+		Attributes: map[string]any{"driver": "007", "plate_suffix": "1e3", "mode": "true", "fuel_level": 7.0},
+	}).Return([]*model.VehicleLocation{}, nil)
+
+	r := gin.New()
+	r.GET("/vehicles/:vehicle_id/history", handler.GetLocationHistory)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/vehicles/TEST123/history?start=2024-05-01T00:00:00Z&end=2024-05-02T00:00:00Z"+
+		"&attr[driver]=%22007%22&attr[plate_suffix]=%221e3%22&attr[mode]=%22true%22&attr[fuel_level]=007", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetLocationHistory_AttributeFilterErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockRepo := new(mockVehicleRepo)
+	handler := NewVehicleHandler(mockRepo)
+	mockRepo.On("FindLocations", mock.Anything).Return(nil, errors.New("connection refused"))
+
+	r := gin.New()
+	r.GET("/vehicles/:vehicle_id/history", handler.GetLocationHistory)
+	const url = "/vehicles/TEST123/history?start=2024-05-01T00:00:00Z&end=2024-05-02T00:00:00Z"
+
+	for _, value := range []string{"NaN", "Inf", "-infinity", "1e999"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", url+"&attr[fuel_level]="+value, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, value)
+		assert.Contains(t, w.Body.String(), "attr[fuel_level] must be a finite number")
+	}
+	mockRepo.AssertNotCalled(t, "FindLocations", mock.Anything)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", url+"&attr[ignition]=true", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code, "query failures are not a missing vehicle")
+}
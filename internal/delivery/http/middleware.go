@@ -1,16 +1,33 @@
 package http
 
 import (
+	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/metrics"
 )
 
+// RequestIDHeader carries the request ID in both directions. A client may
+// send one to correlate its own logs; otherwise one is generated.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the client supplied request IDs that are kept
+const maxRequestIDLength = 128
+
+var httpLog = logging.Component("http")
+
 func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+RequestIDHeader)
+		c.Writer.Header().Set("Access-Control-Expose-Headers", RequestIDHeader)
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
 			return
@@ -18,9 +35,59 @@ func corsMiddleware() gin.HandlerFunc {
 	}
 }
 
+// metricsMiddleware records request counts and durations, labelled by the
+// route pattern so that path parameters don't create a series per vehicle
+func metricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		metrics.HTTPRequests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(method, route).Observe(metrics.Since(start))
+	}
+}
+
+// requestIDMiddleware puts the request ID on the request context, where the
+// handlers pass it on to the repositories, and echoes it in the response
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = uuid.NewString()
+		}
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// loggingMiddleware logs each request once it has been handled. Server
+// errors are logged at error level.
 func loggingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		println("Request:", c.Request.Method, c.Request.URL.Path)
+		start := time.Now()
 		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+		}
+		if vehicleID := c.Param("vehicle_id"); vehicleID != "" {
+			attrs = append(attrs, logging.VehicleID(vehicleID))
+		}
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		httpLog.LogAttrs(c.Request.Context(), level, "Request handled", attrs...)
 	}
 }
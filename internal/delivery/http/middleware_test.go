@@ -0,0 +1,69 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/metrics"
+)
+
+func TestMetricsMiddleware_LabelsByRoutePattern(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(metricsMiddleware())
+	r.GET("/vehicles/:vehicle_id/location", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	matched := metrics.HTTPRequests.WithLabelValues("GET", "/vehicles/:vehicle_id/location", "200")
+	unmatched := metrics.HTTPRequests.WithLabelValues("GET", "unmatched", "404")
+	matchedBefore, unmatchedBefore := testutil.ToFloat64(matched), testutil.ToFloat64(unmatched)
+
+	for _, path := range []string{"/vehicles/A/location", "/vehicles/B/location", "/nowhere"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	assert.Equal(t, matchedBefore+2, testutil.ToFloat64(matched))
+	assert.Equal(t, unmatchedBefore+1, testutil.ToFloat64(unmatched))
+}
+
+func TestSetupRouter_ServesMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := SetupRouter(NewVehicleHandler(new(mockVehicleRepo)), nil, nil, nil)
+
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest("GET", "/healthz", nil))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `vehicle_tracker_http_requests_total{method="GET",route="/healthz",status="200"}`)
+}
+
+func TestRequestIDMiddleware_PropagatesID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(requestIDMiddleware())
+	var seen string
+	r.GET("/ping", func(c *gin.Context) {
+		seen = logging.RequestID(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/ping", nil)
+	req.Header.Set(RequestIDHeader, "client-id")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "client-id", seen)
+	assert.Equal(t, "client-id", w.Header().Get(RequestIDHeader))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/ping", nil))
+	assert.NotEmpty(t, seen)
+	assert.NotEqual(t, "client-id", seen)
+	assert.Equal(t, seen, w.Header().Get(RequestIDHeader), "a generated ID is returned to the client")
+}
@@ -4,10 +4,25 @@ import (
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/metrics"
 )
 
-func SetupRouter(handler *VehicleHandler) *gin.Engine {
-	router := gin.Default()
+// ServiceName identifies the API in traces
+const ServiceName = "vehicle-tracker-api"
+
+// SetupRouter registers the API routes. deadLetterHandler may be nil when no
+// queue is configured, in which case the admin routes are left out.
+func SetupRouter(handler *VehicleHandler, geofenceHandler *GeofenceHandler, assignmentHandler *AssignmentHandler, deadLetterHandler *DeadLetterHandler) *gin.Engine {
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(requestIDMiddleware())
+	router.Use(otelgin.Middleware(ServiceName))
+	router.Use(metricsMiddleware())
+
+	// Prometheus metrics
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
 
 	// Health check endpoint
 	router.GET("/healthz", handler.HealthCheck)
@@ -26,6 +41,38 @@ func SetupRouter(handler *VehicleHandler) *gin.Engine {
 		vehicles.GET("/:vehicle_id/location", handler.GetLatestLocation)
 		vehicles.GET("/:vehicle_id/history", handler.GetLocationHistory)
 	}
+	geofences := api.Group("/geofences")
+	{
+		geofences.POST("", geofenceHandler.CreateGeofence)
+		geofences.GET("", geofenceHandler.ListGeofences)
+		geofences.GET("/:id", geofenceHandler.GetGeofence)
+		geofences.PUT("/:id", geofenceHandler.UpdateGeofence)
+		geofences.POST("/:id/activate", geofenceHandler.ActivateGeofence)
+		geofences.POST("/:id/deactivate", geofenceHandler.DeactivateGeofence)
+		geofences.DELETE("/:id", geofenceHandler.DeleteGeofence)
+		geofences.GET("/:id/assignments", assignmentHandler.ListGeofenceAssignments)
+		geofences.POST("/:id/assignments", assignmentHandler.CreateGeofenceAssignment)
+		geofences.DELETE("/:id/assignments/:assignment_id", assignmentHandler.DeleteGeofenceAssignment)
+	}
+	groups := api.Group("/vehicle-groups")
+	{
+		groups.POST("", assignmentHandler.CreateVehicleGroup)
+		groups.GET("", assignmentHandler.ListVehicleGroups)
+		groups.DELETE("/:id", assignmentHandler.DeleteVehicleGroup)
+		groups.GET("/:id/vehicles", assignmentHandler.ListGroupVehicles)
+		groups.PUT("/:id/vehicles/:vehicle_id", assignmentHandler.AddGroupVehicle)
+		groups.DELETE("/:id/vehicles/:vehicle_id", assignmentHandler.RemoveGroupVehicle)
+	}
+	if deadLetterHandler != nil {
+		deadLetters := api.Group("/admin/dead-letters")
+		{
+			deadLetters.GET("", deadLetterHandler.ListDeadLetters)
+			deadLetters.POST("/replay", deadLetterHandler.ReplayDeadLetters)
+			deadLetters.POST("/purge", deadLetterHandler.PurgeDeadLetters)
+			deadLetters.GET("/:id", deadLetterHandler.GetDeadLetter)
+			deadLetters.DELETE("/:id", deadLetterHandler.DeleteDeadLetter)
+		}
+	}
 
 	return router
 }
@@ -1,6 +1,11 @@
 package http
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+)
 
 // API response structures
 type Response struct {
@@ -24,14 +29,23 @@ func (e *NotFoundError) Error() string {
 	return e.Message
 }
 
-// Location and health structures
+// Location and health structures. Telemetry the device didn't report is
+// left out; timestamp is the device time and received_at the server time.
 type LocationResponse struct {
-	VehicleID string    `json:"vehicle_id"`
-	Latitude  float64   `json:"latitude"`
-	Longitude float64   `json:"longitude"`
-	Timestamp time.Time `json:"timestamp"`
-	Speed     float64   `json:"speed,omitempty"`
-	Heading   float64   `json:"heading,omitempty"`
+	ID         int64      `json:"id" example:"1"`
+	VehicleID  string     `json:"vehicle_id" example:"B1234XYZ"`
+	Latitude   float64    `json:"latitude" example:"-6.193125"`
+	Longitude  float64    `json:"longitude" example:"106.820233"`
+	Speed      float64    `json:"speed" example:"42.5"`
+	Heading    *float64   `json:"heading,omitempty" example:"90"`
+	Altitude   *float64   `json:"altitude,omitempty" example:"12.5"`
+	HDOP       *float64   `json:"hdop,omitempty" example:"0.9"`
+	Accuracy   *float64   `json:"accuracy,omitempty" example:"3.5"`
+	Satellites *int       `json:"satellites,omitempty" example:"9"`
+	Timestamp  time.Time  `json:"timestamp"`
+	ReceivedAt *time.Time `json:"received_at,omitempty"`
+	// Sensor and IO values, e.g. {"ignition": true, "fuel_level": 62.5}
+	Attributes map[string]any `json:"attributes,omitempty" swaggertype:"object"`
 }
 
 type LocationHistoryRequest struct {
@@ -45,3 +59,58 @@ type HealthResponse struct {
 	Service   string    `json:"service"`
 	Timestamp time.Time `json:"timestamp"`
 }
+
+// Geofence structures
+type GeofenceRequest struct {
+	Name      string  `json:"name" example:"Bundaran HI"`
+	Shape     string  `json:"shape" example:"circle" enums:"circle,polygon,multipolygon"`
+	CenterLat float64 `json:"center_lat" example:"-6.193125"`
+	CenterLng float64 `json:"center_lng" example:"106.820233"`
+	Radius    float64 `json:"radius" example:"100"`
+	// GeoJSON-style [lng, lat] coordinates, required for polygon shapes
+	Coordinates json.RawMessage `json:"coordinates,omitempty" swaggertype:"object"`
+	// Meters beyond the boundary a vehicle must reach to exit, greater than 0
+	// and at most 1000; omitted uses the default of 5
+	Hysteresis *float64 `json:"hysteresis,omitempty" example:"5"`
+	// Minutes inside before a geofence_dwell event; 0 disables it
+	DwellMinutes int `json:"dwell_minutes,omitempty" example:"45"`
+	// Daily HH:MM time, in the schedule's timezone or UTC, after which vehicles still inside raise geofence_overdue
+	DepartBy string `json:"depart_by,omitempty" example:"18:00"`
+	// Optional windows outside of which the geofence is not enforced
+	Schedule *model.GeofenceSchedule `json:"schedule,omitempty"`
+	// Omitted means active on create and unchanged on update
+	Active *bool `json:"active,omitempty"`
+}
+
+type VehicleGroupRequest struct {
+	Name string `json:"name" example:"Depot A buses"`
+}
+
+// GeofenceAssignmentRequest assigns a geofence to exactly one of a vehicle or
+// a vehicle group
+type GeofenceAssignmentRequest struct {
+	VehicleID string `json:"vehicle_id,omitempty" example:"BUS-001"`
+	GroupID   int64  `json:"group_id,omitempty" example:"1"`
+}
+
+type GeofenceListResponse struct {
+	Count     int               `json:"count"`
+	Geofences []*model.Geofence `json:"geofences"`
+}
+
+// Dead letter structures
+type DeadLetterListResponse struct {
+	Count   int                     `json:"count"`
+	Entries []model.DeadLetterEntry `json:"entries"`
+}
+
+// DeadLetterSelection picks dead letter entries to replay or purge, either by
+// ID or by filter. Selecting everything requires all to be set.
+type DeadLetterSelection struct {
+	IDs   []string   `json:"ids,omitempty" example:"42"`
+	Queue string     `json:"queue,omitempty" example:"event_log:stream"`
+	Error string     `json:"error,omitempty" example:"connection refused"`
+	Since *time.Time `json:"since,omitempty"`
+	Until *time.Time `json:"until,omitempty"`
+	All   bool       `json:"all,omitempty"`
+}
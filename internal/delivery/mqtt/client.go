@@ -1,24 +1,29 @@
 package mqtt
 
 import (
-	"log"
+	"log/slog"
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/config"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
 )
 
+var clientLog = logging.Component("mqtt_client")
+
 type MQTTClient struct {
 	client mqtt.Client
-	config *MQTTConfig
+	config config.MQTTConfig
 }
 
-func NewMQTTClient(config *MQTTConfig) *MQTTClient {
-	return &MQTTClient{config: config}
+func NewMQTTClient(cfg config.MQTTConfig) *MQTTClient {
+	return &MQTTClient{config: cfg}
 }
 
 func (c *MQTTClient) Connect() error {
 	options := mqtt.NewClientOptions()
-	options.AddBroker(c.config.GetBrokerURL())
+	options.AddBroker(c.config.BrokerURL())
 	options.SetClientID(c.config.ClientID)
 
 	if c.config.Username != "" {
@@ -30,34 +35,36 @@ func (c *MQTTClient) Connect() error {
 	options.SetConnectTimeout(10 * time.Second)
 	options.SetAutoReconnect(true)
 	options.SetConnectRetry(true)
+	// Deliver messages to the handler one at a time in arrival order, so
+	// each vehicle's updates are queued in the order they were published
+	options.SetOrderMatters(true)
 
-	log.Printf("[MQTT CLIENT] Attempting to connect to MQTT broker: %s", c.config.GetBrokerURL())
+	clientLog.Info("Connecting to MQTT broker", slog.String("broker", c.config.BrokerURL()))
 	c.client = mqtt.NewClient(options)
 
 	if token := c.client.Connect(); token.Wait() && token.Error() != nil {
-		log.Printf("[MQTT CLIENT] MQTT connection failed: %v", token.Error())
+		clientLog.Error("MQTT connection failed", slog.String("broker", c.config.BrokerURL()), logging.Err(token.Error()))
 		return token.Error()
 	}
 
-	log.Printf("[MQTT CLIENT] Successfully connected to MQTT broker")
-	log.Printf("[MQTT CLIENT] MQTT client is now listening for messages...")
+	clientLog.Info("Connected to MQTT broker", slog.String("broker", c.config.BrokerURL()))
 	return nil
 }
 
 func (c *MQTTClient) Subscribe(topic string, callback mqtt.MessageHandler) error {
-	log.Printf("[MQTT CLIENT] Subscribing to topic: %s", topic)
+	clientLog.Info("Subscribing to topic", logging.Queue(topic))
 	if token := c.client.Subscribe(topic, 0, callback); token.Wait() && token.Error() != nil {
-		log.Printf("[MQTT CLIENT] Failed to subscribe: %v", token.Error())
+		clientLog.Error("Failed to subscribe", logging.Queue(topic), logging.Err(token.Error()))
 		return token.Error()
 	}
 
-	log.Printf("[MQTT CLIENT] Successfully subscribed to topic")
+	clientLog.Info("Subscribed to topic", logging.Queue(topic))
 	return nil
 }
 
 func (c *MQTTClient) Disconnect() {
 	if c.client != nil {
 		c.client.Disconnect(250)
-		log.Println("[MQTT CLIENT] Disconnected from MQTT broker")
+		clientLog.Info("Disconnected from MQTT broker")
 	}
 }
@@ -1,21 +1,72 @@
 package mqtt
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/app/service"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/metrics"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/queue"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func MessageHandler(rdb *redis.Client) mqtt.MessageHandler {
+var handlerLog = logging.Component("mqtt_handler")
+
+// MessageHandler validates every received location update and pushes it to
+// Redis under ctx, or quarantines it with the reason it was refused. Pushes
+// run synchronously, since paho delivers messages one at a time in order and
+// the workers rely on each vehicle's updates reaching the stream in that
+// order. Each push is tracked in inflight so that shutdown can wait for it.
+// Every message starts a trace that the workers continue.
+func MessageHandler(ctx context.Context, rdb *redis.Client, validator *service.LocationValidator, inflight *sync.WaitGroup) mqtt.MessageHandler {
+	// Export every reason from the start, so rates don't begin at a gap
+	for _, reason := range service.IngestRejectReasons {
+		metrics.IngestRejected.WithLabelValues(reason)
+	}
+	quarantine := queue.NewRedisQueue(rdb, "")
+
 	return func(client mqtt.Client, msg mqtt.Message) {
-		log.Printf("[MQTT_HANDLER] Received message on topic %s: %s", msg.Topic(), string(msg.Payload()))
-		go func() {
-			if err := service.PushLocationUpdateToRedis(rdb, "location_update", "mqtt-subscriber", msg.Payload()); err != nil {
-				log.Printf("[MQTT_HANDLER] Failed to push raw event to Redis: %v", err)
+		metrics.MQTTMessagesReceived.Inc()
+		receivedAt := time.Now()
+		msgCtx, span := tracing.Tracer().Start(ctx, "MessageHandler",
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(attribute.String("mqtt.topic", msg.Topic())))
+		handlerLog.DebugContext(msgCtx, "Received message", logging.Queue(msg.Topic()), slog.String("payload", string(msg.Payload())))
+
+		loc, err := validator.Validate(msg.Payload(), receivedAt)
+		var rejected *service.IngestError
+		if errors.As(err, &rejected) {
+			metrics.IngestRejected.WithLabelValues(rejected.Reason).Inc()
+			span.SetAttributes(attribute.String("ingest.rejected_reason", rejected.Reason))
+			attrs := []any{logging.Queue(msg.Topic()), slog.String("reason", rejected.Reason), logging.Err(rejected.Err)}
+			if loc != nil {
+				attrs = append(attrs, logging.VehicleID(loc.VehicleID))
+			}
+			handlerLog.WarnContext(msgCtx, "Quarantined invalid location update", attrs...)
+		}
+
+		inflight.Add(1)
+		defer inflight.Done()
+		if rejected != nil {
+			err = service.QuarantineLocationUpdate(msgCtx, quarantine, msg.Topic(), msg.Payload(), receivedAt, rejected)
+			if err != nil {
+				handlerLog.ErrorContext(msgCtx, "Failed to quarantine location update", logging.Queue(service.QuarantineTopic), logging.Err(err))
+			}
+		} else {
+			err = service.PushLocationUpdateToRedis(msgCtx, rdb, "location_update", "mqtt-subscriber", msg.Payload())
+			if err != nil {
+				handlerLog.ErrorContext(msgCtx, "Failed to push location update to Redis", logging.Queue(msg.Topic()), logging.Err(err))
 			}
-		}()
+		}
+		tracing.End(span, err)
 	}
 }
@@ -0,0 +1,60 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/app/service"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+)
+
+// fakeMessage is a received MQTT message
+type fakeMessage struct {
+	topic   string
+	payload []byte
+}
+
+func (m fakeMessage) Duplicate() bool   { return false }
+func (m fakeMessage) Qos() byte         { return 0 }
+func (m fakeMessage) Retained() bool    { return false }
+func (m fakeMessage) Topic() string     { return m.topic }
+func (m fakeMessage) MessageID() uint16 { return 0 }
+func (m fakeMessage) Payload() []byte   { return m.payload }
+func (m fakeMessage) Ack()              {}
+
+func TestMessageHandler_PushesInOrder(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	validator, err := service.NewLocationValidator(`^[A-Za-z0-9_-]{1,64}$`, 5*time.Minute, time.Hour)
+	require.NoError(t, err)
+	var inflight sync.WaitGroup
+	handler := MessageHandler(context.Background(), rdb, validator, &inflight)
+
+	base := time.Now().UTC().Add(-time.Minute).Truncate(time.Second)
+	const updates = 20
+	for i := range updates {
+		payload := fmt.Sprintf(`{"vehicle_id":"BUS-001","latitude":-6.19,"longitude":106.82,"timestamp":%q}`,
+			base.Add(time.Duration(i)*time.Second).Format(time.RFC3339))
+		handler(nil, fakeMessage{topic: "fleet/vehicle/BUS-001/location", payload: []byte(payload)})
+	}
+
+	entries, err := rdb.XRange(context.Background(), service.VehicleLocationTopic, "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, entries, updates, "every push completes before the handler returns")
+	for i, entry := range entries {
+		var envelope model.EventEnvelope
+		require.NoError(t, json.Unmarshal([]byte(entry.Values["data"].(string)), &envelope))
+		var loc model.VehicleLocation
+		require.NoError(t, json.Unmarshal(envelope.Payload, &loc))
+		assert.True(t, base.Add(time.Duration(i)*time.Second).Equal(loc.Timestamp), "update %d out of order", i)
+	}
+}
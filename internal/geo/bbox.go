@@ -0,0 +1,91 @@
+package geo
+
+import (
+	"math"
+)
+
+// BBox is an axis-aligned bounding box in decimal degrees.
+// Boxes crossing the antimeridian are not supported.
+type BBox struct {
+	MinLat float64
+	MinLng float64
+	MaxLat float64
+	MaxLng float64
+}
+
+// Contains reports whether p lies inside or on the edge of the box
+func (b BBox) Contains(p Point) bool {
+	return p.Lat >= b.MinLat && p.Lat <= b.MaxLat && p.Lng >= b.MinLng && p.Lng <= b.MaxLng
+}
+
+// Intersects reports whether the boxes overlap
+func (b BBox) Intersects(o BBox) bool {
+	return b.MinLat <= o.MaxLat && b.MaxLat >= o.MinLat && b.MinLng <= o.MaxLng && b.MaxLng >= o.MinLng
+}
+
+// Union returns the smallest box containing both boxes
+func (b BBox) Union(o BBox) BBox {
+	return BBox{
+		MinLat: math.Min(b.MinLat, o.MinLat),
+		MinLng: math.Min(b.MinLng, o.MinLng),
+		MaxLat: math.Max(b.MaxLat, o.MaxLat),
+		MaxLng: math.Max(b.MaxLng, o.MaxLng),
+	}
+}
+
+// Expand grows the box by the given distance in meters on every side
+func (b BBox) Expand(meters float64) BBox {
+	dLat := metersToLat(meters)
+	// Widen by the longitude span at the latitude furthest from the equator
+	dLng := metersToLng(meters, math.Max(math.Abs(b.MinLat), math.Abs(b.MaxLat)))
+	return BBox{
+		MinLat: math.Max(-90, b.MinLat-dLat),
+		MinLng: math.Max(-180, b.MinLng-dLng),
+		MaxLat: math.Min(90, b.MaxLat+dLat),
+		MaxLng: math.Min(180, b.MaxLng+dLng),
+	}
+}
+
+// CircleBBox returns the box enclosing a circle of radius meters around center
+func CircleBBox(center Point, radius float64) BBox {
+	return BBox{MinLat: center.Lat, MinLng: center.Lng, MaxLat: center.Lat, MaxLng: center.Lng}.Expand(radius)
+}
+
+// BBox returns the box enclosing the ring
+func (r Ring) BBox() BBox {
+	b := BBox{MinLat: math.Inf(1), MinLng: math.Inf(1), MaxLat: math.Inf(-1), MaxLng: math.Inf(-1)}
+	for _, p := range r {
+		b = b.Union(BBox{MinLat: p.Lat, MinLng: p.Lng, MaxLat: p.Lat, MaxLng: p.Lng})
+	}
+	return b
+}
+
+// BBox returns the box enclosing the outer ring; holes lie within it
+func (pg Polygon) BBox() BBox {
+	return pg.Outer.BBox()
+}
+
+// BBox returns the box enclosing every polygon
+func (mp MultiPolygon) BBox() BBox {
+	b := BBox{MinLat: math.Inf(1), MinLng: math.Inf(1), MaxLat: math.Inf(-1), MaxLng: math.Inf(-1)}
+	for _, pg := range mp {
+		b = b.Union(pg.BBox())
+	}
+	return b
+}
+
+func (b BBox) center() Point {
+	return Point{Lat: (b.MinLat + b.MaxLat) / 2, Lng: (b.MinLng + b.MaxLng) / 2}
+}
+
+func metersToLat(meters float64) float64 {
+	return meters / earthRadius * 180 / math.Pi
+}
+
+func metersToLng(meters, lat float64) float64 {
+	cos := math.Cos(lat * math.Pi / 180)
+	if cos < 1e-9 {
+		return 180
+	}
+	return metersToLat(meters) / cos
+}
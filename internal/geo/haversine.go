@@ -3,7 +3,6 @@ package geo
 import "math"
 
 func Haversine(lat1, lon1, lat2, lon2 float64) float64 {
-	const R = 6371000 // Earth radius (meters)
 	lat1Rad := lat1 * math.Pi / 180
 	lat2Rad := lat2 * math.Pi / 180
 	deltaLat := (lat2 - lat1) * math.Pi / 180
@@ -14,5 +13,5 @@ func Haversine(lat1, lon1, lat2, lon2 float64) float64 {
 			math.Sin(deltaLon/2)*math.Sin(deltaLon/2)
 	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
 
-	return R * c
+	return earthRadius * c
 }
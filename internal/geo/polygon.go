@@ -0,0 +1,277 @@
+package geo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const earthRadius = 6371000 // Earth radius (meters)
+
+// Point is a WGS84 coordinate in decimal degrees
+type Point struct {
+	Lat float64
+	Lng float64
+}
+
+// Ring is a closed boundary. The closing point may be repeated or omitted.
+type Ring []Point
+
+// Polygon is an outer boundary with optional holes
+type Polygon struct {
+	Outer Ring
+	Holes []Ring
+}
+
+// MultiPolygon is a set of disjoint polygons treated as a single area
+type MultiPolygon []Polygon
+
+// Contains reports whether p lies inside the ring using ray casting
+func (r Ring) Contains(p Point) bool {
+	inside := false
+	n := len(r)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		a, b := r[i], r[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) {
+			lngAtLat := (b.Lng-a.Lng)*(p.Lat-a.Lat)/(b.Lat-a.Lat) + a.Lng
+			if p.Lng < lngAtLat {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// DistanceToEdge returns the distance in meters from p to the closest ring edge
+func (r Ring) DistanceToEdge(p Point) float64 {
+	n := len(r)
+	if n == 0 {
+		return math.Inf(1)
+	}
+	if n == 1 {
+		return Haversine(p.Lat, p.Lng, r[0].Lat, r[0].Lng)
+	}
+	nearest := math.Inf(1)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		if d := DistanceToSegment(p, r[j], r[i]); d < nearest {
+			nearest = d
+		}
+	}
+	return nearest
+}
+
+// Contains reports whether p lies inside the outer ring and outside every hole
+func (pg Polygon) Contains(p Point) bool {
+	if !pg.Outer.Contains(p) {
+		return false
+	}
+	for _, hole := range pg.Holes {
+		if hole.Contains(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// DistanceToEdge returns the distance in meters from p to the closest boundary,
+// holes included
+func (pg Polygon) DistanceToEdge(p Point) float64 {
+	nearest := pg.Outer.DistanceToEdge(p)
+	for _, hole := range pg.Holes {
+		if d := hole.DistanceToEdge(p); d < nearest {
+			nearest = d
+		}
+	}
+	return nearest
+}
+
+// Contains reports whether p lies inside any of the polygons
+func (mp MultiPolygon) Contains(p Point) bool {
+	for _, pg := range mp {
+		if pg.Contains(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// DistanceToEdge returns the distance in meters from p to the closest boundary
+// of any polygon
+func (mp MultiPolygon) DistanceToEdge(p Point) float64 {
+	nearest := math.Inf(1)
+	for _, pg := range mp {
+		if d := pg.DistanceToEdge(p); d < nearest {
+			nearest = d
+		}
+	}
+	return nearest
+}
+
+// IntersectsSegment reports whether the segment a-b touches any ring edge.
+// The test is planar in degrees, which is adequate for geofence-sized shapes.
+func (r Ring) IntersectsSegment(a, b Point) bool {
+	n := len(r)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		if SegmentsIntersect(a, b, r[j], r[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// IntersectsSegment reports whether the segment a-b touches any boundary of
+// the polygon, holes included
+func (pg Polygon) IntersectsSegment(a, b Point) bool {
+	if pg.Outer.IntersectsSegment(a, b) {
+		return true
+	}
+	for _, hole := range pg.Holes {
+		if hole.IntersectsSegment(a, b) {
+			return true
+		}
+	}
+	return false
+}
+
+// IntersectsSegment reports whether the segment a-b touches the boundary of
+// any polygon
+func (mp MultiPolygon) IntersectsSegment(a, b Point) bool {
+	for _, pg := range mp {
+		if pg.IntersectsSegment(a, b) {
+			return true
+		}
+	}
+	return false
+}
+
+// SegmentsIntersect reports whether segments p1-p2 and q1-q2 share a point
+func SegmentsIntersect(p1, p2, q1, q2 Point) bool {
+	d1 := orientation(q1, q2, p1)
+	d2 := orientation(q1, q2, p2)
+	d3 := orientation(p1, p2, q1)
+	d4 := orientation(p1, p2, q2)
+
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	// Collinear cases: an endpoint lying on the other segment
+	return (d1 == 0 && onSegment(q1, q2, p1)) ||
+		(d2 == 0 && onSegment(q1, q2, p2)) ||
+		(d3 == 0 && onSegment(p1, p2, q1)) ||
+		(d4 == 0 && onSegment(p1, p2, q2))
+}
+
+// orientation returns the sign of the cross product (b-a) x (c-a)
+func orientation(a, b, c Point) float64 {
+	return (b.Lng-a.Lng)*(c.Lat-a.Lat) - (b.Lat-a.Lat)*(c.Lng-a.Lng)
+}
+
+// onSegment reports whether c, known to be collinear with a-b, lies within it
+func onSegment(a, b, c Point) bool {
+	return math.Min(a.Lng, b.Lng) <= c.Lng && c.Lng <= math.Max(a.Lng, b.Lng) &&
+		math.Min(a.Lat, b.Lat) <= c.Lat && c.Lat <= math.Max(a.Lat, b.Lat)
+}
+
+// DistanceToSegment returns the distance in meters from p to the segment a-b.
+// Coordinates are projected onto a local equirectangular plane centred on p,
+// which is accurate for geofence-sized shapes.
+func DistanceToSegment(p, a, b Point) float64 {
+	ax, ay := project(p, a)
+	bx, by := project(p, b)
+
+	dx, dy := bx-ax, by-ay
+	lengthSq := dx*dx + dy*dy
+	t := 0.0
+	if lengthSq > 0 {
+		// p is the origin, so the projection factor is -a·(b-a) / |b-a|²
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/lengthSq))
+	}
+	cx, cy := ax+t*dx, ay+t*dy
+	return math.Hypot(cx, cy)
+}
+
+// project maps q to meters east/north of origin
+func project(origin, q Point) (float64, float64) {
+	dLng := q.Lng - origin.Lng
+	if dLng > 180 {
+		dLng -= 360
+	} else if dLng < -180 {
+		dLng += 360
+	}
+	x := dLng * math.Pi / 180 * earthRadius * math.Cos(origin.Lat*math.Pi/180)
+	y := (q.Lat - origin.Lat) * math.Pi / 180 * earthRadius
+	return x, y
+}
+
+// ParsePolygon decodes GeoJSON-style polygon coordinates
+// ([[[lng, lat], ...], ...]); the first ring is the outer boundary and any
+// further rings are holes.
+func ParsePolygon(data []byte) (Polygon, error) {
+	var coords [][][]float64
+	if err := json.Unmarshal(data, &coords); err != nil {
+		return Polygon{}, fmt.Errorf("invalid polygon coordinates: %w", err)
+	}
+	return polygonFromCoordinates(coords)
+}
+
+// ParseMultiPolygon decodes GeoJSON-style multi-polygon coordinates
+// ([[[[lng, lat], ...], ...], ...])
+func ParseMultiPolygon(data []byte) (MultiPolygon, error) {
+	var coords [][][][]float64
+	if err := json.Unmarshal(data, &coords); err != nil {
+		return nil, fmt.Errorf("invalid multipolygon coordinates: %w", err)
+	}
+	if len(coords) == 0 {
+		return nil, errors.New("multipolygon must contain at least one polygon")
+	}
+
+	mp := make(MultiPolygon, 0, len(coords))
+	for i, c := range coords {
+		pg, err := polygonFromCoordinates(c)
+		if err != nil {
+			return nil, fmt.Errorf("polygon %d: %w", i, err)
+		}
+		mp = append(mp, pg)
+	}
+	return mp, nil
+}
+
+func polygonFromCoordinates(coords [][][]float64) (Polygon, error) {
+	if len(coords) == 0 {
+		return Polygon{}, errors.New("polygon must contain an outer ring")
+	}
+
+	rings := make([]Ring, 0, len(coords))
+	for i, c := range coords {
+		ring, err := ringFromCoordinates(c)
+		if err != nil {
+			return Polygon{}, fmt.Errorf("ring %d: %w", i, err)
+		}
+		rings = append(rings, ring)
+	}
+	return Polygon{Outer: rings[0], Holes: rings[1:]}, nil
+}
+
+func ringFromCoordinates(coords [][]float64) (Ring, error) {
+	ring := make(Ring, 0, len(coords))
+	for _, c := range coords {
+		if len(c) < 2 {
+			return nil, errors.New("position must have longitude and latitude")
+		}
+		p := Point{Lat: c[1], Lng: c[0]}
+		if p.Lat < -90 || p.Lat > 90 || p.Lng < -180 || p.Lng > 180 {
+			return nil, fmt.Errorf("position (%v, %v) out of range", c[0], c[1])
+		}
+		ring = append(ring, p)
+	}
+
+	// Drop the explicit closing point
+	if len(ring) > 1 && ring[0] == ring[len(ring)-1] {
+		ring = ring[:len(ring)-1]
+	}
+	if len(ring) < 3 {
+		return nil, errors.New("ring must have at least 3 distinct positions")
+	}
+	return ring, nil
+}
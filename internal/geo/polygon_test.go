@@ -0,0 +1,169 @@
+package geo
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Shapes are laid out near the equator so 0.001° ≈ 111.2m on both axes
+var (
+	square = Polygon{Outer: Ring{{0, 0}, {0, 0.01}, {0.01, 0.01}, {0.01, 0}}}
+
+	// L-shape with the north-east quadrant cut out
+	lShape = Polygon{Outer: Ring{
+		{0, 0}, {0, 0.02}, {0.01, 0.02}, {0.01, 0.01}, {0.02, 0.01}, {0.02, 0},
+	}}
+
+	squareWithHole = Polygon{
+		Outer: Ring{{0, 0}, {0, 0.03}, {0.03, 0.03}, {0.03, 0}},
+		Holes: []Ring{{{0.01, 0.01}, {0.01, 0.02}, {0.02, 0.02}, {0.02, 0.01}}},
+	}
+
+	twoSquares = MultiPolygon{
+		square,
+		{Outer: Ring{{0.02, 0.02}, {0.02, 0.03}, {0.03, 0.03}, {0.03, 0.02}}},
+	}
+)
+
+func TestPolygonContains(t *testing.T) {
+	tests := []struct {
+		name    string
+		polygon Polygon
+		point   Point
+		want    bool
+	}{
+		{"square center", square, Point{0.005, 0.005}, true},
+		{"square outside east", square, Point{0.005, 0.011}, false},
+		{"square outside south", square, Point{-0.001, 0.005}, false},
+		{"l-shape inner corner", lShape, Point{0.005, 0.015}, true},
+		{"l-shape notch", lShape, Point{0.015, 0.015}, false},
+		{"l-shape lower arm", lShape, Point{0.015, 0.005}, true},
+		{"hole excluded", squareWithHole, Point{0.015, 0.015}, false},
+		{"between outer and hole", squareWithHole, Point{0.005, 0.005}, true},
+		{"outside outer ring", squareWithHole, Point{0.035, 0.015}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.polygon.Contains(tt.point))
+		})
+	}
+}
+
+func TestMultiPolygonContains(t *testing.T) {
+	tests := []struct {
+		name  string
+		point Point
+		want  bool
+	}{
+		{"first polygon", Point{0.005, 0.005}, true},
+		{"second polygon", Point{0.025, 0.025}, true},
+		{"gap between polygons", Point{0.015, 0.015}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, twoSquares.Contains(tt.point))
+		})
+	}
+}
+
+func TestDistanceToEdge(t *testing.T) {
+	degree := earthRadius * math.Pi / 180 // meters per degree at the equator
+
+	tests := []struct {
+		name    string
+		polygon MultiPolygon
+		point   Point
+		want    float64
+	}{
+		{"square center", MultiPolygon{square}, Point{0.005, 0.005}, 0.005 * degree},
+		{"just outside square", MultiPolygon{square}, Point{0.005, 0.011}, 0.001 * degree},
+		{"on edge", MultiPolygon{square}, Point{0, 0.005}, 0},
+		{"beyond corner", MultiPolygon{square}, Point{0.02, 0.02},
+			Haversine(0.02, 0.02, 0.01, 0.01)},
+		{"l-shape notch", MultiPolygon{lShape}, Point{0.012, 0.015}, 0.002 * degree},
+		{"inside hole", MultiPolygon{squareWithHole}, Point{0.015, 0.015}, 0.005 * degree},
+		{"closest polygon wins", twoSquares, Point{0.015, 0.005}, 0.005 * degree},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, tt.polygon.DistanceToEdge(tt.point), 1.0)
+		})
+	}
+}
+
+func TestParsePolygon(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		points  int
+		holes   int
+		wantErr bool
+	}{
+		{"closed ring", `[[[0,0],[0.01,0],[0.01,0.01],[0,0.01],[0,0]]]`, 4, 0, false},
+		{"open ring", `[[[0,0],[0.01,0],[0.01,0.01]]]`, 3, 0, false},
+		{"with hole", `[[[0,0],[0.03,0],[0.03,0.03],[0,0.03]],[[0.01,0.01],[0.02,0.01],[0.02,0.02]]]`, 4, 1, false},
+		{"too few positions", `[[[0,0],[0.01,0],[0,0]]]`, 0, 0, true},
+		{"latitude out of range", `[[[0,0],[0,95],[1,1]]]`, 0, 0, true},
+		{"missing latitude", `[[[0],[0,1],[1,1]]]`, 0, 0, true},
+		{"empty", `[]`, 0, 0, true},
+		{"not json", `nope`, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pg, err := ParsePolygon([]byte(tt.input))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, pg.Outer, tt.points)
+			assert.Len(t, pg.Holes, tt.holes)
+		})
+	}
+}
+
+func TestParseMultiPolygon(t *testing.T) {
+	mp, err := ParseMultiPolygon([]byte(`[
+		[[[0,0],[0.01,0],[0.01,0.01],[0,0.01]]],
+		[[[0.02,0.02],[0.03,0.02],[0.03,0.03],[0.02,0.03]]]
+	]`))
+	require.NoError(t, err)
+	assert.Len(t, mp, 2)
+	// Positions are [lng, lat]
+	assert.Equal(t, Point{Lat: 0, Lng: 0.01}, mp[0].Outer[1])
+
+	_, err = ParseMultiPolygon([]byte(`[]`))
+	assert.Error(t, err)
+}
+
+func TestIntersectsSegment(t *testing.T) {
+	tests := []struct {
+		name    string
+		polygon MultiPolygon
+		a, b    Point
+		want    bool
+	}{
+		{"passes through square", MultiPolygon{square}, Point{-0.005, 0.005}, Point{0.015, 0.005}, true},
+		{"misses square", MultiPolygon{square}, Point{-0.005, 0.015}, Point{0.015, 0.015}, false},
+		{"ends inside square", MultiPolygon{square}, Point{-0.005, 0.005}, Point{0.005, 0.005}, true},
+		{"fully inside square", MultiPolygon{square}, Point{0.002, 0.002}, Point{0.008, 0.008}, false},
+		{"touches corner", MultiPolygon{square}, Point{-0.01, 0.01}, Point{0.01, -0.01}, true},
+		{"runs along edge", MultiPolygon{square}, Point{0, -0.005}, Point{0, 0.005}, true},
+		{"crosses l-shape notch only", MultiPolygon{lShape}, Point{0.015, 0.025}, Point{0.025, 0.015}, false},
+		{"crosses hole edge", MultiPolygon{squareWithHole}, Point{0.015, 0.015}, Point{0.015, 0.005}, true},
+		{"second polygon", twoSquares, Point{0.025, 0.015}, Point{0.025, 0.035}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.polygon.IntersectsSegment(tt.a, tt.b))
+		})
+	}
+}
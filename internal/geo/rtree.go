@@ -0,0 +1,101 @@
+package geo
+
+import (
+	"math"
+	"sort"
+)
+
+// rtreeNodeSize is the maximum number of entries per node
+const rtreeNodeSize = 16
+
+// RTree is a static R-tree over bounding boxes, bulk-loaded with
+// Sort-Tile-Recursive packing. Items are identified by their index in the
+// slice passed to NewRTree. Rebuild the tree when the item set changes.
+type RTree struct {
+	root *rtreeNode
+	size int
+}
+
+type rtreeNode struct {
+	box      BBox
+	children []*rtreeNode
+	item     int // leaf entries only
+}
+
+// NewRTree builds a tree over the given boxes
+func NewRTree(boxes []BBox) *RTree {
+	if len(boxes) == 0 {
+		return &RTree{}
+	}
+
+	nodes := make([]*rtreeNode, len(boxes))
+	for i, b := range boxes {
+		nodes[i] = &rtreeNode{box: b, item: i}
+	}
+	for len(nodes) > 1 {
+		nodes = packLevel(nodes)
+	}
+	return &RTree{root: nodes[0], size: len(boxes)}
+}
+
+// Len returns the number of indexed items
+func (t *RTree) Len() int {
+	return t.size
+}
+
+// SearchPoint returns the items whose boxes contain p, in no particular order
+func (t *RTree) SearchPoint(p Point) []int {
+	return t.Search(BBox{MinLat: p.Lat, MinLng: p.Lng, MaxLat: p.Lat, MaxLng: p.Lng})
+}
+
+// Search returns the items whose boxes intersect b, in no particular order
+func (t *RTree) Search(b BBox) []int {
+	if t.root == nil {
+		return nil
+	}
+	var result []int
+	stack := []*rtreeNode{t.root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !n.box.Intersects(b) {
+			continue
+		}
+		if n.children == nil {
+			result = append(result, n.item)
+			continue
+		}
+		stack = append(stack, n.children...)
+	}
+	return result
+}
+
+// packLevel groups nodes into parents of at most rtreeNodeSize children by
+// sorting into vertical slices by longitude, then by latitude within a slice
+func packLevel(nodes []*rtreeNode) []*rtreeNode {
+	parentCount := int(math.Ceil(float64(len(nodes)) / rtreeNodeSize))
+	sliceCount := int(math.Ceil(math.Sqrt(float64(parentCount))))
+	sliceSize := sliceCount * rtreeNodeSize
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].box.center().Lng < nodes[j].box.center().Lng
+	})
+
+	parents := make([]*rtreeNode, 0, parentCount)
+	for start := 0; start < len(nodes); start += sliceSize {
+		slice := nodes[start:min(start+sliceSize, len(nodes))]
+		sort.Slice(slice, func(i, j int) bool {
+			return slice[i].box.center().Lat < slice[j].box.center().Lat
+		})
+
+		for i := 0; i < len(slice); i += rtreeNodeSize {
+			children := append([]*rtreeNode(nil), slice[i:min(i+rtreeNodeSize, len(slice))]...)
+			box := children[0].box
+			for _, c := range children[1:] {
+				box = box.Union(c.box)
+			}
+			parents = append(parents, &rtreeNode{box: box, children: children})
+		}
+	}
+	return parents
+}
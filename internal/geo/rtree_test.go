@@ -0,0 +1,108 @@
+package geo
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// randomCircles scatters circles of 50-500m over greater Jakarta
+func randomCircles(n int, rng *rand.Rand) []BBox {
+	boxes := make([]BBox, n)
+	for i := range boxes {
+		center := Point{Lat: -6.4 + rng.Float64()*0.4, Lng: 106.6 + rng.Float64()*0.4}
+		boxes[i] = CircleBBox(center, 50+rng.Float64()*450)
+	}
+	return boxes
+}
+
+func linearSearch(boxes []BBox, p Point) []int {
+	var result []int
+	for i, b := range boxes {
+		if b.Contains(p) {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+func TestRTree_MatchesLinearScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range []int{1, 15, 16, 17, 300, 5000} {
+		boxes := randomCircles(n, rng)
+		tree := NewRTree(boxes)
+		assert.Equal(t, n, tree.Len())
+
+		for i := 0; i < 200; i++ {
+			p := Point{Lat: -6.4 + rng.Float64()*0.4, Lng: 106.6 + rng.Float64()*0.4}
+			got := tree.SearchPoint(p)
+			sort.Ints(got)
+			assert.Equal(t, linearSearch(boxes, p), got, "n=%d point=%v", n, p)
+		}
+	}
+}
+
+func TestRTree_SearchBox(t *testing.T) {
+	boxes := []BBox{
+		{MinLat: 0, MinLng: 0, MaxLat: 1, MaxLng: 1},
+		{MinLat: 2, MinLng: 2, MaxLat: 3, MaxLng: 3},
+		{MinLat: 0.5, MinLng: 0.5, MaxLat: 2.5, MaxLng: 2.5},
+	}
+	tree := NewRTree(boxes)
+
+	got := tree.Search(BBox{MinLat: 0.9, MinLng: 0.9, MaxLat: 1.1, MaxLng: 1.1})
+	sort.Ints(got)
+	assert.Equal(t, []int{0, 2}, got)
+	assert.Empty(t, tree.Search(BBox{MinLat: 5, MinLng: 5, MaxLat: 6, MaxLng: 6}))
+}
+
+func TestRTree_Empty(t *testing.T) {
+	tree := NewRTree(nil)
+	assert.Equal(t, 0, tree.Len())
+	assert.Empty(t, tree.SearchPoint(Point{0, 0}))
+}
+
+func TestCircleBBox(t *testing.T) {
+	center := Point{Lat: -6.193125, Lng: 106.820233}
+	box := CircleBBox(center, 100)
+
+	// Points just inside each edge of the circle stay in the box
+	assert.True(t, box.Contains(Point{Lat: center.Lat + 0.0008, Lng: center.Lng}))
+	assert.True(t, box.Contains(Point{Lat: center.Lat, Lng: center.Lng - 0.0008}))
+	assert.False(t, box.Contains(Point{Lat: center.Lat + 0.001, Lng: center.Lng}))
+	assert.InDelta(t, 200, Haversine(box.MinLat, center.Lng, box.MaxLat, center.Lng), 0.5)
+	assert.InDelta(t, 200, Haversine(center.Lat, box.MinLng, center.Lat, box.MaxLng), 0.5)
+}
+
+func BenchmarkPointLookup(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	boxes := randomCircles(10000, rng)
+	points := make([]Point, 1024)
+	for i := range points {
+		points[i] = Point{Lat: -6.4 + rng.Float64()*0.4, Lng: 106.6 + rng.Float64()*0.4}
+	}
+
+	b.Run("Linear", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			linearSearch(boxes, points[i%len(points)])
+		}
+	})
+
+	b.Run("RTree", func(b *testing.B) {
+		tree := NewRTree(boxes)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			tree.SearchPoint(points[i%len(points)])
+		}
+	})
+}
+
+func BenchmarkNewRTree(b *testing.B) {
+	boxes := randomCircles(10000, rand.New(rand.NewSource(1)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewRTree(boxes)
+	}
+}
@@ -0,0 +1,177 @@
+// Package logging sets up structured logging with log/slog. Entries are JSON
+// by default and carry the service name, the component that wrote them and,
+// when logged with a context, the request ID and trace ID of that context.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Keys shared by every service, so entries can be filtered the same way
+// whichever service wrote them
+const (
+	KeyService   = "service"
+	KeyComponent = "component"
+	KeyVehicleID = "vehicle_id"
+	KeyQueue     = "queue"
+	KeyEventType = "event_type"
+	KeyRequestID = "request_id"
+	KeyTraceID   = "trace_id"
+	KeySpanID    = "span_id"
+	KeyError     = "error"
+)
+
+// Setup installs and returns the default logger for service. level is debug,
+// info, warn or error and format is json or text. The standard log package is
+// routed through it too.
+func Setup(service, level, format string) (*slog.Logger, error) {
+	logger, err := New(os.Stderr, service, level, format)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(logger)
+	return logger, nil
+}
+
+// New returns a logger for service that writes to w
+func New(w io.Writer, service, level, format string) (*slog.Logger, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	switch format {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("log format %q must be json or text", format)
+	}
+	return slog.New(contextHandler{handler}).With(KeyService, service), nil
+}
+
+// ParseLevel parses debug, info, warn or error
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("log level %q must be debug, info, warn or error", level)
+}
+
+// Fatal logs msg at error level and exits
+func Fatal(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
+
+// Err is the attribute for an error
+func Err(err error) slog.Attr {
+	return slog.Any(KeyError, err)
+}
+
+// VehicleID is the attribute for the vehicle an entry is about
+func VehicleID(id string) slog.Attr {
+	return slog.String(KeyVehicleID, id)
+}
+
+// Queue is the attribute for a stream, topic or RabbitMQ queue
+func Queue(name string) slog.Attr {
+	return slog.String(KeyQueue, name)
+}
+
+// EventType is the attribute for the type of an event or alert
+func EventType(eventType string) slog.Attr {
+	return slog.String(KeyEventType, eventType)
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns ctx carrying the request ID, which every entry logged
+// with ctx then includes
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or ""
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler adds the request ID and trace context of the logging
+// context to each entry
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String(KeyRequestID, id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String(KeyTraceID, sc.TraceID().String()), slog.String(KeySpanID, sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// Component returns a logger that tags entries with the component name.
+// It writes through whatever default logger is installed when it logs, so
+// package-level component loggers pick up the logger Setup installs later.
+func Component(name string) *slog.Logger {
+	return slog.New(defaultHandler{wrap: func(h slog.Handler) slog.Handler {
+		return h.WithAttrs([]slog.Attr{slog.String(KeyComponent, name)})
+	}})
+}
+
+// defaultHandler applies wrap to the handler of the current default logger
+type defaultHandler struct {
+	wrap func(slog.Handler) slog.Handler
+}
+
+func (h defaultHandler) handler() slog.Handler {
+	return h.wrap(slog.Default().Handler())
+}
+
+func (h defaultHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return slog.Default().Handler().Enabled(ctx, level)
+}
+
+func (h defaultHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler().Handle(ctx, r)
+}
+
+func (h defaultHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return defaultHandler{wrap: func(base slog.Handler) slog.Handler {
+		return h.wrap(base).WithAttrs(attrs)
+	}}
+}
+
+func (h defaultHandler) WithGroup(name string) slog.Handler {
+	return defaultHandler{wrap: func(base slog.Handler) slog.Handler {
+		return h.wrap(base).WithGroup(name)
+	}}
+}
@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// decode returns the fields of the single JSON entry in buf
+func decode(t *testing.T, buf *bytes.Buffer) map[string]any {
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	return entry
+}
+
+func TestNew_AddsServiceAndContextFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "test-service", "info", "json")
+	require.NoError(t, err)
+
+	traceID, _ := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	spanID, _ := trace.SpanIDFromHex("0102030405060708")
+	ctx := trace.ContextWithSpanContext(WithRequestID(context.Background(), "req-1"),
+		trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+
+	logger.InfoContext(ctx, "saved", VehicleID("TJ001"), Err(errors.New("boom")))
+
+	entry := decode(t, &buf)
+	assert.Equal(t, "test-service", entry[KeyService])
+	assert.Equal(t, "req-1", entry[KeyRequestID])
+	assert.Equal(t, traceID.String(), entry[KeyTraceID])
+	assert.Equal(t, spanID.String(), entry[KeySpanID])
+	assert.Equal(t, "TJ001", entry[KeyVehicleID])
+	assert.Equal(t, "boom", entry[KeyError])
+}
+
+func TestNew_OmitsMissingContextFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "test-service", "info", "json")
+	require.NoError(t, err)
+
+	logger.InfoContext(context.Background(), "started")
+
+	entry := decode(t, &buf)
+	assert.NotContains(t, entry, KeyRequestID)
+	assert.NotContains(t, entry, KeyTraceID)
+}
+
+func TestNew_FiltersByLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "test-service", "warn", "text")
+	require.NoError(t, err)
+
+	logger.Info("hidden")
+	logger.Warn("shown")
+
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.Contains(t, buf.String(), "shown")
+}
+
+func TestNew_RejectsInvalidSettings(t *testing.T) {
+	_, err := New(&bytes.Buffer{}, "test-service", "verbose", "json")
+	assert.ErrorContains(t, err, "log level")
+	_, err = New(&bytes.Buffer{}, "test-service", "info", "xml")
+	assert.ErrorContains(t, err, "log format")
+}
+
+func TestComponent_UsesLoggerInstalledLater(t *testing.T) {
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	component := Component("stream")
+	var buf bytes.Buffer
+	logger, err := New(&buf, "test-service", "info", "json")
+	require.NoError(t, err)
+	slog.SetDefault(logger)
+
+	component.InfoContext(WithRequestID(context.Background(), "req-2"), "claimed", Queue("vehicle_location:stream"))
+
+	entry := decode(t, &buf)
+	assert.Equal(t, "test-service", entry[KeyService])
+	assert.Equal(t, "stream", entry[KeyComponent])
+	assert.Equal(t, "req-2", entry[KeyRequestID])
+	assert.Equal(t, "vehicle_location:stream", entry[KeyQueue])
+}
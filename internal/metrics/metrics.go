@@ -0,0 +1,135 @@
+// Package metrics defines the Prometheus metrics of every service and serves
+// them on /metrics. Metrics are registered with the default registry, which
+// also carries the Go runtime and process metrics.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
+)
+
+const namespace = "vehicle_tracker"
+
+var metricsLog = logging.Component("metrics")
+
+var (
+	MQTTMessagesReceived = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mqtt_messages_received_total",
+		Help:      "MQTT location messages received by the subscriber.",
+	})
+
+	IngestRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ingest_rejected_total",
+		Help:      "MQTT location messages quarantined by the subscriber, by reason.",
+	}, []string{"reason"})
+
+	RedisPushDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "redis_push_duration_seconds",
+		Help:      "Time taken to append a message to a Redis stream.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 12),
+	}, []string{"topic"})
+
+	RedisPushErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redis_push_errors_total",
+		Help:      "Messages that failed to be appended to a Redis stream.",
+	}, []string{"topic"})
+
+	WorkerProcessingDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "worker_processing_duration_seconds",
+		Help:      "Time a worker took to handle one delivery, a single message or a batch.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+	}, []string{"worker"})
+
+	WorkerMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "worker_messages_total",
+		Help:      "Messages handled by a worker, by result (ok or error).",
+	}, []string{"worker", "result"})
+
+	DBInsertFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_insert_failures_total",
+		Help:      "Failed database inserts by table.",
+	}, []string{"table"})
+
+	GeofenceEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "geofence_events_total",
+		Help:      "Geofence events saved, by event type.",
+	}, []string{"event_type"})
+
+	RabbitMQPublishFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rabbitmq_publish_failures_total",
+		Help:      "Alerts that failed to be published to RabbitMQ, by exchange.",
+	}, []string{"exchange"})
+
+	AlertsReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "alerts_received_total",
+		Help:      "Alerts received by the alert consumer, by event type.",
+	}, []string{"event_type"})
+
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests served by the API, by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to serve an HTTP request, by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// Result labels a handled message as ok or error
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// Since returns the seconds elapsed since start, for observing histograms
+func Since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
+
+// Handler serves the metrics of the default registry
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Serve starts an HTTP server for /metrics on addr in the background and
+// returns the function that shuts it down. An empty addr serves nothing.
+func Serve(addr string) (shutdown func(context.Context) error) {
+	if addr == "" {
+		return func(context.Context) error { return nil }
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		metricsLog.Info("Serving metrics", slog.String("addr", addr), slog.String("path", "/metrics"))
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			metricsLog.Error("Metrics server failed", logging.Err(err))
+		}
+	}()
+	return server.Shutdown
+}
@@ -10,6 +10,9 @@ type EventEnvelope struct {
 	Timestamp time.Time       `json:"timestamp"`
 	Payload   json.RawMessage `json:"payload"`
 	Source    string          `json:"source"`
+	// TraceContext carries the W3C trace context (traceparent, tracestate)
+	// of the publisher, so the consumer continues the same trace
+	TraceContext map[string]string `json:"trace_context,omitempty"`
 }
 
 type EventLog struct {
@@ -20,10 +23,36 @@ type EventLog struct {
 	Source    string
 }
 
+// DeadLetterEntry is a message set aside after it could not be processed.
+// Queue is the topic it was consumed from, empty when it didn't come from one
+// and so can't be replayed. RetryCount counts earlier replays that failed, and
+// Attempts records each failed delivery before the message was set aside.
 type DeadLetterEntry struct {
-	EventJSON string `json:"event_json"`
-	ErrorMsg  string `json:"error_msg"`
-	FailedAt  int64  `json:"failed_at"`
+	ID         string            `json:"id"`
+	Queue      string            `json:"queue,omitempty"`
+	EventJSON  string            `json:"event_json"`
+	ErrorMsg   string            `json:"error_msg"`
+	FailedAt   int64             `json:"failed_at"`
+	RetryCount int               `json:"retry_count"`
+	Attempts   []DeliveryAttempt `json:"attempts,omitempty"`
+}
+
+// DeliveryAttempt is one failed attempt at processing a message
+type DeliveryAttempt struct {
+	Error    string `json:"error"`
+	FailedAt int64  `json:"failed_at"`
+}
+
+// QuarantinedMessage is an MQTT message the subscriber refused at ingestion.
+// Reason is a short code for the first check it failed and Error the details.
+// Payload is the message as received, quoted as a JSON string if it isn't
+// valid JSON itself.
+type QuarantinedMessage struct {
+	Reason     string          `json:"reason"`
+	Error      string          `json:"error"`
+	MQTTTopic  string          `json:"mqtt_topic"`
+	Payload    json.RawMessage `json:"payload"`
+	ReceivedAt time.Time       `json:"received_at"`
 }
 
 func (EventLog) TableName() string {
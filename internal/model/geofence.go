@@ -1,14 +1,124 @@
 package model
 
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/geo"
+)
+
+// DefaultGeofenceHysteresis is the band (meters) beyond a boundary that a
+// vehicle must clear before an exit is recorded
+const DefaultGeofenceHysteresis = 5.0
+
+// DepartByLayout is the "HH:MM" layout of Geofence.DepartBy
+const DepartByLayout = "15:04"
+
+// Geofence shapes
+const (
+	GeofenceShapeCircle       = "circle"
+	GeofenceShapePolygon      = "polygon"
+	GeofenceShapeMultiPolygon = "multipolygon"
+)
+
 type Geofence struct {
-	ID        int64   `gorm:"primaryKey"`
-	Name      string  `gorm:"not null"`
-	CenterLat float64 `gorm:"not null"`
-	CenterLng float64 `gorm:"not null"`
-	Radius    float64 `gorm:"not null"` // meters
-	Active    bool    `gorm:"default:true"`
+	ID        int64   `gorm:"primaryKey" json:"id"`
+	Name      string  `gorm:"not null" json:"name"`
+	Shape     string  `gorm:"not null;default:circle" json:"shape"`
+	CenterLat float64 `gorm:"not null" json:"center_lat"` // circle only
+	CenterLng float64 `gorm:"not null" json:"center_lng"` // circle only
+	Radius    float64 `gorm:"not null" json:"radius"`     // meters, circle only
+	// GeoJSON-style [lng, lat] coordinates for polygon and multipolygon shapes
+	Coordinates json.RawMessage `gorm:"type:jsonb" json:"coordinates,omitempty" swaggertype:"object"`
+	// Meters outside the boundary a vehicle must reach before it counts as
+	// having left. The API requires a positive value; HysteresisMeters falls
+	// back to DefaultGeofenceHysteresis only for legacy non-positive rows.
+	Hysteresis float64 `gorm:"not null;default:5" json:"hysteresis"`
+	// Minutes inside before a dwell event is raised; zero disables dwell events
+	DwellMinutes int `gorm:"not null;default:0" json:"dwell_minutes"`
+	// Daily "HH:MM" time by which vehicles should have left, in the
+	// schedule's timezone or UTC without one; an overdue event is raised for
+	// vehicles still inside. Empty disables it.
+	DepartBy string `gorm:"size:5" json:"depart_by,omitempty" example:"18:00"`
+	// Optional windows outside of which the geofence is not enforced
+	Schedule *GeofenceSchedule `gorm:"type:jsonb;serializer:json" json:"schedule,omitempty"`
+	Active   bool              `gorm:"default:true" json:"active"`
 }
 
 func (Geofence) TableName() string {
 	return "geofences"
 }
+
+// IsCircle reports whether the geofence uses the center/radius model.
+// An empty shape is treated as a circle for rows created before shapes existed.
+func (g Geofence) IsCircle() bool {
+	return g.Shape == "" || g.Shape == GeofenceShapeCircle
+}
+
+// HysteresisMeters returns the configured hysteresis, or the default for
+// legacy rows stored with a non-positive value
+func (g Geofence) HysteresisMeters() float64 {
+	if g.Hysteresis <= 0 {
+		return DefaultGeofenceHysteresis
+	}
+	return g.Hysteresis
+}
+
+// DwellThreshold returns how long a vehicle may stay inside before a dwell
+// event is raised, or zero if dwell events are disabled
+func (g Geofence) DwellThreshold() time.Duration {
+	if g.DwellMinutes <= 0 {
+		return 0
+	}
+	return time.Duration(g.DwellMinutes) * time.Minute
+}
+
+// DepartureDeadline returns the first DepartBy time after the vehicle entered
+// at since, as a wall-clock time in the schedule's timezone. ok is false when
+// the geofence has no valid DepartBy.
+func (g Geofence) DepartureDeadline(since time.Time) (deadline time.Time, ok bool) {
+	if g.DepartBy == "" {
+		return time.Time{}, false
+	}
+	departBy, err := time.Parse(DepartByLayout, g.DepartBy)
+	if err != nil {
+		return time.Time{}, false
+	}
+	loc := g.timezone()
+	since = since.In(loc)
+	deadline = time.Date(since.Year(), since.Month(), since.Day(), departBy.Hour(), departBy.Minute(), 0, 0, loc)
+	if !deadline.After(since) {
+		deadline = deadline.AddDate(0, 0, 1)
+	}
+	return deadline, true
+}
+
+// timezone returns the location of the geofence's schedule, or UTC when it
+// has none or its timezone is unknown
+func (g Geofence) timezone() *time.Location {
+	if g.Schedule == nil {
+		return time.UTC
+	}
+	loc, err := g.Schedule.location()
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Polygons decodes the coordinates of a polygon or multipolygon geofence
+func (g Geofence) Polygons() (geo.MultiPolygon, error) {
+	switch g.Shape {
+	case GeofenceShapePolygon:
+		pg, err := geo.ParsePolygon(g.Coordinates)
+		if err != nil {
+			return nil, err
+		}
+		return geo.MultiPolygon{pg}, nil
+	case GeofenceShapeMultiPolygon:
+		return geo.ParseMultiPolygon(g.Coordinates)
+	default:
+		return nil, fmt.Errorf("geofence shape %q has no polygon coordinates", g.Shape)
+	}
+}
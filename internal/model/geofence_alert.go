@@ -0,0 +1,52 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// GeofenceAlertVersion is the schema version of alerts published by this build
+const GeofenceAlertVersion = 2
+
+// GeofenceAlert is the geofence alert published to RabbitMQ.
+//
+// Version 1 alerts carry no version field and only have event_type,
+// vehicle_id, latitude, longitude and timestamp, where timestamp is the Unix
+// time the alert was published. Version 2 adds the geofence, the speed and the
+// device time of the location; timestamp is kept, as the Unix location time,
+// so version 1 readers can still decode it.
+type GeofenceAlert struct {
+	Version           int       `json:"version"`
+	EventType         string    `json:"event_type"`
+	VehicleID         string    `json:"vehicle_id"`
+	GeofenceID        int64     `json:"geofence_id,omitempty"`
+	GeofenceName      string    `json:"geofence_name,omitempty"`
+	Latitude          float64   `json:"latitude"`
+	Longitude         float64   `json:"longitude"`
+	Speed             float64   `json:"speed"`
+	Timestamp         int64     `json:"timestamp"`
+	LocationTimestamp time.Time `json:"location_timestamp"`
+	PublishedAt       time.Time `json:"published_at"`
+}
+
+// DecodeGeofenceAlert decodes a version 1 or 2 alert. Version 1 alerts are
+// upgraded in place: LocationTimestamp and PublishedAt are both set from their
+// timestamp, which is the closest information they have.
+func DecodeGeofenceAlert(body []byte) (*GeofenceAlert, error) {
+	var alert GeofenceAlert
+	if err := json.Unmarshal(body, &alert); err != nil {
+		return nil, err
+	}
+
+	switch alert.Version {
+	case 0, 1:
+		alert.Version = 1
+		alert.LocationTimestamp = time.Unix(alert.Timestamp, 0).UTC()
+		alert.PublishedAt = alert.LocationTimestamp
+	case GeofenceAlertVersion:
+	default:
+		return nil, fmt.Errorf("unsupported geofence alert version %d", alert.Version)
+	}
+	return &alert, nil
+}
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeGeofenceAlert_V1(t *testing.T) {
+	body := `{"event_type":"geofence_entry","vehicle_id":"BUS-001","latitude":-6.19,"longitude":106.82,"timestamp":1714550400}`
+
+	alert, err := DecodeGeofenceAlert([]byte(body))
+	require.NoError(t, err)
+	assert.Equal(t, 1, alert.Version)
+	assert.Equal(t, GeofenceEventEntry, alert.EventType)
+	assert.Equal(t, "BUS-001", alert.VehicleID)
+	assert.Zero(t, alert.GeofenceID)
+	assert.Equal(t, time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC), alert.LocationTimestamp)
+	assert.Equal(t, alert.LocationTimestamp, alert.PublishedAt)
+}
+
+func TestDecodeGeofenceAlert_V2(t *testing.T) {
+	located := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	sent := GeofenceAlert{
+		Version:           GeofenceAlertVersion,
+		EventType:         GeofenceEventExit,
+		VehicleID:         "BUS-001",
+		GeofenceID:        7,
+		GeofenceName:      "Depot A",
+		Latitude:          -6.19,
+		Longitude:         106.82,
+		Speed:             42.5,
+		Timestamp:         located.Unix(),
+		LocationTimestamp: located,
+		PublishedAt:       located.Add(time.Second),
+	}
+	body, err := json.Marshal(sent)
+	require.NoError(t, err)
+
+	alert, err := DecodeGeofenceAlert(body)
+	require.NoError(t, err)
+	assert.Equal(t, sent, *alert)
+
+	// Version 1 readers still see the fields they know
+	var v1 struct {
+		EventType string `json:"event_type"`
+		VehicleID string `json:"vehicle_id"`
+		Timestamp int64  `json:"timestamp"`
+	}
+	require.NoError(t, json.Unmarshal(body, &v1))
+	assert.Equal(t, located.Unix(), v1.Timestamp)
+}
+
+func TestDecodeGeofenceAlert_Invalid(t *testing.T) {
+	_, err := DecodeGeofenceAlert([]byte(`{"version":3,"event_type":"geofence_entry"}`))
+	assert.ErrorContains(t, err, "unsupported geofence alert version 3")
+
+	_, err = DecodeGeofenceAlert([]byte(`not json`))
+	assert.Error(t, err)
+}
@@ -6,13 +6,19 @@ import "time"
 const (
 	GeofenceEventEntry = "geofence_entry"
 	GeofenceEventExit  = "geofence_exit"
+	// GeofenceEventDwell is raised once per visit when a vehicle has stayed
+	// inside longer than the geofence's dwell threshold
+	GeofenceEventDwell = "geofence_dwell"
+	// GeofenceEventOverdue is raised once per visit when a vehicle is still
+	// inside after the geofence's DepartBy time
+	GeofenceEventOverdue = "geofence_overdue"
 )
 
 type GeofenceEvent struct {
 	ID         int64     `gorm:"primaryKey"`
 	VehicleID  string    `gorm:"not null;index:idx_vehicle_geofence"`
 	GeofenceID int64     `gorm:"not null;index:idx_vehicle_geofence"`
-	EventType  string    `gorm:"not null;check:event_type IN ('geofence_entry', 'geofence_exit')"`
+	EventType  string    `gorm:"not null;check:event_type IN ('geofence_entry', 'geofence_exit', 'geofence_dwell', 'geofence_overdue')"`
 	Timestamp  time.Time `gorm:"not null;index"`
 	Latitude   float64   `gorm:"not null"`
 	Longitude  float64   `gorm:"not null"`
@@ -0,0 +1,162 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ScheduleTimeLayout is the "HH:MM" layout of schedule window bounds
+const ScheduleTimeLayout = "15:04"
+
+// ScheduleEndOfDay is accepted as a window's End to run until midnight, so
+// "00:00" to "24:00" covers the whole day
+const ScheduleEndOfDay = "24:00"
+
+var scheduleDays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// GeofenceSchedule limits when a geofence is enforced. Times are wall-clock
+// times in Timezone, so windows follow daylight saving changes.
+type GeofenceSchedule struct {
+	// IANA timezone name, UTC when empty
+	Timezone string           `json:"timezone,omitempty" example:"Asia/Jakarta"`
+	Windows  []ScheduleWindow `json:"windows"`
+}
+
+// ScheduleWindow is a daily time range on the given days. End may be "24:00"
+// for midnight. A window whose End is before Start runs overnight into the
+// next day, and belongs to the day it starts on.
+type ScheduleWindow struct {
+	// Three-letter lowercase day names (mon, tue, ...); empty means every day
+	Days  []string `json:"days,omitempty" example:"mon,tue,wed,thu,fri"`
+	Start string   `json:"start" example:"06:30"`
+	End   string   `json:"end" example:"08:30"`
+}
+
+// Validate checks the timezone, days and window times
+func (s *GeofenceSchedule) Validate() error {
+	if _, err := s.location(); err != nil {
+		return err
+	}
+	if len(s.Windows) == 0 {
+		return errors.New("schedule must have at least one window")
+	}
+	for i, w := range s.Windows {
+		for _, day := range w.Days {
+			if _, ok := scheduleDays[strings.ToLower(day)]; !ok {
+				return fmt.Errorf("schedule window %d: unknown day %q", i, day)
+			}
+		}
+		start, err := parseScheduleTime(w.Start)
+		if err != nil {
+			return fmt.Errorf("schedule window %d: start: %w", i, err)
+		}
+		end, err := parseScheduleEnd(w.End)
+		if err != nil {
+			return fmt.Errorf("schedule window %d: end: %w", i, err)
+		}
+		if start == end {
+			return fmt.Errorf("schedule window %d: start and end must differ", i)
+		}
+	}
+	return nil
+}
+
+// ActiveAt reports whether t falls inside one of the schedule's windows.
+// A nil schedule is always active; an invalid one never is.
+func (s *GeofenceSchedule) ActiveAt(t time.Time) bool {
+	if s == nil {
+		return true
+	}
+	loc, err := s.location()
+	if err != nil {
+		return false
+	}
+
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	today := local.Weekday()
+	yesterday := (today + 6) % 7
+	for _, w := range s.Windows {
+		start, err := parseScheduleTime(w.Start)
+		if err != nil {
+			continue
+		}
+		end, err := parseScheduleEnd(w.End)
+		if err != nil {
+			continue
+		}
+
+		if start < end {
+			if w.onDay(today) && minute >= start && minute < end {
+				return true
+			}
+			continue
+		}
+		// Overnight: the evening part belongs to today, the morning part to
+		// the window that started yesterday
+		if (w.onDay(today) && minute >= start) || (w.onDay(yesterday) && minute < end) {
+			return true
+		}
+	}
+	return false
+}
+
+// locations caches loaded timezones, since time.LoadLocation reads the
+// zoneinfo database on every call
+var locations sync.Map
+
+func (s *GeofenceSchedule) location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.UTC, nil
+	}
+	if loc, ok := locations.Load(s.Timezone); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", s.Timezone)
+	}
+	locations.Store(s.Timezone, loc)
+	return loc, nil
+}
+
+func (w ScheduleWindow) onDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if weekday, ok := scheduleDays[strings.ToLower(d)]; ok && weekday == day {
+			return true
+		}
+	}
+	return false
+}
+
+// parseScheduleTime returns the minutes after midnight of an "HH:MM" time
+func parseScheduleTime(value string) (int, error) {
+	t, err := time.Parse(ScheduleTimeLayout, value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a HH:MM time", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// parseScheduleEnd is parseScheduleTime, also accepting ScheduleEndOfDay as
+// the minute after the last one of the day
+func parseScheduleEnd(value string) (int, error) {
+	if value == ScheduleEndOfDay {
+		return 24 * 60, nil
+	}
+	return parseScheduleTime(value)
+}
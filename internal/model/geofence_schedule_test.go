@@ -0,0 +1,138 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	require.NoError(t, err)
+	return loc
+}
+
+func TestGeofenceSchedule_ActiveAt(t *testing.T) {
+	jakarta := mustLoad(t, "Asia/Jakarta")
+	schoolZone := &GeofenceSchedule{
+		Timezone: "Asia/Jakarta",
+		Windows:  []ScheduleWindow{{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "06:30", End: "08:30"}},
+	}
+	// 2024-05-06 is a Monday
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"weekday morning", time.Date(2024, 5, 6, 7, 0, 0, 0, jakarta), true},
+		{"start is inclusive", time.Date(2024, 5, 6, 6, 30, 0, 0, jakarta), true},
+		{"end is exclusive", time.Date(2024, 5, 6, 8, 30, 0, 0, jakarta), false},
+		{"weekday afternoon", time.Date(2024, 5, 6, 15, 0, 0, 0, jakarta), false},
+		{"saturday morning", time.Date(2024, 5, 11, 7, 0, 0, 0, jakarta), false},
+		// 23:45 UTC Sunday is 06:45 Monday in Jakarta
+		{"converted from UTC", time.Date(2024, 5, 5, 23, 45, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, schoolZone.ActiveAt(tt.at))
+		})
+	}
+}
+
+func TestGeofenceSchedule_Overnight(t *testing.T) {
+	// Loading bay closed Friday night into Saturday morning
+	schedule := &GeofenceSchedule{Windows: []ScheduleWindow{{Days: []string{"fri"}, Start: "22:00", End: "06:00"}}}
+
+	assert.True(t, schedule.ActiveAt(time.Date(2024, 5, 10, 23, 0, 0, 0, time.UTC)))  // Friday
+	assert.True(t, schedule.ActiveAt(time.Date(2024, 5, 11, 5, 59, 0, 0, time.UTC)))  // Saturday
+	assert.False(t, schedule.ActiveAt(time.Date(2024, 5, 11, 6, 0, 0, 0, time.UTC)))  // Saturday
+	assert.False(t, schedule.ActiveAt(time.Date(2024, 5, 10, 5, 0, 0, 0, time.UTC)))  // Friday morning
+	assert.False(t, schedule.ActiveAt(time.Date(2024, 5, 11, 23, 0, 0, 0, time.UTC))) // Saturday night
+}
+
+func TestGeofenceSchedule_DST(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	schedule := &GeofenceSchedule{
+		Timezone: "America/New_York",
+		Windows:  []ScheduleWindow{{Start: "01:30", End: "03:30"}},
+	}
+
+	// Spring forward on 2024-03-10: 02:00 EST jumps to 03:00 EDT, so the
+	// window is only one wall-clock hour long that night
+	springStart := time.Date(2024, 3, 10, 6, 30, 0, 0, time.UTC) // 01:30 EST
+	assert.True(t, schedule.ActiveAt(springStart))
+	assert.True(t, schedule.ActiveAt(springStart.Add(29*time.Minute)))  // 01:59 EST
+	assert.True(t, schedule.ActiveAt(springStart.Add(30*time.Minute)))  // 03:00 EDT
+	assert.True(t, schedule.ActiveAt(springStart.Add(59*time.Minute)))  // 03:29 EDT
+	assert.False(t, schedule.ActiveAt(springStart.Add(60*time.Minute))) // 03:30 EDT
+	assert.Equal(t, 3, springStart.Add(30*time.Minute).In(newYork).Hour())
+	assert.False(t, schedule.ActiveAt(time.Date(2024, 3, 10, 7, 30, 0, 0, time.UTC)))
+
+	// Fall back on 2024-11-03: 01:00-02:00 happens twice, and both count
+	firstPass := time.Date(2024, 11, 3, 5, 45, 0, 0, time.UTC) // 01:45 EDT
+	secondPass := firstPass.Add(time.Hour)                     // 01:45 EST
+	assert.Equal(t, firstPass.In(newYork).Hour(), secondPass.In(newYork).Hour())
+	assert.True(t, schedule.ActiveAt(firstPass))
+	assert.True(t, schedule.ActiveAt(secondPass))
+	assert.True(t, schedule.ActiveAt(time.Date(2024, 11, 3, 8, 15, 0, 0, time.UTC)))  // 03:15 EST
+	assert.False(t, schedule.ActiveAt(time.Date(2024, 11, 3, 8, 45, 0, 0, time.UTC))) // 03:45 EST
+}
+
+func TestGeofenceSchedule_OvernightAcrossDST(t *testing.T) {
+	// 22:00-06:00 Saturday night into the spring-forward Sunday
+	schedule := &GeofenceSchedule{
+		Timezone: "America/New_York",
+		Windows:  []ScheduleWindow{{Days: []string{"sat"}, Start: "22:00", End: "06:00"}},
+	}
+	assert.True(t, schedule.ActiveAt(time.Date(2024, 3, 10, 9, 59, 0, 0, time.UTC)))  // 05:59 EDT
+	assert.False(t, schedule.ActiveAt(time.Date(2024, 3, 10, 10, 0, 0, 0, time.UTC))) // 06:00 EDT
+	assert.True(t, schedule.ActiveAt(time.Date(2024, 3, 10, 5, 0, 0, 0, time.UTC)))   // 00:00 EST
+	assert.True(t, schedule.ActiveAt(time.Date(2024, 3, 10, 3, 0, 0, 0, time.UTC)))   // 22:00 EST Saturday
+}
+
+func TestGeofenceSchedule_UntilMidnight(t *testing.T) {
+	schedule := &GeofenceSchedule{
+		Timezone: "Asia/Jakarta",
+		Windows:  []ScheduleWindow{{Days: []string{"mon"}, Start: "00:00", End: "24:00"}},
+	}
+	// Monday 2024-05-06 in Jakarta (UTC+7)
+	assert.True(t, schedule.ActiveAt(time.Date(2024, 5, 5, 17, 0, 0, 0, time.UTC)))   // 00:00 Monday
+	assert.True(t, schedule.ActiveAt(time.Date(2024, 5, 6, 16, 59, 30, 0, time.UTC))) // 23:59:30 Monday
+	assert.False(t, schedule.ActiveAt(time.Date(2024, 5, 6, 17, 0, 0, 0, time.UTC)))  // 00:00 Tuesday
+}
+
+func TestGeofenceSchedule_Nil(t *testing.T) {
+	var schedule *GeofenceSchedule
+	assert.True(t, schedule.ActiveAt(time.Now()))
+}
+
+func TestGeofenceSchedule_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule GeofenceSchedule
+		message  string
+	}{
+		{"valid", GeofenceSchedule{Timezone: "Asia/Jakarta", Windows: []ScheduleWindow{{Start: "22:00", End: "06:00"}}}, ""},
+		{"unknown timezone", GeofenceSchedule{Timezone: "Mars/Olympus", Windows: []ScheduleWindow{{Start: "06:00", End: "07:00"}}}, "unknown timezone"},
+		{"no windows", GeofenceSchedule{}, "at least one window"},
+		{"unknown day", GeofenceSchedule{Windows: []ScheduleWindow{{Days: []string{"funday"}, Start: "06:00", End: "07:00"}}}, "unknown day"},
+		{"bad start", GeofenceSchedule{Windows: []ScheduleWindow{{Start: "6am", End: "07:00"}}}, "start"},
+		{"until midnight", GeofenceSchedule{Windows: []ScheduleWindow{{Start: "06:00", End: "24:00"}}}, ""},
+		{"bad end", GeofenceSchedule{Windows: []ScheduleWindow{{Start: "06:00", End: "24:30"}}}, "end"},
+		{"start at end of day", GeofenceSchedule{Windows: []ScheduleWindow{{Start: "24:00", End: "06:00"}}}, "start"},
+		{"empty window", GeofenceSchedule{Windows: []ScheduleWindow{{Start: "06:00", End: "06:00"}}}, "must differ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.schedule.Validate()
+			if tt.message == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.message)
+		})
+	}
+}
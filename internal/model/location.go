@@ -1,15 +1,78 @@
 package model
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
+// Bounds outside of which a reported telemetry value can't be real
+const (
+	MaxSpeed      = 500.0   // km/h
+	MinAltitude   = -500.0  // meters, below the lowest dry land
+	MaxAltitude   = 10000.0 // meters, above the highest road
+	MaxSatellites = 150     // more than every GNSS constellation combined has in view
+)
+
+// VehicleLocation is one position report. Timestamp is the time the device
+// took the fix and ReceivedAt the time the subscriber received it. The
+// optional telemetry fields are nil when the device didn't report them.
 type VehicleLocation struct {
-	ID        int64     `gorm:"primaryKey" json:"id"`
-	VehicleID string    `gorm:"index" json:"vehicle_id"`
-	Latitude  float64   `gorm:"not null" json:"latitude"`
-	Longitude float64   `gorm:"not null" json:"longitude"`
-	Timestamp time.Time `gorm:"index" json:"timestamp"`
+	ID        int64   `gorm:"primaryKey" json:"id"`
+	VehicleID string  `gorm:"index" json:"vehicle_id"`
+	Latitude  float64 `gorm:"not null" json:"latitude"`
+	Longitude float64 `gorm:"not null" json:"longitude"`
+	Speed     float64 `json:"speed"` // km/h
+	// Degrees clockwise from true north, in [0, 360)
+	Heading *float64 `json:"heading,omitempty"`
+	// Meters above mean sea level
+	Altitude *float64 `json:"altitude,omitempty"`
+	// Horizontal dilution of precision reported by the receiver
+	HDOP *float64 `gorm:"column:hdop" json:"hdop,omitempty"`
+	// Estimated horizontal accuracy in meters
+	Accuracy   *float64   `json:"accuracy,omitempty"`
+	Satellites *int       `json:"satellites,omitempty"`
+	Timestamp  time.Time  `gorm:"index" json:"timestamp"`
+	ReceivedAt *time.Time `json:"received_at,omitempty"`
+	// Sensor and IO values such as ignition or fuel level, GIN indexed for
+	// containment queries
+	Attributes LocationAttributes `gorm:"type:jsonb;serializer:json;index:idx_vehicle_locations_attributes,type:gin" json:"attributes,omitempty" swaggertype:"object"`
 }
 
 func (VehicleLocation) TableName() string {
 	return "vehicle_locations"
 }
+
+// Validate rejects locations with physically impossible values, reporting
+// every one of them
+func (l VehicleLocation) Validate() error {
+	var errs []error
+	if l.Latitude < -90 || l.Latitude > 90 {
+		errs = append(errs, fmt.Errorf("latitude %g is outside [-90, 90]", l.Latitude))
+	}
+	if l.Longitude < -180 || l.Longitude > 180 {
+		errs = append(errs, fmt.Errorf("longitude %g is outside [-180, 180]", l.Longitude))
+	}
+	if l.Speed < 0 || l.Speed > MaxSpeed {
+		errs = append(errs, fmt.Errorf("speed %g is outside [0, %g] km/h", l.Speed, MaxSpeed))
+	}
+	if l.Heading != nil && (*l.Heading < 0 || *l.Heading >= 360) {
+		errs = append(errs, fmt.Errorf("heading %g is outside [0, 360)", *l.Heading))
+	}
+	if l.Altitude != nil && (*l.Altitude < MinAltitude || *l.Altitude > MaxAltitude) {
+		errs = append(errs, fmt.Errorf("altitude %g is outside [%g, %g] meters", *l.Altitude, MinAltitude, MaxAltitude))
+	}
+	if l.HDOP != nil && *l.HDOP <= 0 {
+		errs = append(errs, fmt.Errorf("hdop %g must be positive", *l.HDOP))
+	}
+	if l.Accuracy != nil && *l.Accuracy < 0 {
+		errs = append(errs, fmt.Errorf("accuracy %g must not be negative", *l.Accuracy))
+	}
+	if l.Satellites != nil && (*l.Satellites < 0 || *l.Satellites > MaxSatellites) {
+		errs = append(errs, fmt.Errorf("satellites %d is outside [0, %d]", *l.Satellites, MaxSatellites))
+	}
+	if err := l.Attributes.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
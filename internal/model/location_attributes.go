@@ -0,0 +1,107 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Well-known location attribute keys. Trackers may report any other key,
+// such as CAN-bus values, which are stored as sent.
+const (
+	AttrIgnition       = "ignition"        // bool
+	AttrFuelLevel      = "fuel_level"      // percent of the tank, 0 to 100
+	AttrBatteryVoltage = "battery_voltage" // volts
+	AttrDoorOpen       = "door_open"       // bool
+	AttrTemperature    = "temperature"     // degrees Celsius
+)
+
+// LocationAttributes holds the sensor and IO values reported with a location,
+// stored as a JSONB object. Numbers decode as float64.
+type LocationAttributes map[string]any
+
+// Bool returns the value of key if it is a bool
+func (a LocationAttributes) Bool(key string) (value, ok bool) {
+	value, ok = a[key].(bool)
+	return value, ok
+}
+
+// Float returns the value of key if it is a number
+func (a LocationAttributes) Float(key string) (float64, bool) {
+	value, ok := a[key].(float64)
+	return value, ok
+}
+
+// String returns the value of key if it is a string
+func (a LocationAttributes) String(key string) (string, bool) {
+	value, ok := a[key].(string)
+	return value, ok
+}
+
+// Ignition reports whether the ignition is on, if the tracker reported it
+func (a LocationAttributes) Ignition() (on, ok bool) {
+	return a.Bool(AttrIgnition)
+}
+
+// FuelLevel returns the fuel level in percent, if reported
+func (a LocationAttributes) FuelLevel() (float64, bool) {
+	return a.Float(AttrFuelLevel)
+}
+
+// BatteryVoltage returns the battery voltage, if reported
+func (a LocationAttributes) BatteryVoltage() (float64, bool) {
+	return a.Float(AttrBatteryVoltage)
+}
+
+// DoorOpen reports whether a door is open, if the tracker reported it
+func (a LocationAttributes) DoorOpen() (open, ok bool) {
+	return a.Bool(AttrDoorOpen)
+}
+
+// Temperature returns the temperature in degrees Celsius, if reported
+func (a LocationAttributes) Temperature() (float64, bool) {
+	return a.Float(AttrTemperature)
+}
+
+// Only returns the attributes with the given keys, or nil if none are present
+func (a LocationAttributes) Only(keys ...string) LocationAttributes {
+	var only LocationAttributes
+	for _, key := range keys {
+		if value, ok := a[key]; ok {
+			if only == nil {
+				only = make(LocationAttributes, len(keys))
+			}
+			only[key] = value
+		}
+	}
+	return only
+}
+
+// Validate checks the types and ranges of the well-known attributes that are
+// present. Other keys are not checked.
+func (a LocationAttributes) Validate() error {
+	var errs []error
+	for _, key := range []string{AttrIgnition, AttrDoorOpen} {
+		if _, present := a[key]; present {
+			if _, ok := a.Bool(key); !ok {
+				errs = append(errs, fmt.Errorf("attribute %s must be a boolean", key))
+			}
+		}
+	}
+	for _, key := range []string{AttrFuelLevel, AttrBatteryVoltage, AttrTemperature} {
+		if _, present := a[key]; present {
+			if _, ok := a.Float(key); !ok {
+				errs = append(errs, fmt.Errorf("attribute %s must be a number", key))
+			}
+		}
+	}
+	if level, ok := a.FuelLevel(); ok && (level < 0 || level > 100) {
+		errs = append(errs, fmt.Errorf("attribute %s %g is outside [0, 100]", AttrFuelLevel, level))
+	}
+	if voltage, ok := a.BatteryVoltage(); ok && voltage < 0 {
+		errs = append(errs, fmt.Errorf("attribute %s %g must not be negative", AttrBatteryVoltage, voltage))
+	}
+	if temperature, ok := a.Temperature(); ok && temperature < -273.15 {
+		errs = append(errs, fmt.Errorf("attribute %s %g is below absolute zero", AttrTemperature, temperature))
+	}
+	return errors.Join(errs...)
+}
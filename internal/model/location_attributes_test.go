@@ -0,0 +1,52 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocationAttributes_Accessors(t *testing.T) {
+	var attributes LocationAttributes
+	require.NoError(t, json.Unmarshal([]byte(`{"ignition":false,"fuel_level":62.5,"can.engine_rpm":1800,"driver":"D-17"}`), &attributes))
+
+	on, ok := attributes.Ignition()
+	assert.True(t, ok)
+	assert.False(t, on)
+	level, ok := attributes.FuelLevel()
+	assert.True(t, ok)
+	assert.Equal(t, 62.5, level)
+	rpm, ok := attributes.Float("can.engine_rpm")
+	assert.True(t, ok)
+	assert.Equal(t, 1800.0, rpm)
+	driver, _ := attributes.String("driver")
+	assert.Equal(t, "D-17", driver)
+
+	_, ok = attributes.BatteryVoltage()
+	assert.False(t, ok, "missing keys are reported as absent")
+	_, ok = attributes.Float("driver")
+	assert.False(t, ok, "values of another type are reported as absent")
+}
+
+func TestLocationAttributes_Only(t *testing.T) {
+	attributes := LocationAttributes{AttrIgnition: true, AttrFuelLevel: 40.0, AttrDoorOpen: false}
+
+	assert.Equal(t, LocationAttributes{AttrFuelLevel: 40.0}, attributes.Only(AttrFuelLevel, "missing"))
+	assert.Nil(t, attributes.Only("missing"))
+	assert.Nil(t, LocationAttributes(nil).Only(AttrFuelLevel))
+}
+
+func TestLocationAttributes_Validate(t *testing.T) {
+	assert.NoError(t, LocationAttributes{AttrIgnition: true, AttrFuelLevel: 100.0, AttrTemperature: -18.0, "custom": "anything"}.Validate())
+	assert.NoError(t, LocationAttributes(nil).Validate())
+
+	err := LocationAttributes{AttrIgnition: "on", AttrFuelLevel: 120.0, AttrBatteryVoltage: "12V"}.Validate()
+	assert.ErrorContains(t, err, "ignition must be a boolean")
+	assert.ErrorContains(t, err, "fuel_level 120 is outside [0, 100]")
+	assert.ErrorContains(t, err, "battery_voltage must be a number")
+
+	loc := VehicleLocation{VehicleID: "B1234XYZ", Attributes: LocationAttributes{AttrTemperature: -300.0}}
+	assert.ErrorContains(t, loc.Validate(), "below absolute zero", "locations validate their attributes")
+}
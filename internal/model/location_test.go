@@ -0,0 +1,48 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVehicleLocation_Validate(t *testing.T) {
+	float := func(v float64) *float64 { return &v }
+	count := func(v int) *int { return &v }
+	valid := VehicleLocation{
+		VehicleID: "B1234XYZ", Latitude: -6.193125, Longitude: 106.820233, Speed: 42.5,
+		Heading: float(359.9), Altitude: float(-430), HDOP: float(0.8), Accuracy: float(0), Satellites: count(0),
+	}
+	assert.NoError(t, valid.Validate())
+	assert.NoError(t, VehicleLocation{VehicleID: "B1234XYZ"}.Validate(), "telemetry is optional")
+
+	tests := []struct {
+		name   string
+		modify func(*VehicleLocation)
+		want   string
+	}{
+		{"latitude", func(l *VehicleLocation) { l.Latitude = -90.5 }, "latitude"},
+		{"longitude", func(l *VehicleLocation) { l.Longitude = 180.5 }, "longitude"},
+		{"negative speed", func(l *VehicleLocation) { l.Speed = -1 }, "speed"},
+		{"too fast", func(l *VehicleLocation) { l.Speed = MaxSpeed + 1 }, "speed"},
+		{"full turn heading", func(l *VehicleLocation) { l.Heading = float(360) }, "heading"},
+		{"negative heading", func(l *VehicleLocation) { l.Heading = float(-1) }, "heading"},
+		{"altitude", func(l *VehicleLocation) { l.Altitude = float(MaxAltitude + 1) }, "altitude"},
+		{"zero hdop", func(l *VehicleLocation) { l.HDOP = float(0) }, "hdop"},
+		{"negative accuracy", func(l *VehicleLocation) { l.Accuracy = float(-5) }, "accuracy"},
+		{"satellites", func(l *VehicleLocation) { l.Satellites = count(MaxSatellites + 1) }, "satellites"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := valid
+			tt.modify(&loc)
+			assert.ErrorContains(t, loc.Validate(), tt.want)
+		})
+	}
+
+	both := valid
+	both.Latitude, both.Speed = 100, -1
+	err := both.Validate()
+	assert.ErrorContains(t, err, "latitude")
+	assert.ErrorContains(t, err, "speed", "every violation is reported")
+}
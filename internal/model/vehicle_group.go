@@ -0,0 +1,37 @@
+package model
+
+// VehicleGroup is a named set of vehicles that geofences can be assigned to
+type VehicleGroup struct {
+	ID   int64  `gorm:"primaryKey" json:"id"`
+	Name string `gorm:"not null;uniqueIndex" json:"name"`
+}
+
+func (VehicleGroup) TableName() string {
+	return "vehicle_groups"
+}
+
+type VehicleGroupMember struct {
+	GroupID   int64         `gorm:"primaryKey" json:"group_id"`
+	VehicleID string        `gorm:"primaryKey;index" json:"vehicle_id"`
+	Group     *VehicleGroup `gorm:"constraint:OnDelete:CASCADE" json:"-" swaggerignore:"true"`
+}
+
+func (VehicleGroupMember) TableName() string {
+	return "vehicle_group_members"
+}
+
+// GeofenceAssignment restricts a geofence to one vehicle or to the members of
+// one vehicle group. Geofences without assignments apply to every vehicle, so
+// a group can't be deleted while it is still assigned.
+type GeofenceAssignment struct {
+	ID         int64         `gorm:"primaryKey" json:"id"`
+	GeofenceID int64         `gorm:"not null;index" json:"geofence_id"`
+	VehicleID  *string       `gorm:"check:chk_geofence_assignments_target,(vehicle_id IS NULL) <> (group_id IS NULL)" json:"vehicle_id,omitempty"`
+	GroupID    *int64        `gorm:"index" json:"group_id,omitempty"`
+	Geofence   *Geofence     `gorm:"constraint:OnDelete:CASCADE" json:"-" swaggerignore:"true"`
+	Group      *VehicleGroup `gorm:"constraint:OnDelete:RESTRICT" json:"-" swaggerignore:"true"`
+}
+
+func (GeofenceAssignment) TableName() string {
+	return "geofence_assignments"
+}
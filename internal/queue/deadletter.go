@@ -0,0 +1,117 @@
+package queue
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+)
+
+var (
+	ErrDeadLetterNotFound = errors.New("dead letter entry not found")
+	// ErrNotReplayable is returned when replaying an entry that doesn't
+	// record the queue it came from
+	ErrNotReplayable = errors.New("dead letter entry has no original queue")
+)
+
+// DeadLetterFilter narrows ListDeadLetters results. Zero values are ignored.
+type DeadLetterFilter struct {
+	Queue string
+	Error string // case-insensitive substring of the error message
+	Since time.Time
+	Until time.Time
+	Limit int
+}
+
+// Matches reports whether the entry passes the queue and error filters. The
+// time range is left to the store, which can use its index.
+func (f DeadLetterFilter) Matches(entry model.DeadLetterEntry) bool {
+	if f.Queue != "" && entry.Queue != f.Queue {
+		return false
+	}
+	if f.Error != "" && !strings.Contains(strings.ToLower(entry.ErrorMsg), strings.ToLower(f.Error)) {
+		return false
+	}
+	return true
+}
+
+func (f DeadLetterFilter) inRange(failedAt int64) bool {
+	if !f.Since.IsZero() && failedAt < f.Since.Unix() {
+		return false
+	}
+	if !f.Until.IsZero() && failedAt > f.Until.Unix() {
+		return false
+	}
+	return true
+}
+
+// DeadLetterStore gives operators access to dead-lettered entries
+type DeadLetterStore interface {
+	// ListDeadLetters returns matching entries, oldest first
+	ListDeadLetters(ctx context.Context, filter DeadLetterFilter) ([]model.DeadLetterEntry, error)
+	GetDeadLetter(ctx context.Context, id string) (model.DeadLetterEntry, error)
+	// ReplayDeadLetter publishes the entry back onto its original queue and
+	// removes it. If it is dead-lettered again, the new entry's RetryCount is
+	// one higher.
+	ReplayDeadLetter(ctx context.Context, id string) error
+	DeleteDeadLetter(ctx context.Context, id string) error
+}
+
+// replayKey identifies a replayed message so that its retry count carries
+// over if it comes back
+func replayKey(queue string, body []byte) string {
+	sum := sha1.Sum(append([]byte(queue+"\x00"), body...))
+	return hex.EncodeToString(sum[:])
+}
+
+// DeadLetterResult reports the outcome of a batch operation by entry ID
+type DeadLetterResult struct {
+	Succeeded []string          `json:"succeeded"`
+	Failed    map[string]string `json:"failed,omitempty"`
+}
+
+// SelectDeadLetters returns ids if given, otherwise the IDs of every entry
+// matching filter
+func SelectDeadLetters(ctx context.Context, store DeadLetterStore, ids []string, filter DeadLetterFilter) ([]string, error) {
+	if len(ids) > 0 {
+		return ids, nil
+	}
+	entries, err := store.ListDeadLetters(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	selected := make([]string, len(entries))
+	for i, entry := range entries {
+		selected[i] = entry.ID
+	}
+	return selected, nil
+}
+
+// ReplayDeadLetters replays each entry, carrying on past failures
+func ReplayDeadLetters(ctx context.Context, store DeadLetterStore, ids []string) DeadLetterResult {
+	return applyDeadLetters(ctx, ids, store.ReplayDeadLetter)
+}
+
+// PurgeDeadLetters deletes each entry, carrying on past failures
+func PurgeDeadLetters(ctx context.Context, store DeadLetterStore, ids []string) DeadLetterResult {
+	return applyDeadLetters(ctx, ids, store.DeleteDeadLetter)
+}
+
+func applyDeadLetters(ctx context.Context, ids []string, apply func(context.Context, string) error) DeadLetterResult {
+	result := DeadLetterResult{Succeeded: []string{}}
+	for _, id := range ids {
+		if err := apply(ctx, id); err != nil {
+			if result.Failed == nil {
+				result.Failed = make(map[string]string)
+			}
+			result.Failed[id] = err.Error()
+			continue
+		}
+		result.Succeeded = append(result.Succeeded, id)
+	}
+	return result
+}
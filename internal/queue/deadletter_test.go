@@ -0,0 +1,173 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+)
+
+type deadLetterQueue interface {
+	Queue
+	DeadLetterStore
+}
+
+// forEachStore runs the test against both implementations
+func forEachStore(t *testing.T, test func(t *testing.T, q deadLetterQueue)) {
+	t.Run("Memory", func(t *testing.T) {
+		test(t, NewMemoryQueue())
+	})
+	t.Run("Redis", func(t *testing.T) {
+		_, rdb := newTestRedis(t)
+		test(t, NewRedisQueue(rdb, "worker-1"))
+	})
+}
+
+func TestDeadLetterStore_ListAndFilter(t *testing.T) {
+	forEachStore(t, func(t *testing.T, q deadLetterQueue) {
+		ctx := context.Background()
+		require.NoError(t, q.DeadLetter(ctx, "event_log:stream", Message{Body: []byte(`{"n":1}`)}, errors.New("connection refused")))
+		require.NoError(t, q.DeadLetter(ctx, "event_log:stream", Message{Body: []byte(`{"n":2}`)}, errors.New("invalid character 'x'")))
+		require.NoError(t, q.DeadLetter(ctx, "", Message{Body: []byte(`{"n":3}`)}, errors.New("Connection reset")))
+
+		all, err := q.ListDeadLetters(ctx, DeadLetterFilter{})
+		require.NoError(t, err)
+		require.Len(t, all, 3)
+		assert.NotEqual(t, all[0].ID, all[1].ID)
+		assert.Equal(t, "event_log:stream", all[0].Queue)
+		assert.Equal(t, `{"n":1}`, all[0].EventJSON)
+		assert.Equal(t, "connection refused", all[0].ErrorMsg)
+		assert.Zero(t, all[0].RetryCount)
+
+		byError, err := q.ListDeadLetters(ctx, DeadLetterFilter{Error: "CONNECTION"})
+		require.NoError(t, err)
+		assert.Len(t, byError, 2)
+
+		byQueue, err := q.ListDeadLetters(ctx, DeadLetterFilter{Queue: "event_log:stream", Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, byQueue, 1)
+		assert.Equal(t, all[0].ID, byQueue[0].ID)
+
+		future, err := q.ListDeadLetters(ctx, DeadLetterFilter{Since: time.Now().Add(time.Hour)})
+		require.NoError(t, err)
+		assert.Empty(t, future)
+		past, err := q.ListDeadLetters(ctx, DeadLetterFilter{Until: time.Now().Add(-time.Hour)})
+		require.NoError(t, err)
+		assert.Empty(t, past)
+
+		got, err := q.GetDeadLetter(ctx, all[1].ID)
+		require.NoError(t, err)
+		assert.Equal(t, all[1], got)
+		_, err = q.GetDeadLetter(ctx, "missing")
+		assert.ErrorIs(t, err, ErrDeadLetterNotFound)
+	})
+}
+
+func TestDeadLetterStore_ReplayCountsRetries(t *testing.T) {
+	forEachStore(t, func(t *testing.T, q deadLetterQueue) {
+		ctx := context.Background()
+		body := []byte(`{"event_type":"location_update"}`)
+		require.NoError(t, q.DeadLetter(ctx, testStream, Message{Body: body}, errors.New("connection refused")))
+		entries, err := q.ListDeadLetters(ctx, DeadLetterFilter{})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+
+		require.NoError(t, q.ReplayDeadLetter(ctx, entries[0].ID))
+		_, err = q.GetDeadLetter(ctx, entries[0].ID)
+		assert.ErrorIs(t, err, ErrDeadLetterNotFound, "replayed entries are removed")
+
+		// The replayed message is delivered on its original topic and fails again
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		err = q.Consume(ctx, testStream, "test_workers", func(ctx context.Context, msg Message) error {
+			assert.Equal(t, body, msg.Body)
+			defer cancel()
+			return q.DeadLetter(ctx, testStream, msg, errors.New("still refused"))
+		})
+		require.ErrorIs(t, err, context.Canceled)
+
+		entries, err = q.ListDeadLetters(context.Background(), DeadLetterFilter{})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, 1, entries[0].RetryCount)
+		assert.Equal(t, "still refused", entries[0].ErrorMsg)
+	})
+}
+
+func TestDeadLetterStore_ReplayRequiresQueue(t *testing.T) {
+	forEachStore(t, func(t *testing.T, q deadLetterQueue) {
+		ctx := context.Background()
+		require.NoError(t, q.DeadLetter(ctx, "", Message{Body: []byte(`{}`)}, errors.New("boom")))
+		entries, err := q.ListDeadLetters(ctx, DeadLetterFilter{})
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, q.ReplayDeadLetter(ctx, entries[0].ID), ErrNotReplayable)
+		assert.ErrorIs(t, q.ReplayDeadLetter(ctx, "missing"), ErrDeadLetterNotFound)
+	})
+}
+
+func TestDeadLetterStore_Delete(t *testing.T) {
+	forEachStore(t, func(t *testing.T, q deadLetterQueue) {
+		ctx := context.Background()
+		require.NoError(t, q.DeadLetter(ctx, testStream, Message{Body: []byte(`{}`)}, errors.New("boom")))
+		entries, err := q.ListDeadLetters(ctx, DeadLetterFilter{})
+		require.NoError(t, err)
+
+		require.NoError(t, q.DeleteDeadLetter(ctx, entries[0].ID))
+		assert.ErrorIs(t, q.DeleteDeadLetter(ctx, entries[0].ID), ErrDeadLetterNotFound)
+		entries, err = q.ListDeadLetters(ctx, DeadLetterFilter{})
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+}
+
+func TestRedisQueue_ImportLegacyDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	mr, rdb := newTestRedis(t)
+	legacy, _ := json.Marshal(model.DeadLetterEntry{EventJSON: `{"n":1}`, ErrorMsg: "boom", FailedAt: 1700000000})
+	mr.RPush("event_log:dead_letter", string(legacy))
+	mr.RPush("event_log:dead_letter_queue", string(legacy))
+
+	q := NewRedisQueue(rdb, "")
+	imported, err := q.ImportLegacyDeadLetters(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, imported)
+	assert.False(t, mr.Exists("event_log:dead_letter"))
+
+	entries, err := q.ListDeadLetters(ctx, DeadLetterFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "boom", entries[0].ErrorMsg)
+	assert.Equal(t, int64(1700000000), entries[0].FailedAt)
+	assert.Empty(t, entries[0].Queue)
+}
+
+func TestReplayDeadLetters_SelectsByFilter(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue()
+	require.NoError(t, q.DeadLetter(ctx, testStream, Message{Body: []byte(`{"n":1}`)}, errors.New("connection refused")))
+	require.NoError(t, q.DeadLetter(ctx, "", Message{Body: []byte(`{"n":2}`)}, errors.New("connection refused")))
+	require.NoError(t, q.DeadLetter(ctx, testStream, Message{Body: []byte(`{"n":3}`)}, errors.New("invalid character")))
+
+	ids, err := SelectDeadLetters(ctx, q, nil, DeadLetterFilter{Error: "refused"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, ids)
+
+	result := ReplayDeadLetters(ctx, q, ids)
+	assert.Equal(t, []string{"1"}, result.Succeeded)
+	assert.Equal(t, map[string]string{"2": ErrNotReplayable.Error()}, result.Failed)
+	assert.Equal(t, [][]byte{[]byte(`{"n":1}`)}, q.Messages(testStream))
+
+	ids, err = SelectDeadLetters(ctx, q, []string{"3", "missing"}, DeadLetterFilter{})
+	require.NoError(t, err)
+	result = PurgeDeadLetters(ctx, q, ids)
+	assert.Equal(t, []string{"3"}, result.Succeeded)
+	assert.Contains(t, result.Failed, "missing")
+	assert.Len(t, q.DeadLetters(), 1)
+}
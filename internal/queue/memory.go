@@ -0,0 +1,282 @@
+package queue
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+)
+
+// MemoryQueue is an in-process Queue. Topics keep every published message, and
+// each consumer group reads them from the start, like a Redis stream group
+// created at ID 0.
+type MemoryQueue struct {
+	mu          sync.Mutex
+	cond        *sync.Cond
+	topics      map[string]*memoryTopic
+	deadLetters []model.DeadLetterEntry
+	replays     map[string]int
+	nextID      int64
+	closed      bool
+}
+
+type memoryTopic struct {
+	messages []Message
+	groups   map[string]*memoryGroup
+}
+
+// memoryGroup tracks the next unread message and the nacked messages waiting
+// to be delivered again once the group has caught up
+type memoryGroup struct {
+	next      int
+	redeliver []Message
+}
+
+func NewMemoryQueue() *MemoryQueue {
+	q := &MemoryQueue{
+		topics:  make(map[string]*memoryTopic),
+		replays: make(map[string]int),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *MemoryQueue) topic(name string) *memoryTopic {
+	t, ok := q.topics[name]
+	if !ok {
+		t = &memoryTopic{groups: make(map[string]*memoryGroup)}
+		q.topics[name] = t
+	}
+	return t
+}
+
+func (q *MemoryQueue) Publish(ctx context.Context, topic string, body []byte) error {
+	return q.publish(topic, Message{Body: body})
+}
+
+func (q *MemoryQueue) publish(topic string, msg Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+
+	q.nextID++
+	t := q.topic(topic)
+	t.messages = append(t.messages, Message{
+		ID:       strconv.FormatInt(q.nextID, 10),
+		Body:     append([]byte(nil), msg.Body...),
+		Attempts: append([]model.DeliveryAttempt(nil), msg.Attempts...),
+	})
+	q.cond.Broadcast()
+	return nil
+}
+
+// Schedule publishes msg from a timer, so a retry scheduled before Close is
+// dropped if it comes due afterwards
+func (q *MemoryQueue) Schedule(ctx context.Context, topic string, msg Message, at time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+
+	time.AfterFunc(time.Until(at), func() {
+		q.publish(topic, msg)
+	})
+	return nil
+}
+
+func (q *MemoryQueue) Consume(ctx context.Context, topic, group string, handler Handler) error {
+	return q.ConsumeBatch(ctx, topic, group, BatchPolicy{Size: 1}, single(handler))
+}
+
+func (q *MemoryQueue) ConsumeBatch(ctx context.Context, topic, group string, batch BatchPolicy, handler BatchHandler) error {
+	for {
+		msg, err := q.next(ctx, topic, group)
+		if err != nil {
+			return err
+		}
+		msgs := []Message{msg}
+		if len(msgs) < batch.Size {
+			fillCtx, cancel := context.WithTimeout(ctx, batch.Wait)
+			for len(msgs) < batch.Size {
+				msg, err := q.next(fillCtx, topic, group)
+				if err != nil {
+					break
+				}
+				msgs = append(msgs, msg)
+			}
+			cancel()
+		}
+
+		errs := handler(context.WithoutCancel(ctx), msgs)
+		q.mu.Lock()
+		g := q.topic(topic).groups[group]
+		for i, err := range errs {
+			if err != nil {
+				g.redeliver = append(g.redeliver, msgs[i])
+			}
+		}
+		q.cond.Broadcast()
+		q.mu.Unlock()
+	}
+}
+
+// next blocks until the group has a message to deliver
+func (q *MemoryQueue) next(ctx context.Context, topic, group string) (Message, error) {
+	stop := context.AfterFunc(ctx, func() {
+		q.mu.Lock()
+		q.cond.Broadcast()
+		q.mu.Unlock()
+	})
+	defer stop()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	t := q.topic(topic)
+	g, ok := t.groups[group]
+	if !ok {
+		g = &memoryGroup{}
+		t.groups[group] = g
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return Message{}, err
+		}
+		if q.closed {
+			return Message{}, ErrClosed
+		}
+		if g.next < len(t.messages) {
+			msg := t.messages[g.next]
+			g.next++
+			return msg, nil
+		}
+		if len(g.redeliver) > 0 {
+			msg := g.redeliver[0]
+			g.redeliver = g.redeliver[1:]
+			return msg, nil
+		}
+		q.cond.Wait()
+	}
+}
+
+func (q *MemoryQueue) DeadLetter(ctx context.Context, topic string, msg Message, reason error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+
+	q.nextID++
+	key := replayKey(topic, msg.Body)
+	q.deadLetters = append(q.deadLetters, model.DeadLetterEntry{
+		ID:         strconv.FormatInt(q.nextID, 10),
+		Queue:      topic,
+		EventJSON:  string(msg.Body),
+		ErrorMsg:   reason.Error(),
+		FailedAt:   time.Now().Unix(),
+		RetryCount: q.replays[key],
+		Attempts:   msg.Attempts,
+	})
+	delete(q.replays, key)
+	return nil
+}
+
+func (q *MemoryQueue) ListDeadLetters(ctx context.Context, filter DeadLetterFilter) ([]model.DeadLetterEntry, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var entries []model.DeadLetterEntry
+	for _, entry := range q.deadLetters {
+		if !filter.inRange(entry.FailedAt) || !filter.Matches(entry) {
+			continue
+		}
+		entries = append(entries, entry)
+		if filter.Limit > 0 && len(entries) == filter.Limit {
+			break
+		}
+	}
+	return entries, nil
+}
+
+func (q *MemoryQueue) GetDeadLetter(ctx context.Context, id string) (model.DeadLetterEntry, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if i := q.deadLetterIndex(id); i >= 0 {
+		return q.deadLetters[i], nil
+	}
+	return model.DeadLetterEntry{}, ErrDeadLetterNotFound
+}
+
+func (q *MemoryQueue) ReplayDeadLetter(ctx context.Context, id string) error {
+	entry, err := q.GetDeadLetter(ctx, id)
+	if err != nil {
+		return err
+	}
+	if entry.Queue == "" {
+		return ErrNotReplayable
+	}
+
+	body := []byte(entry.EventJSON)
+	q.mu.Lock()
+	q.replays[replayKey(entry.Queue, body)] = entry.RetryCount + 1
+	q.mu.Unlock()
+	if err := q.Publish(ctx, entry.Queue, body); err != nil {
+		return err
+	}
+	return q.DeleteDeadLetter(ctx, id)
+}
+
+func (q *MemoryQueue) DeleteDeadLetter(ctx context.Context, id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i := q.deadLetterIndex(id)
+	if i < 0 {
+		return ErrDeadLetterNotFound
+	}
+	q.deadLetters = append(q.deadLetters[:i], q.deadLetters[i+1:]...)
+	return nil
+}
+
+func (q *MemoryQueue) deadLetterIndex(id string) int {
+	for i, entry := range q.deadLetters {
+		if entry.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Close stops all consumers and rejects further publishing
+func (q *MemoryQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// Messages returns the bodies published to topic so far
+func (q *MemoryQueue) Messages(topic string) [][]byte {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	t, ok := q.topics[topic]
+	if !ok {
+		return nil
+	}
+	bodies := make([][]byte, len(t.messages))
+	for i, msg := range t.messages {
+		bodies[i] = msg.Body
+	}
+	return bodies
+}
+
+// DeadLetters returns the entries currently dead-lettered
+func (q *MemoryQueue) DeadLetters() []model.DeadLetterEntry {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]model.DeadLetterEntry(nil), q.deadLetters...)
+}
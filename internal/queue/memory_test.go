@@ -0,0 +1,106 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// consumeN runs a consumer until it has handled n messages and returns the
+// bodies in delivery order
+func consumeN(t *testing.T, q Queue, group string, n int, handler Handler) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var got []string
+	err := q.Consume(ctx, testStream, group, func(ctx context.Context, msg Message) error {
+		got = append(got, string(msg.Body))
+		err := handler(ctx, msg)
+		if len(got) == n {
+			cancel()
+		}
+		return err
+	})
+	require.ErrorIs(t, err, context.Canceled, "consumer timed out after %v", got)
+	return got
+}
+
+func ack(context.Context, Message) error { return nil }
+
+func TestMemoryQueue_GroupsSeeEveryMessage(t *testing.T) {
+	q := NewMemoryQueue()
+	publishAll(t, q, "first", "second")
+
+	assert.Equal(t, []string{"first", "second"}, consumeN(t, q, "a", 2, ack))
+	assert.Equal(t, []string{"first", "second"}, consumeN(t, q, "b", 2, ack))
+
+	// Acknowledged messages are not delivered to the group again
+	publishAll(t, q, "third")
+	assert.Equal(t, []string{"third"}, consumeN(t, q, "a", 1, ack))
+}
+
+func TestMemoryQueue_NackRedelivers(t *testing.T) {
+	q := NewMemoryQueue()
+	publishAll(t, q, "first", "second")
+
+	attempts := 0
+	got := consumeN(t, q, "a", 3, func(ctx context.Context, msg Message) error {
+		if string(msg.Body) == "first" {
+			attempts++
+			if attempts == 1 {
+				return errors.New("transient")
+			}
+		}
+		return nil
+	})
+	assert.Equal(t, []string{"first", "second", "first"}, got)
+}
+
+func TestMemoryQueue_WaitsForPublish(t *testing.T) {
+	q := NewMemoryQueue()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		publishAll(t, q, "late")
+	}()
+	assert.Equal(t, []string{"late"}, consumeN(t, q, "a", 1, ack))
+}
+
+func TestMemoryQueue_Close(t *testing.T) {
+	q := NewMemoryQueue()
+	done := make(chan error)
+	go func() {
+		done <- q.Consume(context.Background(), testStream, "a", ack)
+	}()
+
+	q.Close()
+	assert.ErrorIs(t, <-done, ErrClosed)
+	assert.ErrorIs(t, q.Publish(context.Background(), testStream, []byte("x")), ErrClosed)
+}
+
+func TestMemoryQueue_ConsumeBatch(t *testing.T) {
+	q := NewMemoryQueue()
+	publishAll(t, q, "first", "second", "third")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var batches [][]string
+	err := q.ConsumeBatch(ctx, testStream, "a", BatchPolicy{Size: 2, Wait: 10 * time.Millisecond}, func(ctx context.Context, msgs []Message) []error {
+		batches = append(batches, bodies(msgs))
+		errs := make([]error, len(msgs))
+		if len(batches) == 1 {
+			errs[1] = errors.New("transient")
+		}
+		if len(batches) == 2 {
+			cancel()
+		}
+		return errs
+	})
+	require.ErrorIs(t, err, context.Canceled)
+
+	// The nacked message is redelivered after the new one
+	assert.Equal(t, [][]string{{"first", "second"}, {"third", "second"}}, batches)
+}
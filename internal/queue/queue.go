@@ -0,0 +1,79 @@
+// Package queue decouples the worker pipeline from the message broker. The
+// Redis implementation backs production; the in-memory one backs tests.
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+)
+
+// ErrClosed is returned by operations on a closed queue
+var ErrClosed = errors.New("queue closed")
+
+// Message is one delivery from a topic. Attempts holds the failed attempts at
+// processing it so far, when it was scheduled for a retry.
+type Message struct {
+	ID       string
+	Body     []byte
+	Attempts []model.DeliveryAttempt
+}
+
+// Handler processes a delivered message. Returning nil acknowledges it;
+// returning an error nacks it so that it is delivered again later.
+type Handler func(ctx context.Context, msg Message) error
+
+// BatchHandler processes messages together and returns one error per message,
+// in the same order. Each message is acknowledged or nacked as with Handler.
+type BatchHandler func(ctx context.Context, msgs []Message) []error
+
+// BatchPolicy bounds the batches handed to a BatchHandler
+type BatchPolicy struct {
+	// Size is the most messages in one batch
+	Size int
+	// Wait is how long to keep filling a batch after its first message
+	// arrives
+	Wait time.Duration
+}
+
+// DefaultBatchPolicy favours throughput while keeping latency well under a
+// second
+var DefaultBatchPolicy = BatchPolicy{
+	Size: 500,
+	Wait: 50 * time.Millisecond,
+}
+
+// single adapts handler to process a batch one message at a time
+func single(handler Handler) BatchHandler {
+	return func(ctx context.Context, msgs []Message) []error {
+		errs := make([]error, len(msgs))
+		for i, msg := range msgs {
+			errs[i] = handler(ctx, msg)
+		}
+		return errs
+	}
+}
+
+type Queue interface {
+	// Publish appends body to topic
+	Publish(ctx context.Context, topic string, body []byte) error
+	// Consume delivers the messages of topic to handler, one at a time, until
+	// ctx is cancelled. Consumers sharing a group split the messages between
+	// them; every group sees every message. Cancelling ctx stops new
+	// deliveries but not the handler, whose ctx is never cancelled, so the
+	// message in flight is finished and acknowledged before Consume returns.
+	Consume(ctx context.Context, topic, group string, handler Handler) error
+	// ConsumeBatch is like Consume but hands handler up to batch.Size
+	// messages at a time, in delivery order
+	ConsumeBatch(ctx context.Context, topic, group string, batch BatchPolicy, handler BatchHandler) error
+	// Schedule publishes msg to topic once at has passed, keeping its
+	// attempt history
+	Schedule(ctx context.Context, topic string, msg Message, at time.Time) error
+	// DeadLetter sets aside a message that cannot be processed, along with
+	// the reason and its attempt history. topic is where it was consumed from
+	// so it can be replayed there later; it is empty for payloads that didn't
+	// come from a topic.
+	DeadLetter(ctx context.Context, topic string, msg Message, reason error) error
+}
@@ -0,0 +1,608 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/metrics"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+)
+
+var (
+	streamLog    = logging.Component("stream")
+	schedulerLog = logging.Component("scheduler")
+)
+
+// Dead-lettered entries are stored as JSON in a hash keyed by ID and indexed
+// by failure time in a sorted set
+const (
+	deadLetterEntriesKey = "dead_letter:entries"
+	deadLetterIndexKey   = "dead_letter:index"
+	deadLetterSeqKey     = "dead_letter:seq"
+	deadLetterReplaysKey = "dead_letter:replays"
+)
+
+// retryScheduleKey is a sorted set of messages waiting to be retried, scored
+// by the Unix time in milliseconds at which they are due
+const retryScheduleKey = "retry:scheduled"
+
+// legacyDeadLetterLists are the Redis lists dead letters used to be pushed to
+var legacyDeadLetterLists = []string{"event_log:dead_letter", "event_log:dead_letter_queue"}
+
+const (
+	// DefaultStreamMaxLen bounds each stream; XADD trims approximately past it
+	DefaultStreamMaxLen = 100000
+	// DefaultClaimIdle is how long a message may sit unacknowledged with one
+	// consumer before another consumer claims it
+	DefaultClaimIdle = time.Minute
+
+	// DefaultSchedulePoll is how often RunScheduler looks for due retries
+	DefaultSchedulePoll = time.Second
+
+	streamDataField     = "data"
+	streamAttemptsField = "attempts"
+	streamReadCount     = 100
+	streamReadBlock     = 5 * time.Second
+	streamRetryPause    = time.Second
+	streamClaimPeriod   = DefaultClaimIdle / 2
+	scheduleBatch       = 100
+)
+
+// RedisQueue maps topics onto Redis streams and groups onto consumer groups.
+// Messages stay pending until acknowledged, so a crash mid-processing leaves
+// them to be read again on restart or claimed by another consumer.
+type RedisQueue struct {
+	rdb      *redis.Client
+	consumer string
+}
+
+// NewRedisQueue creates a queue that consumes under the given consumer name.
+// A stable name lets a restarted process pick up its own pending messages;
+// publish-only users may leave it empty.
+func NewRedisQueue(rdb *redis.Client, consumer string) *RedisQueue {
+	return &RedisQueue{rdb: rdb, consumer: consumer}
+}
+
+// Publish appends body to the topic's stream, trimming it to roughly
+// DefaultStreamMaxLen entries
+func (q *RedisQueue) Publish(ctx context.Context, topic string, body []byte) error {
+	start := time.Now()
+	err := q.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: topic,
+		MaxLen: DefaultStreamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{streamDataField: body},
+	}).Err()
+	metrics.RedisPushDuration.WithLabelValues(topic).Observe(metrics.Since(start))
+	if err != nil {
+		metrics.RedisPushErrors.WithLabelValues(topic).Inc()
+	}
+	return err
+}
+
+// scheduledMessage is the sorted set member for a scheduled retry. ID keeps
+// members for identical bodies apart.
+type scheduledMessage struct {
+	ID       string `json:"id"`
+	Topic    string `json:"topic"`
+	Body     string `json:"body"`
+	Attempts string `json:"attempts"`
+}
+
+// Schedule adds msg to the retry sorted set. RunScheduler publishes it once it
+// is due.
+func (q *RedisQueue) Schedule(ctx context.Context, topic string, msg Message, at time.Time) error {
+	attempts, err := json.Marshal(msg.Attempts)
+	if err != nil {
+		return err
+	}
+	member, err := json.Marshal(scheduledMessage{
+		ID:       msg.ID,
+		Topic:    topic,
+		Body:     string(msg.Body),
+		Attempts: string(attempts),
+	})
+	if err != nil {
+		return err
+	}
+	return q.rdb.ZAdd(ctx, retryScheduleKey, redis.Z{
+		Score:  float64(at.UnixMilli()),
+		Member: member,
+	}).Err()
+}
+
+// publishDueScript moves due retries from the sorted set onto their streams.
+// Removing and publishing happen in one script so that concurrent schedulers
+// never publish a retry twice and a crash never loses one. The stream keys
+// come from the members, so this assumes a single Redis node.
+var publishDueScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, member in ipairs(due) do
+	redis.call('ZREM', KEYS[1], member)
+	local msg = cjson.decode(member)
+	redis.call('XADD', msg.topic, 'MAXLEN', '~', ARGV[3], '*', 'data', msg.body, 'attempts', msg.attempts)
+end
+return #due
+`)
+
+// publishDue publishes up to one batch of retries due by now and returns how
+// many it published
+func (q *RedisQueue) publishDue(ctx context.Context, now time.Time) (int, error) {
+	return publishDueScript.Run(ctx, q.rdb, []string{retryScheduleKey},
+		now.UnixMilli(), scheduleBatch, DefaultStreamMaxLen).Int()
+}
+
+// RunScheduler publishes scheduled retries as they come due until ctx is
+// cancelled. Every worker process may run one.
+func (q *RedisQueue) RunScheduler(ctx context.Context, poll time.Duration) {
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			n, err := q.publishDue(ctx, time.Now())
+			if err != nil {
+				schedulerLog.ErrorContext(ctx, "Failed to publish due retries", logging.Err(err))
+				break
+			}
+			if n < scheduleBatch {
+				break
+			}
+		}
+	}
+}
+
+// Scheduled returns the number of retries waiting to come due
+func (q *RedisQueue) Scheduled(ctx context.Context) (int64, error) {
+	return q.rdb.ZCard(ctx, retryScheduleKey).Result()
+}
+
+// DeadLetters returns the number of dead-lettered entries
+func (q *RedisQueue) DeadLetters(ctx context.Context) (int64, error) {
+	return q.rdb.ZCard(ctx, deadLetterIndexKey).Result()
+}
+
+// Consume first drains this consumer's own pending messages, then reads new
+// ones, periodically claiming messages left pending for over DefaultClaimIdle.
+// A nacked message stays pending, so it is delivered again by such a claim.
+func (q *RedisQueue) Consume(ctx context.Context, topic, group string, handler Handler) error {
+	return q.ConsumeBatch(ctx, topic, group, BatchPolicy{Size: 1}, single(handler))
+}
+
+// ConsumeBatch reads like Consume. After a read returns fewer messages than a
+// batch, it keeps reading until the batch fills or batch.Wait passes. Each
+// batch is acknowledged in one XACK once handled.
+func (q *RedisQueue) ConsumeBatch(ctx context.Context, topic, group string, batch BatchPolicy, handler BatchHandler) error {
+	c := &streamConsumer{
+		rdb:       q.rdb,
+		stream:    topic,
+		group:     group,
+		consumer:  q.consumer,
+		claimIdle: DefaultClaimIdle,
+		batch:     batch,
+	}
+	return c.run(ctx, handler)
+}
+
+// DeadLetter stores a model.DeadLetterEntry for the message, carrying over
+// the retry count if it was replayed before
+func (q *RedisQueue) DeadLetter(ctx context.Context, topic string, msg Message, reason error) error {
+	entry := model.DeadLetterEntry{
+		Queue:     topic,
+		EventJSON: string(msg.Body),
+		ErrorMsg:  reason.Error(),
+		FailedAt:  time.Now().Unix(),
+		Attempts:  msg.Attempts,
+	}
+
+	key := replayKey(topic, msg.Body)
+	retries, err := q.rdb.HGet(ctx, deadLetterReplaysKey, key).Int()
+	switch {
+	case err == nil:
+		entry.RetryCount = retries
+		q.rdb.HDel(ctx, deadLetterReplaysKey, key)
+	case !errors.Is(err, redis.Nil):
+		return err
+	}
+	return q.storeDeadLetter(ctx, entry)
+}
+
+func (q *RedisQueue) storeDeadLetter(ctx context.Context, entry model.DeadLetterEntry) error {
+	seq, err := q.rdb.Incr(ctx, deadLetterSeqKey).Result()
+	if err != nil {
+		return err
+	}
+	entry.ID = strconv.FormatInt(seq, 10)
+
+	entryJSON, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	pipe := q.rdb.TxPipeline()
+	pipe.HSet(ctx, deadLetterEntriesKey, entry.ID, entryJSON)
+	pipe.ZAdd(ctx, deadLetterIndexKey, redis.Z{Score: float64(entry.FailedAt), Member: entry.ID})
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (q *RedisQueue) ListDeadLetters(ctx context.Context, filter DeadLetterFilter) ([]model.DeadLetterEntry, error) {
+	scoreRange := &redis.ZRangeBy{Min: "-inf", Max: "+inf"}
+	if !filter.Since.IsZero() {
+		scoreRange.Min = strconv.FormatInt(filter.Since.Unix(), 10)
+	}
+	if !filter.Until.IsZero() {
+		scoreRange.Max = strconv.FormatInt(filter.Until.Unix(), 10)
+	}
+	ids, err := q.rdb.ZRangeByScore(ctx, deadLetterIndexKey, scoreRange).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	values, err := q.rdb.HMGet(ctx, deadLetterEntriesKey, ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]model.DeadLetterEntry, 0, len(values))
+	for _, value := range values {
+		raw, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var entry model.DeadLetterEntry
+		if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+			return nil, err
+		}
+		if !filter.Matches(entry) {
+			continue
+		}
+		entries = append(entries, entry)
+		if filter.Limit > 0 && len(entries) == filter.Limit {
+			break
+		}
+	}
+	return entries, nil
+}
+
+func (q *RedisQueue) GetDeadLetter(ctx context.Context, id string) (model.DeadLetterEntry, error) {
+	var entry model.DeadLetterEntry
+	raw, err := q.rdb.HGet(ctx, deadLetterEntriesKey, id).Result()
+	if errors.Is(err, redis.Nil) {
+		return entry, ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return entry, err
+	}
+	err = json.Unmarshal([]byte(raw), &entry)
+	return entry, err
+}
+
+func (q *RedisQueue) ReplayDeadLetter(ctx context.Context, id string) error {
+	entry, err := q.GetDeadLetter(ctx, id)
+	if err != nil {
+		return err
+	}
+	if entry.Queue == "" {
+		return ErrNotReplayable
+	}
+
+	body := []byte(entry.EventJSON)
+	if err := q.rdb.HSet(ctx, deadLetterReplaysKey, replayKey(entry.Queue, body), entry.RetryCount+1).Err(); err != nil {
+		return err
+	}
+	if err := q.Publish(ctx, entry.Queue, body); err != nil {
+		return err
+	}
+	return q.DeleteDeadLetter(ctx, id)
+}
+
+func (q *RedisQueue) DeleteDeadLetter(ctx context.Context, id string) error {
+	pipe := q.rdb.TxPipeline()
+	deleted := pipe.HDel(ctx, deadLetterEntriesKey, id)
+	pipe.ZRem(ctx, deadLetterIndexKey, id)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	if deleted.Val() == 0 {
+		return ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+// ImportLegacyDeadLetters moves entries from the lists dead letters used to be
+// pushed to into the store. They don't record their queue, so they can be
+// inspected and purged but not replayed.
+func (q *RedisQueue) ImportLegacyDeadLetters(ctx context.Context) (int, error) {
+	imported := 0
+	for _, list := range legacyDeadLetterLists {
+		for {
+			raw, err := q.rdb.LPop(ctx, list).Result()
+			if errors.Is(err, redis.Nil) {
+				break
+			}
+			if err != nil {
+				return imported, err
+			}
+
+			var entry model.DeadLetterEntry
+			if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+				entry = model.DeadLetterEntry{EventJSON: raw, ErrorMsg: err.Error(), FailedAt: time.Now().Unix()}
+			}
+			if err := q.storeDeadLetter(ctx, entry); err != nil {
+				return imported, err
+			}
+			imported++
+		}
+	}
+	return imported, nil
+}
+
+// streamConsumer reads a stream as one named consumer of a consumer group
+type streamConsumer struct {
+	rdb       *redis.Client
+	stream    string
+	group     string
+	consumer  string
+	claimIdle time.Duration
+	batch     BatchPolicy
+}
+
+// ensureGroup creates the stream and consumer group if they don't exist yet
+func (c *streamConsumer) ensureGroup(ctx context.Context) error {
+	err := c.rdb.XGroupCreateMkStream(ctx, c.stream, c.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+// pending returns messages already delivered to this consumer but not yet
+// acknowledged, such as those left by a previous run with the same name
+func (c *streamConsumer) pending(ctx context.Context) ([]Message, error) {
+	return c.read(ctx, "0", -1, streamReadCount)
+}
+
+// readNew blocks briefly for new messages and returns nothing on timeout. A
+// batching consumer then keeps reading until it has a full batch or the batch
+// wait has passed.
+func (c *streamConsumer) readNew(ctx context.Context) ([]Message, error) {
+	messages, err := c.read(ctx, ">", streamReadBlock, int64(max(streamReadCount, c.batch.Size)))
+	if err != nil || len(messages) == 0 {
+		return messages, err
+	}
+
+	deadline := time.Now().Add(c.batch.Wait)
+	for len(messages) < c.batch.Size {
+		// A zero block would wait forever
+		remaining := time.Until(deadline)
+		if remaining < time.Millisecond {
+			break
+		}
+		more, err := c.read(ctx, ">", remaining, int64(c.batch.Size-len(messages)))
+		if err != nil {
+			// The messages read so far are already pending, so hand them on
+			// and let the next read report the error
+			break
+		}
+		messages = append(messages, more...)
+	}
+	return messages, nil
+}
+
+func (c *streamConsumer) read(ctx context.Context, id string, block time.Duration, count int64) ([]Message, error) {
+	streams, err := c.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    c.group,
+		Consumer: c.consumer,
+		Streams:  []string{c.stream, id},
+		Count:    count,
+		Block:    block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []Message
+	for _, stream := range streams {
+		messages = append(messages, decodeStreamMessages(stream.Messages)...)
+	}
+	return messages, nil
+}
+
+// claimStale takes over messages left pending for longer than the claim idle
+// time, whether by dead consumers or by this one failing to requeue a nack
+func (c *streamConsumer) claimStale(ctx context.Context) ([]Message, error) {
+	pending, err := c.rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: c.stream,
+		Group:  c.group,
+		Idle:   c.claimIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  streamReadCount,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pending) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, len(pending))
+	for i, p := range pending {
+		ids[i] = p.ID
+	}
+
+	claimed, err := c.rdb.XClaim(ctx, &redis.XClaimArgs{
+		Stream:   c.stream,
+		Group:    c.group,
+		Consumer: c.consumer,
+		MinIdle:  c.claimIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	return decodeStreamMessages(claimed), nil
+}
+
+// ack marks messages as processed
+func (c *streamConsumer) ack(ctx context.Context, ids ...string) error {
+	return c.rdb.XAck(ctx, c.stream, c.group, ids...).Err()
+}
+
+// run hands every message to handle in batches and acknowledges those it
+// doesn't fail on, until ctx is cancelled
+func (c *streamConsumer) run(ctx context.Context, handle BatchHandler) error {
+	for {
+		err := c.ensureGroup(ctx)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		streamLog.ErrorContext(ctx, "Failed to create consumer group", logging.Queue(c.stream), slog.String("group", c.group), logging.Err(err))
+		time.Sleep(streamRetryPause)
+	}
+
+	pending, err := c.pending(ctx)
+	if err != nil {
+		streamLog.ErrorContext(ctx, "Failed to read pending messages", logging.Queue(c.stream), logging.Err(err))
+	} else if len(pending) > 0 {
+		streamLog.InfoContext(ctx, "Reprocessing pending messages", logging.Queue(c.stream), slog.Int("count", len(pending)))
+	}
+	c.process(ctx, pending, handle)
+
+	lastClaim := time.Now()
+	for ctx.Err() == nil {
+		if time.Since(lastClaim) >= streamClaimPeriod {
+			lastClaim = time.Now()
+			claimed, err := c.claimStale(ctx)
+			if err != nil {
+				streamLog.ErrorContext(ctx, "Failed to claim stale messages", logging.Queue(c.stream), logging.Err(err))
+			} else if len(claimed) > 0 {
+				streamLog.InfoContext(ctx, "Claimed stale messages", logging.Queue(c.stream), slog.Int("count", len(claimed)))
+			}
+			c.process(ctx, claimed, handle)
+		}
+
+		messages, err := c.readNew(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			streamLog.ErrorContext(ctx, "Failed to read from stream", logging.Queue(c.stream), logging.Err(err))
+			time.Sleep(streamRetryPause)
+			continue
+		}
+		c.process(ctx, messages, handle)
+	}
+	return ctx.Err()
+}
+
+// process hands messages to handle in batches, acknowledging each batch as
+// soon as it has been handled. Once ctx is cancelled the batch in flight is
+// still handled and acknowledged, while later ones are left pending for the
+// next run.
+func (c *streamConsumer) process(ctx context.Context, messages []Message, handle BatchHandler) {
+	work := context.WithoutCancel(ctx)
+	var dropped []string
+	batch := make([]Message, 0, len(messages))
+	for _, msg := range messages {
+		if len(msg.Body) == 0 {
+			streamLog.WarnContext(ctx, "Dropping empty message", logging.Queue(c.stream), slog.String("message_id", msg.ID))
+			dropped = append(dropped, msg.ID)
+			continue
+		}
+		batch = append(batch, msg)
+	}
+	c.ackAll(work, dropped)
+
+	size := max(c.batch.Size, 1)
+	for start := 0; start < len(batch) && ctx.Err() == nil; start += size {
+		chunk := batch[start:min(start+size, len(batch))]
+		var handled []string
+		for i, err := range handle(work, chunk) {
+			if err != nil {
+				streamLog.WarnContext(ctx, "Leaving message pending", logging.Queue(c.stream), slog.String("message_id", chunk[i].ID), logging.Err(err))
+				continue
+			}
+			handled = append(handled, chunk[i].ID)
+		}
+		c.ackAll(work, handled)
+	}
+}
+
+// ackAll acknowledges ids, logging rather than returning a failure since the
+// messages are then simply claimed and handled again
+func (c *streamConsumer) ackAll(ctx context.Context, ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+	if err := c.ack(ctx, ids...); err != nil {
+		streamLog.ErrorContext(ctx, "Failed to acknowledge messages", logging.Queue(c.stream), slog.Int("count", len(ids)), logging.Err(err))
+	}
+}
+
+func decodeStreamMessages(entries []redis.XMessage) []Message {
+	messages := make([]Message, 0, len(entries))
+	for _, entry := range entries {
+		// Entries trimmed from the stream before delivery come back empty
+		body, _ := entry.Values[streamDataField].(string)
+		msg := Message{ID: entry.ID, Body: []byte(body)}
+		// Only retries carry attempts. A malformed history is dropped rather
+		// than holding up the message.
+		if attempts, ok := entry.Values[streamAttemptsField].(string); ok {
+			json.Unmarshal([]byte(attempts), &msg.Attempts)
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+// StreamStatus summarises the backlog of one consumer group
+type StreamStatus struct {
+	Stream  string
+	Group   string
+	Length  int64
+	Pending int64
+	Lag     int64
+}
+
+// Status reports the stream length, messages delivered but not yet
+// acknowledged, and messages not yet delivered to the group
+func (q *RedisQueue) Status(ctx context.Context, stream, group string) (StreamStatus, error) {
+	status := StreamStatus{Stream: stream, Group: group}
+	length, err := q.rdb.XLen(ctx, stream).Result()
+	if err != nil {
+		return status, err
+	}
+	status.Length = length
+
+	groups, err := q.rdb.XInfoGroups(ctx, stream).Result()
+	if err != nil {
+		return status, err
+	}
+	for _, g := range groups {
+		if g.Name == group {
+			status.Pending = g.Pending
+			status.Lag = g.Lag
+			return status, nil
+		}
+	}
+	return status, fmt.Errorf("consumer group %s not found on %s", group, stream)
+}
@@ -0,0 +1,236 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+)
+
+const testStream = "test:stream"
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return mr, rdb
+}
+
+func newTestConsumer(rdb *redis.Client, consumer string) *streamConsumer {
+	return &streamConsumer{
+		rdb:       rdb,
+		stream:    testStream,
+		group:     "test_workers",
+		consumer:  consumer,
+		claimIdle: DefaultClaimIdle,
+	}
+}
+
+func publishAll(t *testing.T, q Queue, bodies ...string) {
+	for _, body := range bodies {
+		require.NoError(t, q.Publish(context.Background(), testStream, []byte(body)))
+	}
+}
+
+func bodies(messages []Message) []string {
+	out := make([]string, len(messages))
+	for i, msg := range messages {
+		out[i] = string(msg.Body)
+	}
+	return out
+}
+
+func TestStreamConsumer_RestartRereadsUnacknowledged(t *testing.T) {
+	ctx := context.Background()
+	_, rdb := newTestRedis(t)
+	consumer := newTestConsumer(rdb, "worker-1")
+	require.NoError(t, consumer.ensureGroup(ctx))
+	require.NoError(t, consumer.ensureGroup(ctx), "creating an existing group is not an error")
+	publishAll(t, NewRedisQueue(rdb, ""), "first", "second")
+
+	// The worker reads both messages, saves the first and crashes
+	messages, err := consumer.readNew(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"first", "second"}, bodies(messages))
+	require.NoError(t, consumer.ack(ctx, messages[0].ID))
+
+	// After a restart under the same name only the second is pending
+	restarted := newTestConsumer(rdb, "worker-1")
+	pending, err := restarted.pending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"second"}, bodies(pending))
+
+	messages, err = restarted.readNew(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, messages, "new reads must not redeliver pending messages")
+}
+
+func TestStreamConsumer_ClaimStaleFromDeadConsumer(t *testing.T) {
+	ctx := context.Background()
+	mr, rdb := newTestRedis(t)
+	crashed := newTestConsumer(rdb, "crashed")
+	survivor := newTestConsumer(rdb, "survivor")
+	require.NoError(t, crashed.ensureGroup(ctx))
+	publishAll(t, NewRedisQueue(rdb, ""), "first")
+
+	// The crashed consumer reads the message and never acknowledges it
+	messages, err := crashed.readNew(ctx)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+
+	claimed, err := survivor.claimStale(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, claimed, "recently delivered messages must not be claimed")
+
+	mr.SetTime(time.Now().Add(DefaultClaimIdle + time.Second))
+	claimed, err = survivor.claimStale(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"first"}, bodies(claimed))
+
+	require.NoError(t, survivor.ack(ctx, claimed[0].ID))
+	status, err := NewRedisQueue(rdb, "").Status(ctx, testStream, "test_workers")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), status.Pending)
+}
+
+func TestStreamConsumer_NackStaysPendingUntilClaimed(t *testing.T) {
+	ctx := context.Background()
+	mr, rdb := newTestRedis(t)
+	consumer := newTestConsumer(rdb, "worker-1")
+	require.NoError(t, consumer.ensureGroup(ctx))
+	publishAll(t, NewRedisQueue(rdb, ""), "first", "second")
+
+	messages, err := consumer.readNew(ctx)
+	require.NoError(t, err)
+	consumer.process(ctx, messages, single(func(ctx context.Context, msg Message) error {
+		if string(msg.Body) == "first" {
+			return errors.New("transient")
+		}
+		return nil
+	}))
+
+	status, err := NewRedisQueue(rdb, "").Status(ctx, testStream, "test_workers")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), status.Pending)
+
+	mr.SetTime(time.Now().Add(DefaultClaimIdle + time.Second))
+	claimed, err := consumer.claimStale(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first"}, bodies(claimed))
+}
+
+func TestRedisQueue_ConsumeAcknowledgesHandled(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	q := NewRedisQueue(rdb, "worker-1")
+	publishAll(t, q, "first", "second")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handled := make(chan string, 2)
+	done := make(chan error)
+	go func() {
+		done <- q.Consume(ctx, testStream, "test_workers", func(ctx context.Context, msg Message) error {
+			handled <- string(msg.Body)
+			return nil
+		})
+	}()
+
+	assert.Equal(t, "first", <-handled)
+	assert.Equal(t, "second", <-handled)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	status, err := q.Status(context.Background(), testStream, "test_workers")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), status.Length)
+	assert.Equal(t, int64(0), status.Pending)
+}
+
+func TestRedisQueue_SchedulePublishesWhenDue(t *testing.T) {
+	ctx := context.Background()
+	_, rdb := newTestRedis(t)
+	q := NewRedisQueue(rdb, "worker-1")
+	consumer := newTestConsumer(rdb, "worker-1")
+	require.NoError(t, consumer.ensureGroup(ctx))
+
+	now := time.Now()
+	attempts := []model.DeliveryAttempt{{Error: "connection refused", FailedAt: now.Unix()}}
+	require.NoError(t, q.Schedule(ctx, testStream, Message{ID: "1-0", Body: []byte("soon"), Attempts: attempts}, now.Add(time.Second)))
+	require.NoError(t, q.Schedule(ctx, testStream, Message{ID: "2-0", Body: []byte("later"), Attempts: attempts}, now.Add(time.Minute)))
+
+	n, err := q.publishDue(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "nothing is due yet")
+
+	n, err = q.publishDue(ctx, now.Add(2*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	messages, err := consumer.readNew(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"soon"}, bodies(messages))
+	assert.Equal(t, attempts, messages[0].Attempts)
+
+	scheduled, err := q.Scheduled(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), scheduled)
+}
+
+func TestRedisQueue_ConsumeBatchFillsBatches(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	q := NewRedisQueue(rdb, "worker-1")
+	publishAll(t, q, "first", "second", "third")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	batches := make(chan []string, 2)
+	done := make(chan error)
+	go func() {
+		done <- q.ConsumeBatch(ctx, testStream, "test_workers", BatchPolicy{Size: 2, Wait: 10 * time.Millisecond}, func(ctx context.Context, msgs []Message) []error {
+			batches <- bodies(msgs)
+			return make([]error, len(msgs))
+		})
+	}()
+
+	assert.Equal(t, []string{"first", "second"}, <-batches)
+	assert.Equal(t, []string{"third"}, <-batches)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	status, err := q.Status(context.Background(), testStream, "test_workers")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), status.Pending)
+}
+
+func TestRedisQueue_ConsumeFinishesInFlightOnCancel(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	q := NewRedisQueue(rdb, "worker-1")
+	publishAll(t, q, "first")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	handlerErr := make(chan error, 1)
+	done := make(chan error)
+	go func() {
+		done <- q.Consume(ctx, testStream, "test_workers", func(ctx context.Context, msg Message) error {
+			close(started)
+			// Shutdown begins while the message is being handled
+			cancel()
+			handlerErr <- ctx.Err()
+			return nil
+		})
+	}()
+
+	<-started
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.NoError(t, <-handlerErr, "the handler must be able to finish its work")
+
+	status, err := q.Status(context.Background(), testStream, "test_workers")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), status.Pending, "the in-flight message must be acknowledged")
+}
@@ -0,0 +1,117 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/logging"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+)
+
+var retryLog = logging.Component("retry")
+
+// RetryPolicy decides how often and how soon a failed message is retried
+type RetryPolicy struct {
+	// MaxAttempts counts the first attempt; 1 or less disables retries
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. It doubles with every
+	// further retry, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Jitter is the fraction of each delay, between 0 and 1, that is chosen
+	// at random so that retries of a failed batch spread out
+	Jitter float64
+}
+
+// DefaultRetryPolicy retries over roughly half a minute before giving up
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   time.Second,
+	MaxDelay:    time.Minute,
+	Jitter:      0.2,
+}
+
+// Backoff returns the delay before the retry that follows the given number of
+// failed attempts
+func (p RetryPolicy) Backoff(failures int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		delay -= time.Duration(rand.Float64() * p.Jitter * float64(delay))
+	}
+	return delay
+}
+
+// permanentError marks a failure that retrying can't fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so that Retry dead-letters the message straight away,
+// e.g. when it can't be decoded
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// Retry wraps handler so that a message it fails on is scheduled on topic
+// again after the policy's backoff. Once the policy is exhausted, or the error
+// is permanent, the message is dead-lettered with its attempt history. The
+// wrapped handler only fails, leaving the message to be redelivered, when
+// scheduling or dead-lettering does.
+func Retry(q Queue, topic string, policy RetryPolicy, handler Handler) Handler {
+	return func(ctx context.Context, msg Message) error {
+		err := handler(ctx, msg)
+		if err == nil {
+			return nil
+		}
+		return retryFailed(ctx, q, topic, policy, msg, err)
+	}
+}
+
+// RetryBatch is Retry for a BatchHandler, applied to each failed message
+func RetryBatch(q Queue, topic string, policy RetryPolicy, handler BatchHandler) BatchHandler {
+	return func(ctx context.Context, msgs []Message) []error {
+		errs := handler(ctx, msgs)
+		for i, err := range errs {
+			if err != nil {
+				errs[i] = retryFailed(ctx, q, topic, policy, msgs[i], err)
+			}
+		}
+		return errs
+	}
+}
+
+func retryFailed(ctx context.Context, q Queue, topic string, policy RetryPolicy, msg Message, err error) error {
+	now := time.Now()
+	msg.Attempts = append(msg.Attempts, model.DeliveryAttempt{
+		Error:    err.Error(),
+		FailedAt: now.Unix(),
+	})
+	if !IsPermanent(err) && len(msg.Attempts) < policy.MaxAttempts {
+		delay := policy.Backoff(len(msg.Attempts))
+		retryLog.WarnContext(ctx, "Attempt failed, retrying", logging.Queue(topic),
+			slog.Int("attempt", len(msg.Attempts)), slog.Duration("delay", delay), logging.Err(err))
+		return q.Schedule(ctx, topic, msg, now.Add(delay))
+	}
+
+	retryLog.ErrorContext(ctx, "Dead-lettering message", logging.Queue(topic),
+		slog.Int("attempts", len(msg.Attempts)), logging.Err(err))
+	return q.DeadLetter(ctx, topic, msg, err)
+}
@@ -0,0 +1,85 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	assert.Equal(t, time.Second, policy.Backoff(1))
+	assert.Equal(t, 2*time.Second, policy.Backoff(2))
+	assert.Equal(t, 8*time.Second, policy.Backoff(4))
+	assert.Equal(t, 10*time.Second, policy.Backoff(5))
+	assert.Equal(t, 10*time.Second, policy.Backoff(60))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay := policy.Backoff(2)
+		assert.GreaterOrEqual(t, delay, time.Second)
+		assert.LessOrEqual(t, delay, 2*time.Second)
+	}
+}
+
+func TestRetry_SucceedsAfterTransientFailures(t *testing.T) {
+	q := NewMemoryQueue()
+	publishAll(t, q, "first")
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+	var seen [][]string
+	handler := Retry(q, testStream, policy, func(ctx context.Context, msg Message) error {
+		var errs []string
+		for _, attempt := range msg.Attempts {
+			errs = append(errs, attempt.Error)
+		}
+		seen = append(seen, errs)
+		if len(seen) < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	got := consumeN(t, q, "a", 3, handler)
+
+	assert.Equal(t, []string{"first", "first", "first"}, got)
+	assert.Equal(t, [][]string{nil, {"transient"}, {"transient", "transient"}}, seen)
+	assert.Empty(t, q.DeadLetters())
+}
+
+func TestRetry_DeadLettersWhenExhausted(t *testing.T) {
+	q := NewMemoryQueue()
+	publishAll(t, q, "first")
+	policy := RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}
+
+	handler := Retry(q, testStream, policy, func(ctx context.Context, msg Message) error {
+		return errors.New("connection refused")
+	})
+	consumeN(t, q, "a", 2, handler)
+
+	entries := q.DeadLetters()
+	require.Len(t, entries, 1)
+	assert.Equal(t, testStream, entries[0].Queue)
+	assert.Equal(t, "first", entries[0].EventJSON)
+	assert.Equal(t, "connection refused", entries[0].ErrorMsg)
+	require.Len(t, entries[0].Attempts, 2)
+	assert.Equal(t, "connection refused", entries[0].Attempts[1].Error)
+}
+
+func TestRetry_DeadLettersPermanentErrors(t *testing.T) {
+	q := NewMemoryQueue()
+	publishAll(t, q, "first")
+
+	handler := Retry(q, testStream, DefaultRetryPolicy, func(ctx context.Context, msg Message) error {
+		return Permanent(errors.New("invalid character"))
+	})
+	consumeN(t, q, "a", 1, handler)
+
+	entries := q.DeadLetters()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "invalid character", entries[0].ErrorMsg)
+	assert.Len(t, entries[0].Attempts, 1)
+}
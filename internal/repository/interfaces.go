@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -9,16 +10,80 @@ import (
 
 // Common repository errors
 var (
-	ErrVehicleNotFound = errors.New("vehicle not found")
-	ErrEventNotFound   = errors.New("event not found")
+	ErrVehicleNotFound      = errors.New("vehicle not found")
+	ErrEventNotFound        = errors.New("event not found")
+	ErrGeofenceNotFound     = errors.New("geofence not found")
+	ErrVehicleGroupNotFound = errors.New("vehicle group not found")
+	ErrVehicleGroupExists   = errors.New("vehicle group already exists")
+	ErrVehicleGroupAssigned = errors.New("vehicle group is assigned to geofences")
+	ErrAssignmentNotFound   = errors.New("geofence assignment not found")
 )
 
+// LocationFilter narrows FindLocations results. Zero values are ignored.
+type LocationFilter struct {
+	VehicleID string
+	Start     time.Time
+	End       time.Time
+	// Attributes matches locations whose attributes hold every key with the
+	// given value
+	Attributes map[string]any
+	Limit      int
+}
+
 type VehicleRepository interface {
-	InsertLocation(loc *model.VehicleLocation) error
-	GetLatestLocation(vehicleID string) (*model.VehicleLocation, error)
-	GetLocationHistory(vehicleID string, start, end time.Time) ([]*model.VehicleLocation, error)
+	InsertLocation(ctx context.Context, loc *model.VehicleLocation) error
+	// InsertLocations saves all of locs or, on error, none of them
+	InsertLocations(ctx context.Context, locs []*model.VehicleLocation) error
+	GetLatestLocation(ctx context.Context, vehicleID string) (*model.VehicleLocation, error)
+	GetLocationHistory(ctx context.Context, vehicleID string, start, end time.Time) ([]*model.VehicleLocation, error)
+	// FindLocations returns the matching locations, oldest first
+	FindLocations(ctx context.Context, filter LocationFilter) ([]*model.VehicleLocation, error)
 }
 
 type EventLogRepository interface {
-	InsertEvent(evt *model.EventLog) error
+	InsertEvent(ctx context.Context, evt *model.EventLog) error
+}
+
+// GeofenceFilter narrows ListGeofences results. Zero values are ignored.
+type GeofenceFilter struct {
+	Active *bool
+	Shape  string
+	Name   string // case-insensitive substring match
+	Limit  int
+	Offset int
+}
+
+type GeofenceRepository interface {
+	InsertGeofence(ctx context.Context, geofence *model.Geofence) error
+	GetGeofence(ctx context.Context, id int64) (*model.Geofence, error)
+	ListGeofences(ctx context.Context, filter GeofenceFilter) ([]*model.Geofence, error)
+	UpdateGeofence(ctx context.Context, geofence *model.Geofence) error
+	SetGeofenceActive(ctx context.Context, id int64, active bool) error
+	DeleteGeofence(ctx context.Context, id int64) error
+}
+
+type GeofenceEventRepository interface {
+	InsertGeofenceEvent(ctx context.Context, evt *model.GeofenceEvent) error
+	// LatestGeofenceStates returns the most recent event of each type for
+	// every vehicle and geofence pair
+	LatestGeofenceStates(ctx context.Context) ([]*model.GeofenceEvent, error)
+}
+
+type VehicleGroupRepository interface {
+	InsertVehicleGroup(ctx context.Context, group *model.VehicleGroup) error
+	ListVehicleGroups(ctx context.Context) ([]*model.VehicleGroup, error)
+	DeleteVehicleGroup(ctx context.Context, id int64) error
+	ListGroupVehicles(ctx context.Context, groupID int64) ([]string, error)
+	AddGroupVehicle(ctx context.Context, groupID int64, vehicleID string) error
+	RemoveGroupVehicle(ctx context.Context, groupID int64, vehicleID string) error
+}
+
+type GeofenceAssignmentRepository interface {
+	InsertGeofenceAssignment(ctx context.Context, assignment *model.GeofenceAssignment) error
+	ListGeofenceAssignments(ctx context.Context, geofenceID int64) ([]*model.GeofenceAssignment, error)
+	DeleteGeofenceAssignment(ctx context.Context, geofenceID, id int64) error
+	// GeofenceVehicles resolves group assignments to their members and returns
+	// the vehicles of every geofence that has assignments. A geofence assigned
+	// only to empty groups maps to an empty slice.
+	GeofenceVehicles(ctx context.Context) (map[int64][]string, error)
 }
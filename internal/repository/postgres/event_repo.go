@@ -1,6 +1,8 @@
 package postgres
 
 import (
+	"context"
+
 	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
 	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/repository"
 	"gorm.io/gorm"
@@ -14,6 +16,6 @@ func NewEventLogRepository(db *gorm.DB) repository.EventLogRepository {
 	return &eventLogRepository{db: db}
 }
 
-func (r *eventLogRepository) InsertEvent(evt *model.EventLog) error {
-	return r.db.Create(evt).Error
+func (r *eventLogRepository) InsertEvent(ctx context.Context, evt *model.EventLog) error {
+	return r.db.WithContext(ctx).Create(evt).Error
 }
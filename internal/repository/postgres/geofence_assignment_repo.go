@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/repository"
+	"gorm.io/gorm"
+)
+
+type geofenceAssignmentRepository struct {
+	db *gorm.DB
+}
+
+func NewGeofenceAssignmentRepository(db *gorm.DB) repository.GeofenceAssignmentRepository {
+	return &geofenceAssignmentRepository{db: db}
+}
+
+// InsertGeofenceAssignment expects the geofence to exist; a missing group is
+// reported as ErrVehicleGroupNotFound
+func (r *geofenceAssignmentRepository) InsertGeofenceAssignment(ctx context.Context, assignment *model.GeofenceAssignment) error {
+	err := r.db.WithContext(ctx).Create(assignment).Error
+	if isPgError(err, pgForeignKeyViolation) {
+		if assignment.GroupID != nil {
+			return repository.ErrVehicleGroupNotFound
+		}
+		return repository.ErrGeofenceNotFound
+	}
+	return err
+}
+
+func (r *geofenceAssignmentRepository) ListGeofenceAssignments(ctx context.Context, geofenceID int64) ([]*model.GeofenceAssignment, error) {
+	var assignments []*model.GeofenceAssignment
+	err := r.db.WithContext(ctx).Where("geofence_id = ?", geofenceID).Order("id ASC").Find(&assignments).Error
+	return assignments, err
+}
+
+func (r *geofenceAssignmentRepository) DeleteGeofenceAssignment(ctx context.Context, geofenceID, id int64) error {
+	result := r.db.WithContext(ctx).Where("geofence_id = ?", geofenceID).Delete(&model.GeofenceAssignment{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return repository.ErrAssignmentNotFound
+	}
+	return nil
+}
+
+func (r *geofenceAssignmentRepository) GeofenceVehicles(ctx context.Context) (map[int64][]string, error) {
+	var rows []struct {
+		GeofenceID int64
+		VehicleID  *string
+	}
+	err := r.db.WithContext(ctx).Raw(`SELECT DISTINCT a.geofence_id, COALESCE(a.vehicle_id, m.vehicle_id) AS vehicle_id
+		FROM geofence_assignments a
+		LEFT JOIN vehicle_group_members m ON m.group_id = a.group_id`).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	vehicles := make(map[int64][]string)
+	for _, row := range rows {
+		if row.VehicleID == nil {
+			// Empty group: the geofence is assigned but applies to nobody yet
+			if _, ok := vehicles[row.GeofenceID]; !ok {
+				vehicles[row.GeofenceID] = []string{}
+			}
+			continue
+		}
+		vehicles[row.GeofenceID] = append(vehicles[row.GeofenceID], *row.VehicleID)
+	}
+	return vehicles, nil
+}
@@ -0,0 +1,30 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/repository"
+	"gorm.io/gorm"
+)
+
+type geofenceEventRepository struct {
+	db *gorm.DB
+}
+
+func NewGeofenceEventRepository(db *gorm.DB) repository.GeofenceEventRepository {
+	return &geofenceEventRepository{db: db}
+}
+
+func (r *geofenceEventRepository) InsertGeofenceEvent(ctx context.Context, evt *model.GeofenceEvent) error {
+	return r.db.WithContext(ctx).Create(evt).Error
+}
+
+func (r *geofenceEventRepository) LatestGeofenceStates(ctx context.Context) ([]*model.GeofenceEvent, error) {
+	var events []*model.GeofenceEvent
+	err := r.db.WithContext(ctx).Raw(`SELECT DISTINCT ON (vehicle_id, geofence_id, event_type) *
+		FROM geofence_events
+		ORDER BY vehicle_id, geofence_id, event_type, timestamp DESC`).
+		Scan(&events).Error
+	return events, err
+}
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/repository"
+	"gorm.io/gorm"
+)
+
+type geofenceRepository struct {
+	db *gorm.DB
+}
+
+func NewGeofenceRepository(db *gorm.DB) repository.GeofenceRepository {
+	return &geofenceRepository{db: db}
+}
+
+func (r *geofenceRepository) InsertGeofence(ctx context.Context, geofence *model.Geofence) error {
+	active := geofence.Active
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(geofence).Error; err != nil {
+			return err
+		}
+		// Active has a column default, so gorm replaces false with true on insert
+		if !active {
+			geofence.Active = false
+			return tx.Model(geofence).Update("active", false).Error
+		}
+		return nil
+	})
+}
+
+func (r *geofenceRepository) GetGeofence(ctx context.Context, id int64) (*model.Geofence, error) {
+	var geofence model.Geofence
+	err := r.db.WithContext(ctx).First(&geofence, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, repository.ErrGeofenceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &geofence, nil
+}
+
+func (r *geofenceRepository) ListGeofences(ctx context.Context, filter repository.GeofenceFilter) ([]*model.Geofence, error) {
+	query := r.db.WithContext(ctx).Model(&model.Geofence{})
+	if filter.Active != nil {
+		query = query.Where("active = ?", *filter.Active)
+	}
+	if filter.Shape != "" {
+		query = query.Where("shape = ?", filter.Shape)
+	}
+	if filter.Name != "" {
+		query = query.Where("name ILIKE ?", "%"+filter.Name+"%")
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+
+	var geofences []*model.Geofence
+	err := query.Order("id ASC").Find(&geofences).Error
+	return geofences, err
+}
+
+func (r *geofenceRepository) UpdateGeofence(ctx context.Context, geofence *model.Geofence) error {
+	// Select all columns so zero values such as Active=false are written
+	result := r.db.WithContext(ctx).Model(geofence).Select("*").Omit("id").Updates(geofence)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return repository.ErrGeofenceNotFound
+	}
+	return nil
+}
+
+func (r *geofenceRepository) SetGeofenceActive(ctx context.Context, id int64, active bool) error {
+	result := r.db.WithContext(ctx).Model(&model.Geofence{}).Where("id = ?", id).Update("active", active)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return repository.ErrGeofenceNotFound
+	}
+	return nil
+}
+
+func (r *geofenceRepository) DeleteGeofence(ctx context.Context, id int64) error {
+	result := r.db.WithContext(ctx).Delete(&model.Geofence{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return repository.ErrGeofenceNotFound
+	}
+	return nil
+}
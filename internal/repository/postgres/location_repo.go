@@ -1,6 +1,8 @@
 package postgres
 
 import (
+	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
@@ -16,23 +18,66 @@ func NewVehicleLocationRepository(db *gorm.DB) repository.VehicleRepository {
 	return &vehicleLocationRepository{db: db}
 }
 
-func (r *vehicleLocationRepository) InsertLocation(loc *model.VehicleLocation) error {
-	return r.db.Create(loc).Error
+// locationInsertBatch keeps each multi-row INSERT well under Postgres's limit
+// of 65535 bind parameters
+const locationInsertBatch = 1000
+
+func (r *vehicleLocationRepository) InsertLocation(ctx context.Context, loc *model.VehicleLocation) error {
+	return r.db.WithContext(ctx).Create(loc).Error
+}
+
+// InsertLocations writes locs with multi-row INSERTs in a single transaction
+func (r *vehicleLocationRepository) InsertLocations(ctx context.Context, locs []*model.VehicleLocation) error {
+	if len(locs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(locs, locationInsertBatch).Error
+	})
 }
 
-func (r *vehicleLocationRepository) GetLatestLocation(vehicleID string) (*model.VehicleLocation, error) {
+func (r *vehicleLocationRepository) GetLatestLocation(ctx context.Context, vehicleID string) (*model.VehicleLocation, error) {
 	var loc model.VehicleLocation
-	err := r.db.Where("vehicle_id = ?", vehicleID).Order("timestamp DESC").First(&loc).Error
+	err := r.db.WithContext(ctx).Where("vehicle_id = ?", vehicleID).Order("timestamp DESC").First(&loc).Error
 	if err != nil {
 		return nil, err
 	}
 	return &loc, nil
 }
 
-func (r *vehicleLocationRepository) GetLocationHistory(vehicleID string, start, end time.Time) ([]*model.VehicleLocation, error) {
+func (r *vehicleLocationRepository) GetLocationHistory(ctx context.Context, vehicleID string, start, end time.Time) ([]*model.VehicleLocation, error) {
 	var history []*model.VehicleLocation
-	err := r.db.Where("vehicle_id = ? AND timestamp BETWEEN ? AND ?",
+	err := r.db.WithContext(ctx).Where("vehicle_id = ? AND timestamp BETWEEN ? AND ?",
 		vehicleID, start, end).Order("timestamp ASC").
 		Find(&history).Error
 	return history, err
 }
+
+// FindLocations matches attributes with jsonb containment, which the GIN
+// index on the attributes column serves
+func (r *vehicleLocationRepository) FindLocations(ctx context.Context, filter repository.LocationFilter) ([]*model.VehicleLocation, error) {
+	query := r.db.WithContext(ctx).Model(&model.VehicleLocation{})
+	if filter.VehicleID != "" {
+		query = query.Where("vehicle_id = ?", filter.VehicleID)
+	}
+	if !filter.Start.IsZero() {
+		query = query.Where("timestamp >= ?", filter.Start)
+	}
+	if !filter.End.IsZero() {
+		query = query.Where("timestamp <= ?", filter.End)
+	}
+	if len(filter.Attributes) > 0 {
+		attributes, err := json.Marshal(filter.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("attributes @> ?::jsonb", string(attributes))
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var locations []*model.VehicleLocation
+	err := query.Order("timestamp ASC").Find(&locations).Error
+	return locations, err
+}
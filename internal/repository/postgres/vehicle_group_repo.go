@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Postgres error codes
+const (
+	pgForeignKeyViolation = "23503"
+	pgUniqueViolation     = "23505"
+)
+
+func isPgError(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
+
+type vehicleGroupRepository struct {
+	db *gorm.DB
+}
+
+func NewVehicleGroupRepository(db *gorm.DB) repository.VehicleGroupRepository {
+	return &vehicleGroupRepository{db: db}
+}
+
+func (r *vehicleGroupRepository) InsertVehicleGroup(ctx context.Context, group *model.VehicleGroup) error {
+	err := r.db.WithContext(ctx).Create(group).Error
+	if isPgError(err, pgUniqueViolation) {
+		return repository.ErrVehicleGroupExists
+	}
+	return err
+}
+
+func (r *vehicleGroupRepository) ListVehicleGroups(ctx context.Context) ([]*model.VehicleGroup, error) {
+	var groups []*model.VehicleGroup
+	err := r.db.WithContext(ctx).Order("id ASC").Find(&groups).Error
+	return groups, err
+}
+
+func (r *vehicleGroupRepository) DeleteVehicleGroup(ctx context.Context, id int64) error {
+	result := r.db.WithContext(ctx).Delete(&model.VehicleGroup{}, id)
+	if isPgError(result.Error, pgForeignKeyViolation) {
+		return repository.ErrVehicleGroupAssigned
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return repository.ErrVehicleGroupNotFound
+	}
+	return nil
+}
+
+func (r *vehicleGroupRepository) ListGroupVehicles(ctx context.Context, groupID int64) ([]string, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.VehicleGroup{}).Where("id = ?", groupID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, repository.ErrVehicleGroupNotFound
+	}
+
+	vehicleIDs := []string{}
+	err := r.db.WithContext(ctx).Model(&model.VehicleGroupMember{}).
+		Where("group_id = ?", groupID).
+		Order("vehicle_id ASC").
+		Pluck("vehicle_id", &vehicleIDs).Error
+	return vehicleIDs, err
+}
+
+func (r *vehicleGroupRepository) AddGroupVehicle(ctx context.Context, groupID int64, vehicleID string) error {
+	// Adding an existing member is a no-op
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.VehicleGroupMember{GroupID: groupID, VehicleID: vehicleID}).Error
+	if isPgError(err, pgForeignKeyViolation) {
+		return repository.ErrVehicleGroupNotFound
+	}
+	return err
+}
+
+func (r *vehicleGroupRepository) RemoveGroupVehicle(ctx context.Context, groupID int64, vehicleID string) error {
+	result := r.db.WithContext(ctx).Where("group_id = ? AND vehicle_id = ?", groupID, vehicleID).Delete(&model.VehicleGroupMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return repository.ErrVehicleNotFound
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+// Package shutdown holds what the commands share to stop cleanly
+package shutdown
+
+import (
+	"sync"
+	"time"
+)
+
+// Wait waits for wg for at most timeout and reports whether it finished
+func Wait(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
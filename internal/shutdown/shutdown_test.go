@@ -0,0 +1,25 @@
+package shutdown
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWait(t *testing.T) {
+	var wg sync.WaitGroup
+	assert.True(t, Wait(&wg, time.Millisecond), "nothing to wait for")
+
+	release := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-release
+	}()
+	assert.False(t, Wait(&wg, 10*time.Millisecond), "work still running at the deadline")
+
+	close(release)
+	assert.True(t, Wait(&wg, time.Second))
+}
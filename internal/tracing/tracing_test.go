@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/config"
+)
+
+// restoreGlobals puts back the global provider and propagator Setup replaces
+func restoreGlobals(t *testing.T) {
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+}
+
+func TestSetup_FileExporterWritesSpans(t *testing.T) {
+	restoreGlobals(t)
+	cfg := config.Default().Tracing
+	cfg.Exporter = "file"
+	cfg.File = filepath.Join(t.TempDir(), "traces.json")
+
+	shutdown, err := Setup(context.Background(), cfg, "test-service")
+	require.NoError(t, err)
+	_, span := Tracer().Start(context.Background(), "test-span")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	data, err := os.ReadFile(cfg.File)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"test-span"`)
+	assert.Contains(t, string(data), `"Value":"test-service"`)
+}
+
+func TestInjectExtract_RoundTrips(t *testing.T) {
+	restoreGlobals(t)
+	cfg := config.Default().Tracing
+	cfg.Exporter = "file"
+	cfg.File = filepath.Join(t.TempDir(), "traces.json")
+	shutdown, err := Setup(context.Background(), cfg, "test-service")
+	require.NoError(t, err)
+	defer shutdown(context.Background())
+
+	ctx, span := Tracer().Start(context.Background(), "publish")
+	defer span.End()
+	want := span.SpanContext()
+
+	carrier := Inject(ctx)
+	require.Contains(t, carrier, "traceparent")
+	got := trace.SpanContextFromContext(Extract(context.Background(), carrier))
+	assert.Equal(t, want.TraceID(), got.TraceID())
+	assert.Equal(t, want.SpanID(), got.SpanID())
+
+	headers := amqp.Table{"alert_version": int32(2)}
+	InjectAMQP(ctx, headers)
+	got = trace.SpanContextFromContext(ExtractAMQP(context.Background(), headers))
+	assert.Equal(t, want.TraceID(), got.TraceID())
+	assert.Equal(t, int32(2), headers["alert_version"], "existing headers are kept")
+}
+
+func TestInject_NilWithoutTrace(t *testing.T) {
+	restoreGlobals(t)
+	_, err := Setup(context.Background(), config.Default().Tracing, "test-service")
+	require.NoError(t, err)
+
+	assert.Nil(t, Inject(context.Background()))
+}
@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -71,7 +72,7 @@ func TestEventLogIntegration(t *testing.T) {
 
 	// Test event insertion
 	t.Run("InsertEventLog", func(t *testing.T) {
-		err := eventRepo.InsertEvent(eventLog)
+		err := eventRepo.InsertEvent(context.Background(), eventLog)
 		assert.NoError(t, err)
 		assert.NotZero(t, eventLog.ID, "Event ID should be set after insertion")
 
@@ -188,7 +189,7 @@ func TestEventLogMultipleEvents(t *testing.T) {
 			Source:    testSource,
 		}
 
-		err := eventRepo.InsertEvent(eventLog)
+		err := eventRepo.InsertEvent(context.Background(), eventLog)
 		assert.NoError(t, err)
 		eventIDs = append(eventIDs, eventLog.ID)
 
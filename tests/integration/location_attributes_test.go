@@ -0,0 +1,62 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/repository"
+	locationpg "github.com/satryo-pramahardi/go-vehicle-tracker/internal/repository/postgres"
+)
+
+func TestFindLocationsByAttributes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping location attributes integration test in short mode")
+	}
+
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
+		getEnv("DB_HOST", "localhost"),
+		getEnv("DB_USER", "admin"),
+		getEnv("DB_PASSWORD", "password"),
+		getEnv("DB_NAME", "vehicle_tracker"),
+		getEnv("DB_PORT", "5432"),
+	)
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	repo := locationpg.NewVehicleLocationRepository(db)
+	ctx := context.Background()
+
+	vehicleID := "ATTR_TEST_001"
+	cleanup := func() { db.Where("vehicle_id = ?", vehicleID).Delete(&model.VehicleLocation{}) }
+	cleanup()
+	t.Cleanup(cleanup)
+
+	base := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, repo.InsertLocations(ctx, []*model.VehicleLocation{
+		{VehicleID: vehicleID, Timestamp: base, Attributes: model.LocationAttributes{"ignition": true, "fuel_level": 80.0}},
+		{VehicleID: vehicleID, Timestamp: base.Add(time.Minute), Attributes: model.LocationAttributes{"ignition": false, "fuel_level": 79.5}},
+		{VehicleID: vehicleID, Timestamp: base.Add(2 * time.Minute)},
+	}))
+
+	parked, err := repo.FindLocations(ctx, repository.LocationFilter{
+		VehicleID:  vehicleID,
+		Attributes: map[string]any{"ignition": false},
+	})
+	require.NoError(t, err)
+	require.Len(t, parked, 1)
+	level, ok := parked[0].Attributes.FuelLevel()
+	assert.True(t, ok)
+	assert.Equal(t, 79.5, level)
+
+	all, err := repo.FindLocations(ctx, repository.LocationFilter{VehicleID: vehicleID, Start: base, End: base.Add(time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Nil(t, all[2].Attributes, "locations without attributes store NULL")
+}
@@ -0,0 +1,79 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/satryo-pramahardi/go-vehicle-tracker/internal/model"
+	locationpg "github.com/satryo-pramahardi/go-vehicle-tracker/internal/repository/postgres"
+)
+
+const benchVehicleID = "BENCH_INGEST_001"
+
+// BenchmarkLocationIngest compares saving locations one INSERT at a time, as
+// the location worker used to, with the batched multi-row INSERTs it uses now.
+// Run with: go test -run '^$' -bench LocationIngest ./tests/integration/
+func BenchmarkLocationIngest(b *testing.B) {
+	if testing.Short() {
+		b.Skip("skipping location ingest benchmark in short mode")
+	}
+
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
+		getEnv("DB_HOST", "localhost"),
+		getEnv("DB_USER", "admin"),
+		getEnv("DB_PASSWORD", "password"),
+		getEnv("DB_NAME", "vehicle_tracker"),
+		getEnv("DB_PORT", "5432"),
+	)
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		b.Fatalf("failed to connect to database: %v", err)
+	}
+	repo := locationpg.NewVehicleLocationRepository(db)
+	ctx := context.Background()
+
+	cleanup := func() { db.Where("vehicle_id = ?", benchVehicleID).Delete(&model.VehicleLocation{}) }
+	cleanup()
+	b.Cleanup(cleanup)
+
+	locations := func(n int) []*model.VehicleLocation {
+		locs := make([]*model.VehicleLocation, n)
+		for i := range locs {
+			locs[i] = &model.VehicleLocation{
+				VehicleID: benchVehicleID,
+				Latitude:  -6.193125 + float64(i)*1e-6,
+				Longitude: 106.820233,
+				Timestamp: time.Now(),
+			}
+		}
+		return locs
+	}
+
+	b.Run("Single", func(b *testing.B) {
+		locs := locations(b.N)
+		b.ResetTimer()
+		for _, loc := range locs {
+			if err := repo.InsertLocation(ctx, loc); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	for _, size := range []int{100, 500} {
+		b.Run(fmt.Sprintf("Batched%d", size), func(b *testing.B) {
+			locs := locations(b.N)
+			b.ResetTimer()
+			for start := 0; start < len(locs); start += size {
+				if err := repo.InsertLocations(ctx, locs[start:min(start+size, len(locs))]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -34,12 +35,17 @@ type MockVehicleRepository struct {
 	mock.Mock
 }
 
-func (m *MockVehicleRepository) InsertLocation(loc *model.VehicleLocation) error {
+func (m *MockVehicleRepository) InsertLocation(ctx context.Context, loc *model.VehicleLocation) error {
 	args := m.Called(loc)
 	return args.Error(0)
 }
 
-func (m *MockVehicleRepository) GetLatestLocation(vehicleID string) (*model.VehicleLocation, error) {
+func (m *MockVehicleRepository) InsertLocations(ctx context.Context, locs []*model.VehicleLocation) error {
+	args := m.Called(locs)
+	return args.Error(0)
+}
+
+func (m *MockVehicleRepository) GetLatestLocation(ctx context.Context, vehicleID string) (*model.VehicleLocation, error) {
 	args := m.Called(vehicleID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -47,7 +53,7 @@ func (m *MockVehicleRepository) GetLatestLocation(vehicleID string) (*model.Vehi
 	return args.Get(0).(*model.VehicleLocation), args.Error(1)
 }
 
-func (m *MockVehicleRepository) GetLocationHistory(vehicleID string, start, end time.Time) ([]*model.VehicleLocation, error) {
+func (m *MockVehicleRepository) GetLocationHistory(ctx context.Context, vehicleID string, start, end time.Time) ([]*model.VehicleLocation, error) {
 	args := m.Called(vehicleID, start, end)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -55,6 +61,14 @@ func (m *MockVehicleRepository) GetLocationHistory(vehicleID string, start, end
 	return args.Get(0).([]*model.VehicleLocation), args.Error(1)
 }
 
+func (m *MockVehicleRepository) FindLocations(ctx context.Context, filter repository.LocationFilter) ([]*model.VehicleLocation, error) {
+	args := m.Called(filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.VehicleLocation), args.Error(1)
+}
+
 func TestVehicleLocationAPI(t *testing.T) {
 	// Test setup
 	vehicleID := "API_TEST_001"